@@ -0,0 +1,23 @@
+package dtos
+
+// ListAlertsDto struct used to accept params for the list alerts call, UserId is only honoured for admins
+// acting on behalf of another user
+type ListAlertsDto struct {
+	UserId string `form:"user_id"`
+}
+
+// CreateAlertDto struct used to accept params for the create alert call. Price and percent conditions need
+// a threshold, sma crosses a window. CooldownMinutes defaults to alerts.defaultCooldown
+type CreateAlertDto struct {
+	UserId          string  `json:"user_id"`
+	Ticker          string  `json:"ticker" binding:"required,max=16"`
+	Condition       string  `json:"condition" binding:"required,oneof=price_above price_below percent_move sma_cross_above sma_cross_below"`
+	Threshold       float64 `json:"threshold" binding:"min=0"`
+	Window          int     `json:"window" binding:"omitempty,min=2,max=200"`
+	CooldownMinutes *int    `json:"cooldown_minutes" binding:"omitempty,min=0"`
+}
+
+// AlertIdDto struct used to accept the alert id from the url
+type AlertIdDto struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
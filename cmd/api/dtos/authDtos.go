@@ -0,0 +1,22 @@
+package dtos
+
+import "github.com/RobsonDevCode/GoApi/cmd/api/models"
+
+// IssueApiKeyDto struct used to accept params for the issue api key call
+type IssueApiKeyDto struct {
+	Name       string   `json:"name" binding:"required"`
+	OwnerId    string   `json:"owner_id" binding:"required"`
+	Scopes     []string `json:"scopes" binding:"required,min=1"`
+	DailyQuota int      `json:"daily_quota" binding:"omitempty,min=1"`
+}
+
+// ApiKeyIdDto struct used to accept the key id from the url
+type ApiKeyIdDto struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+// IssuedApiKeyDto returned once when a key is created, Key is never retrievable again
+type IssuedApiKeyDto struct {
+	Key    string        `json:"key"`
+	ApiKey models.ApiKey `json:"api_key"`
+}
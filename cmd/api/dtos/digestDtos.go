@@ -0,0 +1,18 @@
+package dtos
+
+// DigestSubscriptionDto struct used to accept params for the digest subscription calls, UserId is only
+// honoured for admins acting on behalf of another user
+type DigestSubscriptionDto struct {
+	UserId string `form:"user_id"`
+}
+
+// SubscribeDigestDto struct used to accept params for the subscribe to digest call
+type SubscribeDigestDto struct {
+	UserId string `json:"user_id"`
+	Email  string `json:"email" binding:"required,email,max=254"`
+}
+
+// UnsubscribeDigestDto struct used to accept the token from a digest's unsubscribe link
+type UnsubscribeDigestDto struct {
+	Token string `form:"token" binding:"required,len=64,hexadecimal"`
+}
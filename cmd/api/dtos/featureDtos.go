@@ -0,0 +1,6 @@
+package dtos
+
+// FeatureFlagsDto struct used to accept params for the list feature flags call
+type FeatureFlagsDto struct {
+	UserId string `form:"user_id" binding:"required"`
+}
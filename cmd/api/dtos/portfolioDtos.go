@@ -0,0 +1,54 @@
+package dtos
+
+import "time"
+
+// ListPortfoliosDto struct used to accept params for the list portfolios call, UserId is only honoured
+// for admins acting on behalf of another user
+type ListPortfoliosDto struct {
+	UserId string `form:"user_id"`
+}
+
+// CreatePortfolioDto struct used to accept params for the create portfolio call, CostBasis defaults to fifo
+type CreatePortfolioDto struct {
+	UserId    string `json:"user_id"`
+	Name      string `json:"name" binding:"required,max=100"`
+	CostBasis string `json:"cost_basis" binding:"omitempty,oneof=fifo average"`
+}
+
+// UpdatePortfolioDto struct used to accept params for the update portfolio call, empty fields are left as they are
+type UpdatePortfolioDto struct {
+	Name      string `json:"name" binding:"omitempty,max=100"`
+	CostBasis string `json:"cost_basis" binding:"omitempty,oneof=fifo average"`
+}
+
+// PortfolioIdDto struct used to accept the portfolio id from the url
+type PortfolioIdDto struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+// PortfolioTransactionUriDto struct used to accept the portfolio and transaction ids from the url
+type PortfolioTransactionUriDto struct {
+	Id            string `uri:"id" binding:"required,uuid"`
+	TransactionId string `uri:"transactionId" binding:"required,uuid"`
+}
+
+// AddTransactionDto struct used to accept a transaction. Buys and sells need quantity and price,
+// dividends the cash amount and splits the ratio of new shares per old share
+type AddTransactionDto struct {
+	Ticker     string    `json:"ticker" binding:"required,max=16"`
+	Type       string    `json:"type" binding:"required,oneof=buy sell dividend split"`
+	Quantity   float64   `json:"quantity" binding:"min=0"`
+	Price      float64   `json:"price" binding:"min=0"`
+	Fees       float64   `json:"fees" binding:"min=0"`
+	Amount     float64   `json:"amount" binding:"min=0"`
+	Ratio      float64   `json:"ratio" binding:"min=0"`
+	ExecutedAt time.Time `json:"executed_at" binding:"required"`
+}
+
+// PortfolioPerformanceDto struct used to accept params for the portfolio performance call, To defaults to
+// today and Benchmark to the configured benchmark ticker
+type PortfolioPerformanceDto struct {
+	From      time.Time `form:"from" time_format:"2006-01-02" binding:"required"`
+	To        time.Time `form:"to" time_format:"2006-01-02"`
+	Benchmark string    `form:"benchmark" binding:"max=16"`
+}
@@ -0,0 +1,9 @@
+package dtos
+
+// SocketRequestDto a message a websocket client sends to change its subscriptions, Tickers is required
+// for every channel except alerts
+type SocketRequestDto struct {
+	Action  string   `json:"action" binding:"required,oneof=subscribe unsubscribe"`
+	Channel string   `json:"channel" binding:"required,oneof=prices trades quotes aggregates alerts"`
+	Tickers []string `json:"tickers" binding:"dive,max=16"`
+}
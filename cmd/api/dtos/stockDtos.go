@@ -10,9 +10,10 @@ type TickerDetailsDto struct {
 	Ticker string `form:"ticker" binding:"required"`
 }
 
-// GetFavouriteStocksOpenCloseDto struct used to accept params for GetFavouriteStocksOpenClose api call
+// GetFavouriteStocksOpenCloseDto struct used to accept params for GetFavouriteStocksOpenClose api call,
+// UserId is only honoured for admins acting on behalf of another user
 type GetFavouriteStocksOpenCloseDto struct {
-	UserId string `form:"user_id" binding:"required"` //we use string as uuid isn't safe for urls
+	UserId string `form:"user_id"` //we use string as uuid isn't safe for urls
 }
 
 type PreviousCloseRequestDto struct {
@@ -33,3 +34,53 @@ type SimpleMovingAverageDto struct {
 	Window      int       `form:"window" binding:"required"`
 	MoreDetails bool      `form:"more_details"  default:"false"`
 }
+
+// StreamTickersDto struct used to accept params for the ticker stream, Tickers is comma separated.
+// LastEventId is for clients that can't set the Last-Event-ID header
+type StreamTickersDto struct {
+	Tickers     string `form:"tickers" binding:"required"`
+	LastEventId string `form:"last_event_id" binding:"omitempty,number"`
+}
+
+// StreamFavouritesDto struct used to accept params for the favourites stream, UserId is only honoured
+// for admins acting on behalf of another user
+type StreamFavouritesDto struct {
+	UserId      string `form:"user_id"`
+	LastEventId string `form:"last_event_id" binding:"omitempty,number"`
+}
+
+// SearchTickersDto struct used to accept params for ticker search, Query matches the start of a ticker
+// or of any word in the company's name. Active defaults to true
+type SearchTickersDto struct {
+	Query  string `form:"q" binding:"required,max=64"`
+	Market string `form:"market" binding:"omitempty,oneof=stocks crypto fx otc indices"`
+	Type   string `form:"type" binding:"omitempty,max=16"` //polygon ticker type, e.g. CS or ETF
+	Active *bool  `form:"active"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// TickerNewsDto struct used to accept params for a ticker's news, Cursor is a NewsPage's NextCursor
+type TickerNewsDto struct {
+	Ticker string    `form:"ticker" binding:"required,max=16"`
+	From   time.Time `form:"from" time_format:"2006-01-02"`
+	Limit  int       `form:"limit" binding:"omitempty,min=1,max=50"`
+	Cursor string    `form:"cursor" binding:"omitempty,max=128"`
+}
+
+// FavouritesNewsDto struct used to accept params for the news of the caller's favourites, UserId is only
+// honoured for admins acting on behalf of another user
+type FavouritesNewsDto struct {
+	UserId string    `form:"user_id"`
+	From   time.Time `form:"from" time_format:"2006-01-02"`
+	Limit  int       `form:"limit" binding:"omitempty,min=1,max=50"`
+	Cursor string    `form:"cursor" binding:"omitempty,max=128"`
+}
+
+// CorporateActionsDto struct used to accept params for a ticker's dividends or splits, From and To bound
+// the ex-dividend or execution date
+type CorporateActionsDto struct {
+	Ticker string    `form:"ticker" binding:"required,max=16"`
+	From   time.Time `form:"from" time_format:"2006-01-02"`
+	To     time.Time `form:"to" time_format:"2006-01-02"`
+	Limit  int       `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
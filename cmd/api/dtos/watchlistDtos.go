@@ -0,0 +1,45 @@
+package dtos
+
+// ListWatchlistsDto struct used to accept params for the list watchlists call, UserId is only honoured
+// for admins acting on behalf of another user
+type ListWatchlistsDto struct {
+	UserId string `form:"user_id"`
+}
+
+// CreateWatchlistDto struct used to accept params for the create watchlist call
+type CreateWatchlistDto struct {
+	UserId string `json:"user_id"`
+	Name   string `json:"name" binding:"required,max=100"`
+}
+
+// RenameWatchlistDto struct used to accept the new name of a watchlist
+type RenameWatchlistDto struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// WatchlistIdDto struct used to accept the watchlist id from the url
+type WatchlistIdDto struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+// WatchlistTickerUriDto struct used to accept the watchlist id and ticker from the url
+type WatchlistTickerUriDto struct {
+	Id     string `uri:"id" binding:"required,uuid"`
+	Ticker string `uri:"ticker" binding:"required,max=16"`
+}
+
+// AddWatchlistTickerDto struct used to accept params for the add ticker to watchlist call
+type AddWatchlistTickerDto struct {
+	Ticker string `json:"ticker" binding:"required,max=16"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// WatchlistTickerNoteDto struct used to accept a ticker's note
+type WatchlistTickerNoteDto struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// ReorderWatchlistDto every ticker in the watchlist in the order they should appear
+type ReorderWatchlistDto struct {
+	Tickers []string `json:"tickers" binding:"required,min=1"`
+}
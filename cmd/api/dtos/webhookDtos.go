@@ -0,0 +1,32 @@
+package dtos
+
+// ListWebhooksDto struct used to accept params for the list webhooks call, UserId is only honoured for admins
+// acting on behalf of another user
+type ListWebhooksDto struct {
+	UserId string `form:"user_id"`
+}
+
+// CreateWebhookDto struct used to accept params for the create webhook call, Events are the event types
+// the endpoint is sent
+type CreateWebhookDto struct {
+	UserId string   `json:"user_id"`
+	Url    string   `json:"url" binding:"required,url,max=2048"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=alert.triggered favourite.added"`
+}
+
+// WebhookIdDto struct used to accept the webhook id from the url
+type WebhookIdDto struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+// WebhookDeliveryUriDto struct used to accept the webhook and delivery ids from the url
+type WebhookDeliveryUriDto struct {
+	Id         string `uri:"id" binding:"required,uuid"`
+	DeliveryId string `uri:"deliveryId" binding:"required,uuid"`
+}
+
+// ListDeliveriesDto struct used to accept params for the list deliveries call, Limit defaults to 50
+type ListDeliveriesDto struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending delivered dead"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=200"`
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+const (
+	defaultApiKeyHeader = "X-API-Key"
+	apiKeyPrefixLength  = 11
+)
+
+// ApiKeyAuthenticator resolves callers from the api key header and enforces each key's daily quota
+type ApiKeyAuthenticator struct {
+	repo   *repository.ApiKeyRepository
+	header string
+}
+
+// ApiKeyHeader the header callers send their api key in
+func ApiKeyHeader() string {
+	if header := Current().Auth.ApiKeyHeader; header != "" {
+		return header
+	}
+	return defaultApiKeyHeader
+}
+
+func NewApiKeyAuthenticator(repo *repository.ApiKeyRepository) *ApiKeyAuthenticator {
+
+	return &ApiKeyAuthenticator{
+		repo:   repo,
+		header: ApiKeyHeader(),
+	}
+}
+
+func (a *ApiKeyAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	rawKey := c.GetHeader(a.header)
+	if rawKey == "" {
+		return nil, ErrNoCredentials
+	}
+
+	ctx := c.Request.Context()
+	hash := HashApiKey(rawKey)
+
+	//the bootstrap key lets an admin issue the first real keys, it's read each time so it can be rotated
+	bootstrapKey := Current().Auth.BootstrapAdminKey
+	if bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(HashApiKey(bootstrapKey))) == 1 {
+		return &Principal{KeyId: "bootstrap", Scopes: []string{ScopeAdmin}}, nil
+	}
+
+	result := a.repo.GetApiKeyByHash(hash, ctx)
+	if result.Error != nil {
+		if errors.Is(result.Error, repository.ErrApiKeyNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, result.Error
+	}
+
+	key := result.Data
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: api key has been revoked", ErrInvalidCredentials)
+	}
+
+	usage := a.repo.UseDailyQuota(key.Id, time.Now().UTC(), key.DailyQuota, ctx)
+	if usage.Error != nil {
+		return nil, usage.Error
+	}
+	if !usage.Data {
+		return nil, ErrQuotaExceeded
+	}
+
+	return &Principal{
+		UserId: key.OwnerId,
+		KeyId:  key.Id,
+		Scopes: key.Scopes,
+	}, nil
+}
+
+// GenerateApiKey creates a new random key, returning the raw key, its display prefix and the hash we store
+func GenerateApiKey() (rawKey string, prefix string, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	rawKey = "gk_" + base64.RawURLEncoding.EncodeToString(secret)
+	return rawKey, rawKey[:apiKeyPrefixLength], HashApiKey(rawKey), nil
+}
+
+// HashApiKey keys are high entropy so a plain sha256 is enough to store them safely
+func HashApiKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval stops a flood of tokens with unknown kids forcing a fetch each
+const minRefreshInterval = 30 * time.Second
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// verificationKey either an *rsa.PublicKey for RS256 or a []byte secret for HS256
+type verificationKey struct {
+	kid string
+	key any
+}
+
+// JwksCache loads a JWKS document from a file or url and keeps it cached until it goes stale
+type JwksCache struct {
+	source      string
+	ttl         time.Duration
+	client      *http.Client
+	mu          sync.RWMutex
+	keys        []verificationKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func NewJwksCache(source string, ttl time.Duration) *JwksCache {
+	return &JwksCache{
+		source: source,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Lookup finds a key by kid, or the only key of the right type when the token has no kid
+func (j *JwksCache) Lookup(ctx context.Context, kid string, alg string) (any, error) {
+	j.mu.RLock()
+	stale := time.Since(j.fetchedAt) > j.ttl
+	key, found := findKey(j.keys, kid, alg)
+	j.mu.RUnlock()
+
+	if found && !stale {
+		return key, nil
+	}
+
+	//an unknown kid usually means the keys were rotated so refresh before giving up
+	if err := j.refresh(ctx); err != nil {
+		if found {
+			log.Warnf("jwks refresh failed, using cached keys: %s", err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if key, found = findKey(j.keys, kid, alg); !found {
+		return nil, fmt.Errorf("no %s key found in jwks for kid %q", alg, kid)
+	}
+	return key, nil
+}
+
+func (j *JwksCache) refresh(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if time.Since(j.lastAttempt) < minRefreshInterval {
+		return nil
+	}
+	j.lastAttempt = time.Now()
+
+	document, err := j.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set jsonWebKeySet
+	if err := json.Unmarshal(document, &set); err != nil {
+		return fmt.Errorf("error decoding jwks: %w", err)
+	}
+
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.parse()
+		if err != nil {
+			log.Warnf("skipping jwks key %q: %s", jwk.Kid, err)
+			continue
+		}
+		keys = append(keys, verificationKey{kid: jwk.Kid, key: key})
+	}
+
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	log.Infof("loaded %d keys from jwks", len(keys))
+
+	return nil
+}
+
+func (j *JwksCache) read(ctx context.Context) ([]byte, error) {
+	if !isUrl(j.source) {
+		return os.ReadFile(j.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks request returned %s", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (k jsonWebKey) parse() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+
+	default:
+		return nil, errors.New("unsupported key type " + k.Kty)
+	}
+}
+
+func findKey(keys []verificationKey, kid string, alg string) (any, bool) {
+	var match any
+	matches := 0
+
+	for _, k := range keys {
+		if !keyMatchesAlg(k.key, alg) {
+			continue
+		}
+		if kid != "" && k.kid == kid {
+			return k.key, true
+		}
+		match = k.key
+		matches++
+	}
+
+	//without a kid we can only pick a key if there's no ambiguity
+	if kid == "" && matches == 1 {
+		return match, true
+	}
+	return nil, false
+}
+
+func keyMatchesAlg(key any, alg string) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case []byte:
+		return alg == "HS256"
+	default:
+		return false
+	}
+}
+
+func isUrl(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAdminRole = "admin"
+	defaultJwksTtl   = 15 * time.Minute
+	clockLeeway      = 30 * time.Second
+)
+
+// defaultUserScopes granted to tokens that don't carry a scope claim
+var defaultUserScopes = []string{ScopeMarketData, ScopeFavourites, ScopePortfolios, ScopeAlerts, ScopeWebhooks}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	Scope     string   `json:"scope"`
+	Roles     []string `json:"roles"`
+}
+
+// audience the aud claim can be a single string or a list
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// JwtAuthenticator resolves callers from an Authorization bearer token signed with HS256 or RS256
+type JwtAuthenticator struct {
+	issuer     string
+	audience   string
+	hmacSecret []byte
+	jwks       *JwksCache
+	adminRole  string
+}
+
+// NewJwtAuthenticator returns nil when jwt auth isn't configured
+func NewJwtAuthenticator() *JwtAuthenticator {
+	settings := Current().Auth.Jwt
+	if settings.HmacSecret == "" && settings.Jwks == "" {
+		return nil
+	}
+
+	authenticator := &JwtAuthenticator{
+		issuer:    settings.Issuer,
+		audience:  settings.Audience,
+		adminRole: settings.AdminRole,
+	}
+
+	if authenticator.adminRole == "" {
+		authenticator.adminRole = defaultAdminRole
+	}
+	if settings.HmacSecret != "" {
+		authenticator.hmacSecret = []byte(settings.HmacSecret)
+	}
+	if settings.Jwks != "" {
+		ttl := time.Duration(settings.JwksCacheMinutes) * time.Minute
+		if ttl == 0 {
+			ttl = defaultJwksTtl
+		}
+		authenticator.jwks = NewJwksCache(settings.Jwks, ttl)
+	}
+
+	return authenticator
+}
+
+func (j *JwtAuthenticator) Authenticate(c *gin.Context) (*Principal, error) {
+	header := c.GetHeader("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := j.validate(c.Request.Context(), token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, err)
+	}
+
+	scopes := defaultUserScopes
+	if claims.Scope != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+	if slices.Contains(claims.Roles, j.adminRole) {
+		scopes = append(slices.Clone(scopes), ScopeAdmin)
+	}
+
+	return &Principal{
+		UserId: claims.Subject,
+		Scopes: scopes,
+	}, nil
+}
+
+// validate checks the signature then the issuer, audience and expiry of the token
+func (j *JwtAuthenticator) validate(ctx context.Context, token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %w", err)
+	}
+
+	key, err := j.verificationKey(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockLeeway)) {
+		return nil, errors.New("token has expired")
+	}
+	if claims.NotBefore != 0 && now.Add(clockLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if j.issuer != "" && claims.Issuer != j.issuer {
+		return nil, errors.New("unexpected issuer")
+	}
+	if j.audience != "" && !slices.Contains(claims.Audience, j.audience) {
+		return nil, errors.New("unexpected audience")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	return &claims, nil
+}
+
+func (j *JwtAuthenticator) verificationKey(ctx context.Context, header jwtHeader) (any, error) {
+	switch header.Alg {
+	case "HS256":
+		if j.hmacSecret != nil && (header.Kid == "" || j.jwks == nil) {
+			return j.hmacSecret, nil
+		}
+	case "RS256":
+	default:
+		//never let the token choose "none" or an algorithm we haven't vetted
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+
+	if j.jwks == nil {
+		return nil, fmt.Errorf("no key configured for %s", header.Alg)
+	}
+	return j.jwks.Lookup(ctx, header.Kid, header.Alg)
+}
+
+func verifySignature(alg string, key any, signingInput string, signature []byte) error {
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("key does not match algorithm")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+
+	case "RS256":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key does not match algorithm")
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
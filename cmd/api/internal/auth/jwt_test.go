@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://issuer.goapi.test"
+	testAudience = "goapi"
+	testSecret   = "hmac-test-secret"
+)
+
+// signToken builds a token from header and claims, signing it with a []byte secret for HS256 or an
+// *rsa.PrivateKey for RS256. Any other alg gets an empty signature
+func signToken(t *testing.T, header map[string]any, claims map[string]any, key any) string {
+	t.Helper()
+
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := segment(header) + "." + segment(claims)
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validClaims claims that pass every check, tests change the one they're about
+func validClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"sub": "user-1",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+}
+
+// withClaimsOf swaps the claims of token for those of another, keeping token's signature
+func withClaimsOf(token string, other string) string {
+	parts, otherParts := strings.Split(token, "."), strings.Split(other, ".")
+	return parts[0] + "." + otherParts[1] + "." + parts[2]
+}
+
+func with(claims map[string]any, key string, value any) map[string]any {
+	if value == nil {
+		delete(claims, key)
+		return claims
+	}
+	claims[key] = value
+	return claims
+}
+
+func newRsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func rsaJwk(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Alg: "RS256",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// jwksServer serves a key set that can be swapped out to mimic a rotation, counting the fetches
+type jwksServer struct {
+	server  *httptest.Server
+	fetches atomic.Int32
+
+	mu  sync.Mutex
+	set jsonWebKeySet
+}
+
+func newJwksServer(t *testing.T, keys ...jsonWebKey) *jwksServer {
+	jwks := &jwksServer{set: jsonWebKeySet{Keys: keys}}
+	jwks.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwks.fetches.Add(1)
+
+		jwks.mu.Lock()
+		defer jwks.mu.Unlock()
+		json.NewEncoder(w).Encode(jwks.set)
+	}))
+	t.Cleanup(jwks.server.Close)
+	return jwks
+}
+
+func (j *jwksServer) rotate(keys ...jsonWebKey) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.set = jsonWebKeySet{Keys: keys}
+}
+
+func TestValidate(t *testing.T) {
+	rsaKey := newRsaKey(t)
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+	secret := []byte(testSecret)
+
+	authenticator := &JwtAuthenticator{issuer: testIssuer, audience: testAudience, hmacSecret: secret}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", signToken(t, hs256, validClaims(), secret), false},
+		{"audience as an array", signToken(t, hs256, with(validClaims(), "aud", []string{"other", testAudience}),
+			secret), false},
+		{"expired within the clock leeway", signToken(t, hs256,
+			with(validClaims(), "exp", time.Now().Add(-10*time.Second).Unix()), secret), false},
+		{"alg none", signToken(t, map[string]any{"alg": "none"}, validClaims(), nil), true},
+		{"alg none lower and upper case", signToken(t, map[string]any{"alg": "NoNe"}, validClaims(), nil), true},
+		{"unknown alg", signToken(t, map[string]any{"alg": "HS512"}, validClaims(), secret), true},
+		{"rs256 without a jwks", signToken(t, map[string]any{"alg": "RS256"}, validClaims(), rsaKey), true},
+		{"bad signature", signToken(t, hs256, validClaims(), []byte("another-secret")), true},
+		{"tampered claims", withClaimsOf(signToken(t, hs256, validClaims(), secret),
+			signToken(t, hs256, with(validClaims(), "sub", "admin"), nil)), true},
+		{"expired", signToken(t, hs256, with(validClaims(), "exp", time.Now().Add(-time.Minute).Unix()), secret),
+			true},
+		{"no expiry", signToken(t, hs256, with(validClaims(), "exp", nil), secret), true},
+		{"not valid yet", signToken(t, hs256, with(validClaims(), "nbf", time.Now().Add(time.Minute).Unix()),
+			secret), true},
+		{"wrong issuer", signToken(t, hs256, with(validClaims(), "iss", "https://evil.test"), secret), true},
+		{"wrong audience string", signToken(t, hs256, with(validClaims(), "aud", "other"), secret), true},
+		{"wrong audience array", signToken(t, hs256, with(validClaims(), "aud", []string{"other", "another"}),
+			secret), true},
+		{"no subject", signToken(t, hs256, with(validClaims(), "sub", nil), secret), true},
+		{"malformed", "not-a-token", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := authenticator.validate(context.Background(), test.token)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != "user-1" {
+				t.Fatalf("subject %q, want user-1", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureRejectsKeysForAnotherAlg(t *testing.T) {
+	rsaKey := newRsaKey(t)
+
+	tests := []struct {
+		name string
+		alg  string
+		key  any
+	}{
+		{"hs256 with an rsa key", "HS256", &rsaKey.PublicKey},
+		{"rs256 with a secret", "RS256", []byte(testSecret)},
+		{"unknown alg", "none", []byte(testSecret)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := verifySignature(test.alg, test.key, "header.claims", []byte("signature")); err == nil {
+				t.Fatal("want an error")
+			}
+		})
+	}
+}
+
+func TestHs256TokenIsRejectedAgainstAnRsaJwks(t *testing.T) {
+	rsaKey := newRsaKey(t)
+	jwks := newJwksServer(t, rsaJwk("rsa-1", &rsaKey.PublicKey))
+	authenticator := &JwtAuthenticator{issuer: testIssuer, audience: testAudience,
+		jwks: NewJwksCache(jwks.server.URL, time.Hour)}
+
+	//the classic confusion attack: sign HS256 with the public key the verifier holds for RS256
+	publicKey := rsaKey.PublicKey.N.Bytes()
+	for _, kid := range []string{"rsa-1", ""} {
+		token := signToken(t, map[string]any{"alg": "HS256", "kid": kid}, validClaims(), publicKey)
+		if _, err := authenticator.validate(context.Background(), token); err == nil {
+			t.Fatalf("hs256 token with kid %q was accepted against an rsa key", kid)
+		}
+	}
+
+	token := signToken(t, map[string]any{"alg": "RS256", "kid": "rsa-1"}, validClaims(), rsaKey)
+	if _, err := authenticator.validate(context.Background(), token); err != nil {
+		t.Fatalf("rs256 token with the jwks key: %s", err)
+	}
+}
+
+func TestUnknownKidRefreshesJwksOnce(t *testing.T) {
+	first, second := newRsaKey(t), newRsaKey(t)
+	jwks := newJwksServer(t, rsaJwk("key-1", &first.PublicKey))
+	cache := NewJwksCache(jwks.server.URL, time.Hour)
+	authenticator := &JwtAuthenticator{issuer: testIssuer, audience: testAudience, jwks: cache}
+
+	validate := func(key *rsa.PrivateKey, kid string) error {
+		token := signToken(t, map[string]any{"alg": "RS256", "kid": kid}, validClaims(), key)
+		_, err := authenticator.validate(context.Background(), token)
+		return err
+	}
+
+	if err := validate(first, "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := validate(first, "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if fetches := jwks.fetches.Load(); fetches != 1 {
+		t.Fatalf("%d jwks fetches, want the keys cached after the first", fetches)
+	}
+
+	//the issuer rotates its keys after the minimum refresh interval has passed
+	jwks.rotate(rsaJwk("key-2", &second.PublicKey))
+	cache.mu.Lock()
+	cache.lastAttempt = time.Now().Add(-minRefreshInterval)
+	cache.mu.Unlock()
+
+	if err := validate(second, "key-2"); err != nil {
+		t.Fatalf("token signed with the rotated key: %s", err)
+	}
+	if fetches := jwks.fetches.Load(); fetches != 2 {
+		t.Fatalf("%d jwks fetches, want one refresh for the unknown kid", fetches)
+	}
+
+	//further unknown kids inside the refresh interval don't fetch again
+	for _, kid := range []string{"key-3", "key-4"} {
+		if err := validate(second, kid); err == nil {
+			t.Fatalf("token with unknown kid %q was accepted", kid)
+		}
+	}
+	if err := validate(first, "key-1"); err == nil {
+		t.Fatal("token signed with the rotated out key was accepted")
+	}
+	if fetches := jwks.fetches.Load(); fetches != 2 {
+		t.Fatalf("%d jwks fetches, want no more inside the minimum refresh interval", fetches)
+	}
+}
+
+func TestFindKey(t *testing.T) {
+	first, second := newRsaKey(t), newRsaKey(t)
+	secret := []byte(testSecret)
+
+	keys := []verificationKey{
+		{kid: "rsa-1", key: &first.PublicKey},
+		{kid: "hmac-1", key: secret},
+	}
+	twoRsa := append(keys, verificationKey{kid: "rsa-2", key: &second.PublicKey})
+
+	tests := []struct {
+		name  string
+		keys  []verificationKey
+		kid   string
+		alg   string
+		want  any
+		found bool
+	}{
+		{"by kid", twoRsa, "rsa-2", "RS256", &second.PublicKey, true},
+		{"kid of a key for another alg", keys, "hmac-1", "RS256", nil, false},
+		{"unknown kid", keys, "rsa-9", "RS256", nil, false},
+		{"no kid with one key for the alg", keys, "", "RS256", &first.PublicKey, true},
+		{"no kid with one secret", keys, "", "HS256", secret, true},
+		{"no kid with several keys for the alg", twoRsa, "", "RS256", nil, false},
+		{"unsupported alg", keys, "rsa-1", "ES256", nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, found := findKey(test.keys, test.kid, test.alg)
+			if found != test.found {
+				t.Fatalf("found %t, want %t", found, test.found)
+			}
+			if !test.found {
+				return
+			}
+			switch want := test.want.(type) {
+			case *rsa.PublicKey:
+				if key, ok := got.(*rsa.PublicKey); !ok || !key.Equal(want) {
+					t.Fatal("got the wrong rsa key")
+				}
+			case []byte:
+				if key, ok := got.([]byte); !ok || string(key) != string(want) {
+					t.Fatal("got the wrong secret")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials supplied")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrQuotaExceeded      = errors.New("daily request quota exceeded")
+)
+
+// Authenticator resolves the caller of a request, returning ErrNoCredentials if the request doesn't use its scheme
+type Authenticator interface {
+	Authenticate(c *gin.Context) (*Principal, error)
+}
+
+// Middleware tries each authenticator in order and stores the first principal resolved on the request context
+func Middleware(authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(c)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+
+			if err != nil {
+				log.Warnf("authentication failed: %s", err)
+				status := http.StatusUnauthorized
+				if errors.Is(err, ErrQuotaExceeded) {
+					status = http.StatusTooManyRequests
+				}
+				c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), principal))
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrNoCredentials.Error()})
+	}
+}
+
+// RequireScope rejects callers that weren't granted scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := FromContext(c.Request.Context())
+		if !ok || !p.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing required scope " + scope})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SocketProtocol the websocket subprotocol the api speaks, browsers offer it alongside their token
+const SocketProtocol = "goapi"
+
+// bearerProtocolPrefix marks the subprotocol carrying a bearer token, e.g. bearer.eyJhbGciOi...
+const bearerProtocolPrefix = "bearer."
+
+// ProtocolToken accepts a bearer token offered as a "bearer.{token}" websocket subprotocol when there's no
+// Authorization header, browsers can't set headers when opening a websocket but can list subprotocols.
+// Unlike a query parameter the token doesn't end up in access logs. It must run before the auth middleware
+func ProtocolToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := protocolToken(c.Request); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		c.Next()
+	}
+}
+
+// protocolToken the token of the first bearer subprotocol the request offers, empty if there isn't one
+func protocolToken(r *http.Request) string {
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if token, ok := strings.CutPrefix(strings.TrimSpace(protocol), bearerProtocolPrefix); ok && token != "" {
+				return token
+			}
+		}
+	}
+	return ""
+}
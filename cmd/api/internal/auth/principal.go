@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+const (
+	ScopeMarketData = "market:read"
+	ScopeFavourites = "favourites:manage"
+	ScopePortfolios = "portfolios:manage"
+	ScopeAlerts     = "alerts:manage"
+	ScopeWebhooks   = "webhooks:manage"
+	ScopeAdmin      = "admin"
+)
+
+// Scopes every scope a key can be issued with
+var Scopes = []string{ScopeMarketData, ScopeFavourites, ScopePortfolios, ScopeAlerts, ScopeWebhooks, ScopeAdmin}
+
+// Principal the authenticated caller of a request
+type Principal struct {
+	UserId string
+	KeyId  string
+	Scopes []string
+}
+
+type principalKey struct{}
+
+// HasScope reports whether the principal was granted scope, admin is granted everything
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, ScopeAdmin) || slices.Contains(p.Scopes, scope)
+}
+
+// ValidScope checks the scope is one we know about
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// WithPrincipal stores the principal on the context
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext gets the principal stored by the auth middleware
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+var (
+	ErrForbiddenUser = errors.New("not permitted to act on behalf of another user")
+	ErrNoUser        = errors.New("user_id must be provided when the caller isn't a user")
+)
+
+// ResolveUserId works out which user a request acts for. Normally that's the caller, an admin can
+// act on behalf of another user by explicitly requesting them
+func ResolveUserId(ctx context.Context, requested string) (string, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return "", ErrForbiddenUser
+	}
+
+	if requested == "" || requested == p.UserId {
+		if p.UserId == "" {
+			return "", ErrNoUser
+		}
+		return p.UserId, nil
+	}
+
+	if !p.HasScope(ScopeAdmin) {
+		return "", ErrForbiddenUser
+	}
+	return requested, nil
+}
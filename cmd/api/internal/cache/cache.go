@@ -0,0 +1,16 @@
+package cache
+
+import "time"
+
+// Cache the backend handlers and middleware share for anything that should expire
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any, ttl time.Duration)
+	Delete(key string)
+	// Increment adds one to the counter at key, starting a new counter that lives for ttl if there isn't one,
+	// and returns the new count and when the counter expires
+	Increment(key string, ttl time.Duration) (int64, time.Time)
+}
+
+// Shared the process wide cache backend
+var Shared Cache = NewMemory()
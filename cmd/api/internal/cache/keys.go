@@ -0,0 +1,14 @@
+package cache
+
+import "fmt"
+
+// FavouritesOpenCloseKey the cached open/close of a user's favourites, cleared whenever their favourites or
+// default watchlist change
+func FavouritesOpenCloseKey(userId string) string {
+	return fmt.Sprintf("get-fav-open-close-%s", userId)
+}
+
+// WatchlistOpenCloseKey the cached open/close of a watchlist's tickers, cleared whenever they change
+func WatchlistOpenCloseKey(watchlistId string) string {
+	return fmt.Sprintf("watchlist-open-close-%s", watchlistId)
+}
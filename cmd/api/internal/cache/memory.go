@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+const evictionInterval = time.Minute
+
+type entry struct {
+	value     any
+	expiresAt time.Time
+}
+
+// Memory an in process Cache, expired entries are evicted in the background
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+func NewMemory() *Memory {
+	m := &Memory{
+		entries: make(map[string]*entry),
+	}
+
+	go m.evictExpired()
+	return m
+}
+
+func (m *Memory) Get(key string) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (m *Memory) Set(key string, value any, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[key] = &entry{value: value, expiresAt: time.Now().Add(ttl)}
+}
+
+func (m *Memory) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+}
+
+func (m *Memory) Increment(key string, ttl time.Duration) (int64, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	e, ok := m.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		e = &entry{value: int64(0), expiresAt: now.Add(ttl)}
+		m.entries[key] = e
+	}
+
+	count, _ := e.value.(int64)
+	count++
+	e.value = count
+
+	return count, e.expiresAt
+}
+
+func (m *Memory) evictExpired() {
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.mu.Lock()
+		for key, e := range m.entries {
+			if now.After(e.expiresAt) {
+				delete(m.entries, key)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,350 @@
+package calendar
+
+import (
+	"context"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" //the exchange's timezone is needed on hosts without a zoneinfo database
+)
+
+// regular session hours in the exchange's timezone
+const (
+	regularOpen  = 9*time.Hour + 30*time.Minute
+	regularClose = 16 * time.Hour
+	earlyClose   = 13 * time.Hour
+)
+
+// extended hours, pre-market from 4am and after-hours for four hours after the close
+const (
+	preMarketOpen     = 4 * time.Hour
+	afterHoursLasting = 4 * time.Hour
+)
+
+// statusMargin how far into the session the exchange has to report closed before it counts as an
+// unscheduled closure, so a status fetched around the bell isn't mistaken for one
+const statusMargin = 15 * time.Minute
+
+// maxSearch days searched for a session before giving up, the longest closure on record is four weekdays
+const maxSearch = 31
+
+// Exchange the timezone the exchange's sessions are in
+var Exchange = mustLoadLocation("America/New_York")
+
+// Session a trading day's regular hours. Date is midnight UTC of the trading date, the same as the daily
+// bars it matches
+type Session struct {
+	Date       time.Time
+	Open       time.Time
+	Close      time.Time
+	EarlyClose bool
+}
+
+// Holiday a day the exchange is closed or, when Closed is false, closes early at Close
+type Holiday struct {
+	Name   string
+	Date   time.Time
+	Closed bool
+	Close  time.Time
+}
+
+// Calendar works out the exchange's sessions. Holidays published by polygon take precedence over the
+// built-in NYSE rules, which cover the past and the api being unreachable
+type Calendar struct {
+	mu          sync.Mutex
+	published   map[time.Time]Holiday         //from polygon's upcoming holidays
+	unscheduled map[time.Time]Holiday         //sessions polygon's market status showed closed
+	rules       map[int]map[time.Time]Holiday //built-in rules, worked out once per year
+}
+
+func New() *Calendar {
+	return &Calendar{
+		published:   make(map[time.Time]Holiday),
+		unscheduled: make(map[time.Time]Holiday),
+		rules:       make(map[int]map[time.Time]Holiday),
+	}
+}
+
+// Shared the process wide calendar
+var Shared = New()
+
+// Start refreshes from polygon now and every calendar.refreshInterval until ctx is done, the interval is
+// read before each wait so reloads are picked up
+func (c *Calendar) Start(ctx context.Context, pa *intergration.PolygonApi) {
+	schedule.NowAndEvery(ctx, func() time.Duration {
+		return time.Duration(configuration.Current().Calendar.RefreshInterval)
+	}, func(ctx context.Context) {
+		if err := c.Refresh(ctx, pa); err != nil {
+			log.Errorf("market calendar refresh failed, using what it has: %s", err)
+		}
+	})
+}
+
+// Refresh replaces the upcoming holidays with polygon's and records today as closed if the exchange
+// reports closed in the middle of a session
+func (c *Calendar) Refresh(ctx context.Context, pa *intergration.PolygonApi) error {
+	holidays := pa.FetchMarketHolidays(ctx)
+	if holidays.Error != nil {
+		return holidays.Error
+	}
+	c.publish(holidays.Data, time.Now())
+
+	status := pa.FetchMarketStatus(ctx)
+	if status.Error != nil {
+		return status.Error
+	}
+	c.observe(status.Data, time.Now())
+
+	return nil
+}
+
+// Holiday the date's holiday or early close, false when it's a normal day
+func (c *Calendar) Holiday(date time.Time) (Holiday, bool) {
+	day := dateOf(date)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if holiday, ok := c.unscheduled[day]; ok {
+		return holiday, true
+	}
+	if holiday, ok := c.published[day]; ok {
+		return holiday, true
+	}
+
+	rules, ok := c.rules[day.Year()]
+	if !ok {
+		rules = nyseHolidays(day.Year())
+		c.rules[day.Year()] = rules
+	}
+	holiday, ok := rules[day]
+	return holiday, ok
+}
+
+// IsTradingDay whether the exchange has a session on the date
+func (c *Calendar) IsTradingDay(date time.Time) bool {
+	_, ok := c.Session(date)
+	return ok
+}
+
+// Session the date's regular session, false when the exchange is closed all day
+func (c *Calendar) Session(date time.Time) (Session, bool) {
+	day := dateOf(date)
+	if isWeekend(day) {
+		return Session{}, false
+	}
+
+	session := Session{
+		Date:  day,
+		Open:  atExchange(day, regularOpen),
+		Close: atExchange(day, regularClose),
+	}
+
+	if holiday, ok := c.Holiday(day); ok {
+		if holiday.Closed {
+			return Session{}, false
+		}
+		session.EarlyClose = true
+		session.Close = atExchange(day, earlyClose)
+		if !holiday.Close.IsZero() {
+			session.Close = holiday.Close
+		}
+	}
+
+	return session, true
+}
+
+// LastSession the most recent session to have closed by now
+func (c *Calendar) LastSession(now time.Time) Session {
+	today := now.In(Exchange)
+	if session, ok := c.Session(today); ok && !now.Before(session.Close) {
+		return session
+	}
+	return c.PreviousSession(today)
+}
+
+// PreviousSession the last session before the date
+func (c *Calendar) PreviousSession(date time.Time) Session {
+	return c.SessionOnOrBefore(dateOf(date).AddDate(0, 0, -1))
+}
+
+// SessionOnOrBefore the date's session, or the last one before it when the exchange is closed that day
+func (c *Calendar) SessionOnOrBefore(date time.Time) Session {
+	day := dateOf(date)
+	for i := 0; i < maxSearch; i++ {
+		if session, ok := c.Session(day); ok {
+			return session
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+
+	//only reachable with a month of closures, fall back to treating the date as a session
+	day = dateOf(date)
+	log.Warnf("no trading session in the %d days up to %s", maxSearch, day.Format(time.DateOnly))
+	return Session{Date: day, Open: atExchange(day, regularOpen), Close: atExchange(day, regularClose)}
+}
+
+// NextSession the first session after the date
+func (c *Calendar) NextSession(date time.Time) Session {
+	day := dateOf(date)
+	for i := 0; i < maxSearch; i++ {
+		day = day.AddDate(0, 0, 1)
+		if session, ok := c.Session(day); ok {
+			return session
+		}
+	}
+
+	log.Warnf("no trading session in the %d days after %s", maxSearch, dateOf(date).Format(time.DateOnly))
+	return Session{Date: day, Open: atExchange(day, regularOpen), Close: atExchange(day, regularClose)}
+}
+
+// SessionsBefore the session n sessions before the last one to have closed by now
+func (c *Calendar) SessionsBefore(now time.Time, n int) Session {
+	session := c.LastSession(now)
+	for i := 0; i < n; i++ {
+		session = c.PreviousSession(session.Date)
+	}
+	return session
+}
+
+// Status what the market is doing at now and when the regular session next opens and closes
+func (c *Calendar) Status(now time.Time) models.MarketStatus {
+	today := now.In(Exchange)
+	status := models.MarketStatus{Market: models.MarketClosed, ServerTime: today}
+
+	if holiday, ok := c.Holiday(today); ok {
+		status.Holiday = holiday.Name
+	}
+
+	session, ok := c.Session(today)
+	if ok {
+		switch {
+		case now.Before(atExchange(session.Date, preMarketOpen)):
+		case now.Before(session.Open):
+			status.Market = models.MarketPreMarket
+		case now.Before(session.Close):
+			status.Market = models.MarketOpen
+		case now.Before(session.Close.Add(afterHoursLasting)):
+			status.Market = models.MarketAfterHours
+		}
+	}
+
+	//once today's session has closed it's the next one that matters
+	if !ok || !now.Before(session.Close) {
+		session = c.NextSession(today)
+	}
+	status.NextClose = session.Close.In(Exchange)
+	status.EarlyClose = session.EarlyClose
+
+	if now.Before(session.Open) {
+		status.NextOpen = session.Open.In(Exchange)
+	} else {
+		status.NextOpen = c.NextSession(today).Open.In(Exchange)
+	}
+
+	return status
+}
+
+// CacheTtl how long something cached at now can be kept. While anything trades, extended hours included,
+// it's tradingTtl. Overnight, at weekends and on holidays prices can't change so it's cache.closedTtl, cut
+// short so nothing is still cached once pre-market opens
+func (c *Calendar) CacheTtl(now time.Time, tradingTtl time.Duration) time.Duration {
+	status := c.Status(now)
+	if status.Market != models.MarketClosed {
+		return tradingTtl
+	}
+
+	nextPreMarket := atExchange(dateOf(status.NextOpen), preMarketOpen)
+	ttl := min(time.Duration(configuration.Current().Cache.ClosedTtl), nextPreMarket.Sub(now))
+	return max(ttl, tradingTtl)
+}
+
+// publish swaps in polygon's holidays from today on, earlier ones are kept as they drop off its list
+func (c *Calendar) publish(holidays []polyModels.MarketHoliday, now time.Time) {
+	today := dateOf(now.In(Exchange))
+	upcoming := make(map[time.Time]Holiday)
+
+	for _, published := range holidays {
+		if !strings.EqualFold(published.Exchange, "NYSE") {
+			continue
+		}
+
+		day := dateOf(time.Time(published.Date))
+		holiday := Holiday{Name: published.Name, Date: day}
+		switch published.Status {
+		case "closed":
+			holiday.Closed = true
+		case "early-close":
+			holiday.Close = time.Time(published.Close)
+		default:
+			log.Warnf("unknown market holiday status %q for %s", published.Status, day.Format(time.DateOnly))
+			continue
+		}
+		upcoming[day] = holiday
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for day := range c.published {
+		if !day.Before(today) {
+			delete(c.published, day)
+		}
+	}
+	for day, holiday := range upcoming {
+		c.published[day] = holiday
+	}
+}
+
+// observe records today as an unscheduled closure when the exchange reports closed well inside what
+// should be its session
+func (c *Calendar) observe(status *polyModels.GetMarketStatusResponse, now time.Time) {
+	if status == nil || status.Exchanges["nyse"] != "closed" {
+		return
+	}
+
+	session, ok := c.Session(now.In(Exchange))
+	if !ok || now.Before(session.Open.Add(statusMargin)) || now.After(session.Close.Add(-statusMargin)) {
+		return
+	}
+
+	log.Warnf("market status shows the exchange closed during the %s session", session.Date.Format(time.DateOnly))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.unscheduled[session.Date] = Holiday{Name: "Unscheduled closure", Date: session.Date, Closed: true}
+}
+
+// date midnight UTC of the date
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// dateOf midnight UTC of t's date in its own location
+func dateOf(t time.Time) time.Time {
+	return date(t.Year(), t.Month(), t.Day())
+}
+
+// atExchange the time of day on the date in the exchange's timezone
+func atExchange(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(offset.Hours()), int(offset.Minutes())%60, 0, 0, Exchange)
+}
+
+func isWeekend(day time.Time) bool {
+	return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
@@ -0,0 +1,173 @@
+package calendar
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSettings = `{
+	"connectionStrings": {"stocksDb": "test"},
+	"apiSettings": {"key": "test"},
+	"cache": {"closedTtl": "6h"}
+}`
+
+// et the time on the date in the exchange's timezone
+func et(year int, month time.Month, day int, hour int, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, Exchange)
+}
+
+func TestLastSession(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"during a session it's the day before", et(2024, time.March, 5, 11, 0), date(2024, time.March, 4)},
+		{"at the close it's today", et(2024, time.March, 5, 16, 0), date(2024, time.March, 5)},
+		{"monday morning it's friday", et(2024, time.March, 4, 8, 0), date(2024, time.March, 1)},
+		{"at the weekend it's friday", et(2024, time.March, 10, 12, 0), date(2024, time.March, 8)},
+		{"after martin luther king day it's the friday before", et(2024, time.January, 16, 9, 0),
+			date(2024, time.January, 12)},
+		{"on good friday it's thursday", et(2024, time.March, 29, 17, 0), date(2024, time.March, 28)},
+		{"the monday after good friday it's thursday", et(2024, time.April, 1, 9, 0), date(2024, time.March, 28)},
+		{"after an early close it's today", et(2023, time.November, 24, 13, 30), date(2023, time.November, 24)},
+		{"before an early close it's the day before thanksgiving", et(2023, time.November, 24, 12, 0),
+			date(2023, time.November, 22)},
+		{"evening in new york is the next day in utc", time.Date(2024, time.March, 6, 2, 0, 0, 0, time.UTC),
+			date(2024, time.March, 5)},
+	}
+
+	calendar := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := calendar.LastSession(test.now).Date; !got.Equal(test.want) {
+				t.Fatalf("got %s, want %s", got.Format(time.DateOnly), test.want.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	utc := func(month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		want      string
+		nextOpen  time.Time
+		nextClose time.Time
+	}{
+		{"overnight", et(2024, time.March, 5, 3, 59), models.MarketClosed,
+			et(2024, time.March, 5, 9, 30), et(2024, time.March, 5, 16, 0)},
+		{"pre-market opens at 4", et(2024, time.March, 5, 4, 0), models.MarketPreMarket,
+			et(2024, time.March, 5, 9, 30), et(2024, time.March, 5, 16, 0)},
+		{"still pre-market at 9:29", et(2024, time.March, 5, 9, 29), models.MarketPreMarket,
+			et(2024, time.March, 5, 9, 30), et(2024, time.March, 5, 16, 0)},
+		{"opens at 9:30", et(2024, time.March, 5, 9, 30), models.MarketOpen,
+			et(2024, time.March, 6, 9, 30), et(2024, time.March, 5, 16, 0)},
+		{"still open at 15:59", et(2024, time.March, 5, 15, 59), models.MarketOpen,
+			et(2024, time.March, 6, 9, 30), et(2024, time.March, 5, 16, 0)},
+		{"after-hours from 16:00", et(2024, time.March, 5, 16, 0), models.MarketAfterHours,
+			et(2024, time.March, 6, 9, 30), et(2024, time.March, 6, 16, 0)},
+		{"still after-hours at 19:59", et(2024, time.March, 5, 19, 59), models.MarketAfterHours,
+			et(2024, time.March, 6, 9, 30), et(2024, time.March, 6, 16, 0)},
+		{"closed from 20:00", et(2024, time.March, 5, 20, 0), models.MarketClosed,
+			et(2024, time.March, 6, 9, 30), et(2024, time.March, 6, 16, 0)},
+		{"friday evening waits for monday", et(2024, time.March, 8, 20, 0), models.MarketClosed,
+			et(2024, time.March, 11, 9, 30), et(2024, time.March, 11, 16, 0)},
+
+		//clocks go forward at 2am on sunday 10 march 2024 and back at 2am on sunday 3 november 2024
+		{"spring forward sunday", utc(time.March, 10, 12, 0), models.MarketClosed,
+			utc(time.March, 11, 13, 30), utc(time.March, 11, 20, 0)},
+		{"pre-market after spring forward is 08:00 utc", utc(time.March, 11, 8, 0), models.MarketPreMarket,
+			utc(time.March, 11, 13, 30), utc(time.March, 11, 20, 0)},
+		{"open after spring forward is 13:30 utc", utc(time.March, 11, 13, 30), models.MarketOpen,
+			utc(time.March, 12, 13, 30), utc(time.March, 11, 20, 0)},
+		{"closed after spring forward at 00:00 utc", utc(time.March, 12, 0, 0), models.MarketClosed,
+			utc(time.March, 12, 13, 30), utc(time.March, 12, 20, 0)},
+		{"fall back sunday", utc(time.November, 3, 12, 0), models.MarketClosed,
+			utc(time.November, 4, 14, 30), utc(time.November, 4, 21, 0)},
+		{"still closed at 08:00 utc after fall back", utc(time.November, 4, 8, 0), models.MarketClosed,
+			utc(time.November, 4, 14, 30), utc(time.November, 4, 21, 0)},
+		{"pre-market after fall back is 09:00 utc", utc(time.November, 4, 9, 0), models.MarketPreMarket,
+			utc(time.November, 4, 14, 30), utc(time.November, 4, 21, 0)},
+		{"still pre-market at 14:29 utc after fall back", utc(time.November, 4, 14, 29), models.MarketPreMarket,
+			utc(time.November, 4, 14, 30), utc(time.November, 4, 21, 0)},
+
+		{"after-hours from an early close", et(2024, time.December, 24, 13, 0), models.MarketAfterHours,
+			et(2024, time.December, 26, 9, 30), et(2024, time.December, 26, 16, 0)},
+		{"closed four hours after an early close", et(2024, time.December, 24, 17, 0), models.MarketClosed,
+			et(2024, time.December, 26, 9, 30), et(2024, time.December, 26, 16, 0)},
+		{"holiday", et(2024, time.December, 25, 10, 0), models.MarketClosed,
+			et(2024, time.December, 26, 9, 30), et(2024, time.December, 26, 16, 0)},
+	}
+
+	calendar := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := calendar.Status(test.now)
+			if status.Market != test.want {
+				t.Fatalf("market %s, want %s", status.Market, test.want)
+			}
+			if !status.NextOpen.Equal(test.nextOpen) || !status.NextClose.Equal(test.nextClose) {
+				t.Fatalf("next open %s and close %s, want %s and %s", status.NextOpen, status.NextClose,
+					test.nextOpen, test.nextClose)
+			}
+		})
+	}
+}
+
+func TestStatusReportsHolidaysAndEarlyCloses(t *testing.T) {
+	calendar := New()
+
+	status := calendar.Status(et(2024, time.December, 24, 10, 0))
+	if status.Holiday != "Christmas Eve" || !status.EarlyClose || !status.NextClose.Equal(et(2024, time.December, 24, 13, 0)) {
+		t.Fatalf("christmas eve: holiday %q, early close %t at %s", status.Holiday, status.EarlyClose, status.NextClose)
+	}
+
+	status = calendar.Status(et(2024, time.December, 25, 10, 0))
+	if status.Holiday != "Christmas Day" || status.EarlyClose {
+		t.Fatalf("christmas day: holiday %q, early close %t", status.Holiday, status.EarlyClose)
+	}
+}
+
+func TestCacheTtl(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(testSettings), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	const trading = 4 * time.Minute
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{"open", et(2024, time.March, 5, 11, 0), trading},
+		{"pre-market", et(2024, time.March, 5, 5, 0), trading},
+		{"after-hours", et(2024, time.March, 5, 19, 0), trading},
+		{"friday night keeps the closed ttl", et(2024, time.March, 8, 21, 0), 6 * time.Hour},
+		{"overnight stops at pre-market", et(2024, time.March, 5, 1, 0), 3 * time.Hour},
+		{"sunday night stops at monday's pre-market", et(2024, time.March, 3, 23, 0), 5 * time.Hour},
+		{"never below the trading ttl", et(2024, time.March, 5, 3, 59), trading},
+		{"holiday stops at the next pre-market", et(2024, time.December, 25, 23, 30), 4*time.Hour + 30*time.Minute},
+	}
+
+	calendar := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := calendar.CacheTtl(test.now, trading); got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package calendar
+
+import "time"
+
+// closures the exchange closed outside its usual rules, for days of mourning and emergencies
+var closures = []Holiday{
+	{Name: "September 11", Date: date(2001, time.September, 11), Closed: true},
+	{Name: "September 11", Date: date(2001, time.September, 12), Closed: true},
+	{Name: "September 11", Date: date(2001, time.September, 13), Closed: true},
+	{Name: "September 11", Date: date(2001, time.September, 14), Closed: true},
+	{Name: "Day of Mourning for Ronald Reagan", Date: date(2004, time.June, 11), Closed: true},
+	{Name: "Day of Mourning for Gerald Ford", Date: date(2007, time.January, 2), Closed: true},
+	{Name: "Hurricane Sandy", Date: date(2012, time.October, 29), Closed: true},
+	{Name: "Hurricane Sandy", Date: date(2012, time.October, 30), Closed: true},
+	{Name: "Day of Mourning for George H.W. Bush", Date: date(2018, time.December, 5), Closed: true},
+	{Name: "Day of Mourning for Jimmy Carter", Date: date(2025, time.January, 9), Closed: true},
+}
+
+// nyseHolidays the year's holidays and early closes under the NYSE's rules, keyed by date. Holidays
+// falling on a Saturday are observed the Friday before and on a Sunday the Monday after, except New
+// Year's Day which isn't observed on the last day of the previous year
+func nyseHolidays(year int) map[time.Time]Holiday {
+	holidays := make(map[time.Time]Holiday)
+	closed := func(name string, day time.Time) {
+		holidays[day] = Holiday{Name: name, Date: day, Closed: true}
+	}
+	closesEarly := func(name string, day time.Time) {
+		if isWeekend(day) {
+			return
+		}
+		holidays[day] = Holiday{Name: name, Date: day, Close: atExchange(day, earlyClose)}
+	}
+
+	//early closes first so a holiday observed on the same day wins
+	closesEarly("Day before Independence Day", date(year, time.July, 3))
+	closesEarly("Day after Thanksgiving", nthWeekday(year, time.November, time.Thursday, 4).AddDate(0, 0, 1))
+	closesEarly("Christmas Eve", date(year, time.December, 24))
+
+	if newYear := date(year, time.January, 1); newYear.Weekday() != time.Saturday {
+		closed("New Year's Day", observed(newYear))
+	}
+	if year >= 1998 {
+		closed("Martin Luther King, Jr. Day", nthWeekday(year, time.January, time.Monday, 3))
+	}
+	closed("Washington's Birthday", nthWeekday(year, time.February, time.Monday, 3))
+	closed("Good Friday", easter(year).AddDate(0, 0, -2))
+	closed("Memorial Day", lastWeekday(year, time.May, time.Monday))
+	if year >= 2022 {
+		closed("Juneteenth National Independence Day", observed(date(year, time.June, 19)))
+	}
+	closed("Independence Day", observed(date(year, time.July, 4)))
+	closed("Labor Day", nthWeekday(year, time.September, time.Monday, 1))
+	closed("Thanksgiving Day", nthWeekday(year, time.November, time.Thursday, 4))
+	closed("Christmas Day", observed(date(year, time.December, 25)))
+
+	for _, closure := range closures {
+		if closure.Date.Year() == year {
+			holidays[closure.Date] = closure
+		}
+	}
+
+	return holidays
+}
+
+// observed moves a holiday falling on a weekend to the nearest weekday
+func observed(day time.Time) time.Time {
+	switch day.Weekday() {
+	case time.Saturday:
+		return day.AddDate(0, 0, -1)
+	case time.Sunday:
+		return day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// nthWeekday the nth weekday of the month, e.g. the third Monday
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := date(year, month, 1)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+(n-1)*7)
+}
+
+// lastWeekday the last weekday of the month, e.g. the last Monday
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := date(year, month+1, 0)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// easter western easter sunday, by the anonymous gregorian algorithm
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return date(year, time.Month(month), day)
+}
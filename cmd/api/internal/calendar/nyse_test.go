@@ -0,0 +1,117 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNyseHolidays checks the built-in rules against the calendars the NYSE published for 2021 to 2026
+func TestNyseHolidays(t *testing.T) {
+	const (
+		open   = "open"
+		closed = "closed"
+		early  = "early close"
+	)
+
+	tests := []struct {
+		name string
+		date time.Time
+		want string
+	}{
+		{"ordinary day", date(2024, time.March, 5), open},
+		{"weekend", date(2024, time.March, 9), closed},
+
+		{"new year's day", date(2024, time.January, 1), closed},
+		{"new year's day on a sunday is observed monday", date(2023, time.January, 2), closed},
+		{"new year's day on a saturday isn't observed the friday before", date(2021, time.December, 31), open},
+		{"martin luther king day", date(2024, time.January, 15), closed},
+		{"washington's birthday", date(2023, time.February, 20), closed},
+		{"memorial day", date(2022, time.May, 30), closed},
+		{"labor day", date(2023, time.September, 4), closed},
+		{"thanksgiving", date(2024, time.November, 28), closed},
+		{"christmas on a sunday is observed monday", date(2022, time.December, 26), closed},
+
+		{"good friday 2022", date(2022, time.April, 15), closed},
+		{"good friday 2023", date(2023, time.April, 7), closed},
+		{"good friday in march", date(2024, time.March, 29), closed},
+		{"good friday 2025", date(2025, time.April, 18), closed},
+		{"easter monday is a session", date(2024, time.April, 1), open},
+
+		{"juneteenth wasn't a holiday before 2022", date(2021, time.June, 18), open},
+		{"juneteenth on a sunday is observed monday", date(2022, time.June, 20), closed},
+		{"juneteenth", date(2023, time.June, 19), closed},
+
+		{"independence day", date(2024, time.July, 4), closed},
+		{"july 3 closes early", date(2024, time.July, 3), early},
+		{"july 3 on a monday closes early", date(2023, time.July, 3), early},
+		{"july 3 is the holiday when july 4 is a saturday", date(2026, time.July, 3), closed},
+		{"july 2 doesn't close early when july 3 is the holiday", date(2026, time.July, 2), open},
+		{"day after thanksgiving closes early", date(2023, time.November, 24), early},
+		{"christmas eve closes early", date(2024, time.December, 24), early},
+		{"christmas eve is the holiday when christmas is a saturday", date(2021, time.December, 24), closed},
+
+		{"day of mourning", date(2025, time.January, 9), closed},
+		{"hurricane sandy", date(2012, time.October, 30), closed},
+	}
+
+	calendar := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := open
+			session, ok := calendar.Session(test.date)
+			switch {
+			case !ok:
+				got = closed
+			case session.EarlyClose:
+				got = early
+				if want := atExchange(test.date, earlyClose); !session.Close.Equal(want) {
+					t.Fatalf("early close at %s, want %s", session.Close, want)
+				}
+			}
+
+			if got != test.want {
+				t.Fatalf("%s is %s, want %s", test.date.Format(time.DateOnly), got, test.want)
+			}
+		})
+	}
+}
+
+func TestEaster(t *testing.T) {
+	tests := []struct {
+		year int
+		want time.Time
+	}{
+		{2000, date(2000, time.April, 23)},
+		{2008, date(2008, time.March, 23)},
+		{2019, date(2019, time.April, 21)},
+		{2024, date(2024, time.March, 31)},
+		{2025, date(2025, time.April, 20)},
+		{2038, date(2038, time.April, 25)},
+	}
+
+	for _, test := range tests {
+		if got := easter(test.year); !got.Equal(test.want) {
+			t.Errorf("easter %d: got %s, want %s", test.year, got.Format(time.DateOnly), test.want.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestObserved(t *testing.T) {
+	tests := []struct {
+		name string
+		day  time.Time
+		want time.Time
+	}{
+		{"weekday", date(2024, time.July, 4), date(2024, time.July, 4)},
+		{"saturday moves to friday", date(2026, time.July, 4), date(2026, time.July, 3)},
+		{"sunday moves to monday", date(2022, time.June, 19), date(2022, time.June, 20)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := observed(test.day); !got.Equal(test.want) {
+				t.Fatalf("got %s, want %s", got.Format(time.DateOnly), test.want.Format(time.DateOnly))
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// event types published on the bus
+const (
+	AlertTriggered = "alert.triggered"
+	FavouriteAdded = "favourite.added"
+)
+
+// Types every event type callers can subscribe to
+var Types = []string{AlertTriggered, FavouriteAdded}
+
+// Event something that happened for a user, Data is serialised as is for subscribers that send it on
+type Event struct {
+	Id         string    `json:"id"`
+	Type       string    `json:"type"`
+	UserId     string    `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// New an event of eventType for the user that happened now
+func New(eventType string, userId string, data any) Event {
+	return Event{
+		Id:         uuid.NewString(),
+		Type:       eventType,
+		UserId:     userId,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// Bus passes events to every subscriber in process, subscribers run on the publisher's goroutine so
+// should hand off anything slow
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[int]func(event Event)
+	nextId      int
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]func(event Event))}
+}
+
+// Shared the process wide bus
+var Shared = NewBus()
+
+// Subscribe registers fn to run for every event published until the returned func is called
+func (b *Bus) Subscribe(fn func(event Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextId
+	b.nextId++
+	b.subscribers[id] = fn
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish passes the event to every subscriber
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, subscriber := range b.subscribers {
+		subscriber(event)
+	}
+}
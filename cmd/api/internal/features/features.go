@@ -0,0 +1,107 @@
+package features
+
+import (
+	"context"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"sort"
+)
+
+// flags routes and handlers are gated on, each is on for everyone by default so a config can roll it back
+// or out to a subset of users
+const (
+	Indicators       = "indicators"        //the technical indicator routes
+	News             = "news"              //the ticker and favourites news routes
+	NewsInsights     = "news-insights"     //polygon's sentiment insights on news articles
+	CorporateActions = "corporate-actions" //the dividends and splits routes
+)
+
+const (
+	ReasonUnknown    = "unknown flag"
+	ReasonDisabled   = "disabled"
+	ReasonUserList   = "user listed"
+	ReasonEnabled    = "enabled for everyone"
+	ReasonInRollout  = "in rollout"
+	ReasonOutRollout = "outside rollout"
+)
+
+// Evaluate works out whether the flag is on for the user, flags are read from the current config so
+// changes apply on reload
+func Evaluate(name string, userId string) models.FeatureEvaluation {
+	return evaluate(name, userId, Current().Features)
+}
+
+// EvaluateAll evaluates every configured flag for the user, sorted by name
+func EvaluateAll(userId string) []models.FeatureEvaluation {
+	flags := Current().Features
+
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	evaluations := make([]models.FeatureEvaluation, 0, len(names))
+	for _, name := range names {
+		evaluations = append(evaluations, evaluate(name, userId, flags))
+	}
+	return evaluations
+}
+
+// Enabled checks the flag for the caller stored on the request context by the auth middleware
+func Enabled(ctx context.Context, name string) bool {
+	var userId string
+	if p, ok := auth.FromContext(ctx); ok {
+		userId = p.UserId
+	}
+
+	return Evaluate(name, userId).Enabled
+}
+
+// Require hides a route behind a flag, callers without the feature get the same 404 as an unknown route
+func Require(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Enabled(c.Request.Context(), name) {
+			c.String(http.StatusNotFound, "404 page not found")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func evaluate(name string, userId string, flags map[string]FeatureFlag) models.FeatureEvaluation {
+	result := models.FeatureEvaluation{Name: name}
+
+	flag, ok := flags[name]
+	switch {
+	case !ok:
+		result.Reason = ReasonUnknown
+	case !flag.Enabled:
+		result.Reason = ReasonDisabled
+	case userId != "" && slices.Contains(flag.Users, userId):
+		result.Enabled, result.Reason = true, ReasonUserList
+	case flag.RolloutPercent == nil || *flag.RolloutPercent >= 100:
+		result.Enabled, result.Reason = true, ReasonEnabled
+	case userId != "" && bucket(name, userId) < *flag.RolloutPercent:
+		result.Enabled, result.Reason = true, ReasonInRollout
+	default:
+		result.Reason = ReasonOutRollout
+	}
+
+	return result
+}
+
+// bucket places the user in 0-99 for the flag, hashing the flag name too so the same users
+// aren't always first in every rollout
+func bucket(name string, userId string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(name + ":" + userId))
+	return int(hash.Sum32() % 100)
+}
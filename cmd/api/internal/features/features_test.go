@@ -0,0 +1,55 @@
+package features
+
+import (
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSettings = `{
+	"connectionStrings": {"stocksDb": "test"},
+	"apiSettings": {"key": "test"}%s
+}`
+
+func TestRequireHidesRoutesBehindFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		features string
+		want     int
+	}{
+		{"indicators are on by default", "", http.StatusOK},
+		{"disabled", `, "features": {"indicators": {"enabled": false}}`, http.StatusNotFound},
+		{"no one in the rollout", `, "features": {"indicators": {"enabled": true, "rolloutPercent": 0}}`,
+			http.StatusNotFound},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/stocks/indicators/sma", Require(Indicators), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			settings := []byte(fmt.Sprintf(testSettings, test.features))
+			if err := os.WriteFile(filepath.Join(dir, "config.json"), settings, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{Dir: dir}); err != nil {
+				t.Fatal(err)
+			}
+
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/stocks/indicators/sma", nil))
+			if response.Code != test.want {
+				t.Fatalf("got %d, want %d", response.Code, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package notify
+
+import (
+	"context"
+	"github.com/labstack/gommon/log"
+)
+
+// Log a stand-in Notifier that logs messages rather than sending them
+type Log struct{}
+
+func (l *Log) Send(ctx context.Context, message Message) error {
+	log.Infof("email to %s: %s\n%s", message.To, message.Subject, message.Text)
+	return nil
+}
@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+)
+
+// Message an email with plain text and html bodies, Headers are added as they are e.g. List-Unsubscribe
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	Html    string
+	Headers map[string]string
+}
+
+// Notifier sends messages to users
+type Notifier interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewNotifier sends through smtp when smtp.host is set, otherwise messages are only logged so the api
+// can run locally without a mail server. The choice is made for each message so reloads are picked up
+func NewNotifier() Notifier {
+	return &configured{log: &Log{}, smtp: &Smtp{}}
+}
+
+// configured sends each message with whichever Notifier the current configuration asks for
+type configured struct {
+	log  Notifier
+	smtp Notifier
+}
+
+func (c *configured) Send(ctx context.Context, message Message) error {
+	if configuration.Current().Smtp.Host == "" {
+		return c.log.Send(ctx, message)
+	}
+	return c.smtp.Send(ctx, message)
+}
@@ -0,0 +1,105 @@
+// Package notifytest an in-process smtp server for testing what gets emailed
+package notifytest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// SmtpServer a minimal smtp server, without STARTTLS or AUTH, that passes on every message sent to it
+type SmtpServer struct {
+	listener net.Listener
+	Received chan ReceivedMail
+}
+
+// ReceivedMail the envelope commands and the data of a message, dot-stuffing removed
+type ReceivedMail struct {
+	From string
+	To   string
+	Data string
+}
+
+// NewSmtpServer starts a server on a random local port, it's stopped when the test ends
+func NewSmtpServer(t *testing.T) *SmtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &SmtpServer{listener: listener, Received: make(chan ReceivedMail, 16)}
+	go server.serve()
+	return server
+}
+
+func (s *SmtpServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *SmtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *SmtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	var message ReceivedMail
+	reply("220 goapi.test ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+
+		switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 goapi.test")
+		case "MAIL":
+			message.From = command
+			reply("250 OK")
+		case "RCPT":
+			message.To = command
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			message.Data = data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			s.Received <- message
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
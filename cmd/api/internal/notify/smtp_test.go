@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/notify/notifytest"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSettings = `{
+	"connectionStrings": {"stocksDb": "test"},
+	"apiSettings": {"key": "test"},
+	"smtp": {"host": "127.0.0.1", "port": %d, "from": "GoApi <digest@goapi.test>"}
+}`
+
+func TestSmtpSend(t *testing.T) {
+	server := notifytest.NewSmtpServer(t)
+
+	dir := t.TempDir()
+	settings := []byte(fmt.Sprintf(testSettings, server.Port()))
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), settings, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	//long lines and non-ascii characters both need quoted-printable encoding
+	text := "Your favourites today: AAPL closed at £182.52, " + strings.Repeat("MSFT up 1.2% ", 8)
+	html := "<p>Your favourites today: <b>AAPL</b> closed at £182.52</p>" + strings.Repeat("<p>MSFT up 1.2%</p>", 8)
+	unsubscribe := "<https://goapi.test/api/v1/digest/unsubscribe?token=abc>"
+
+	message := Message{
+		To:      "Investor <investor@goapi.test>",
+		Subject: "Your daily digest",
+		Text:    text,
+		Html:    html,
+		Headers: map[string]string{
+			"List-Unsubscribe":      unsubscribe,
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := NewNotifier().Send(ctx, message); err != nil {
+		t.Fatal(err)
+	}
+
+	var received notifytest.ReceivedMail
+	select {
+	case received = <-server.Received:
+	case <-ctx.Done():
+		t.Fatal("the smtp server didn't receive a message")
+	}
+
+	if received.From != "MAIL FROM:<digest@goapi.test>" || !strings.HasPrefix(received.To, "RCPT TO:<investor@goapi.test>") {
+		t.Fatalf("envelope %q %q, want from digest@goapi.test to investor@goapi.test", received.From, received.To)
+	}
+
+	sent, err := mail.ReadMessage(strings.NewReader(received.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sent.Header.Get("List-Unsubscribe"); got != unsubscribe {
+		t.Fatalf("List-Unsubscribe %q, want %q", got, unsubscribe)
+	}
+	if got := sent.Header.Get("List-Unsubscribe-Post"); got != "List-Unsubscribe=One-Click" {
+		t.Fatalf("List-Unsubscribe-Post %q, want List-Unsubscribe=One-Click", got)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(sent.Header.Get("Subject")); subject != message.Subject {
+		t.Fatalf("subject %q, want %q", subject, message.Subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(sent.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("content type %s, want multipart/alternative", mediaType)
+	}
+
+	//plain text first so clients that can show html prefer it
+	parts := multipart.NewReader(sent.Body, params["boundary"])
+	for _, want := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("reading the %s part: %s", want.contentType, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Fatalf("part content type %q, want %q", got, want.contentType)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Fatalf("%s part encoding %q, want quoted-printable", want.contentType, got)
+		}
+
+		raw, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(raw), "\r\n") {
+			if len(line) > 76 {
+				t.Fatalf("%s part has a %d character line, quoted-printable lines are at most 76", want.contentType, len(line))
+			}
+		}
+		if !strings.Contains(string(raw), "=C2=A3") {
+			t.Fatalf("%s part doesn't quoted-printable encode £: %s", want.contentType, raw)
+		}
+
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(raw))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != want.content {
+			t.Fatalf("%s part decodes to %q, want %q", want.contentType, decoded, want.content)
+		}
+	}
+
+	if _, err := parts.NextRawPart(); err != io.EOF {
+		t.Fatalf("want exactly two parts, got %v reading a third", err)
+	}
+}
@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var funcs = map[string]any{
+	"signed": func(value float64) string {
+		if value > 0 {
+			return "+"
+		}
+		return ""
+	},
+}
+
+var (
+	htmlTemplates = htmlTemplate.Must(htmlTemplate.New("").Funcs(funcs).ParseFS(templateFiles, "templates/*.html"))
+	textTemplates = textTemplate.Must(textTemplate.New("").Funcs(funcs).ParseFS(templateFiles, "templates/*.txt"))
+)
+
+// Render executes the name.txt and name.html templates with data
+func Render(name string, data any) (text string, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+
+	if err := textTemplates.ExecuteTemplate(&textBuf, name+".txt", data); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&htmlBuf, name+".html", data); err != nil {
+		return "", "", err
+	}
+
+	return textBuf.String(), htmlBuf.String(), nil
+}
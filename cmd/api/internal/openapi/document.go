@@ -0,0 +1,284 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation describes one route, handlers declare these next to where they register the route
+type Operation struct {
+	Method      string
+	Path        string //gin style path, e.g. /admin/keys/:id
+	Summary     string
+	Tag         string
+	Query       any //struct bound with ShouldBindQuery, form tags become query parameters
+	Uri         any //struct bound with ShouldBindUri
+	Body        any //struct bound with ShouldBindJSON
+	Status      int
+	ResponseKey string //key the handler wraps its result in, e.g. "data"
+	Response    any
+	ContentType string //of the response, defaults to application/json
+	Public      bool
+	Deprecated  bool
+}
+
+type Document struct {
+	OpenApi    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]*PathItem `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type PathItem struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	OperationId string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+var ginParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+var errorSchema = &Schema{
+	Type:       "object",
+	Properties: map[string]*Schema{"error": {Type: "string"}},
+}
+
+// Build generates an OpenAPI 3.1 document from the operations, apiKeyHeader names the header api keys are sent in
+func Build(title string, version string, apiKeyHeader string, operations []Operation) *Document {
+	registry := newSchemaRegistry()
+
+	document := &Document{
+		OpenApi: "3.1.0",
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]map[string]*PathItem),
+		Components: Components{
+			Schemas: registry.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				"ApiKeyAuth": {Type: "apiKey", In: "header", Name: apiKeyHeader},
+				"BearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []map[string][]string{{"ApiKeyAuth": {}}, {"BearerAuth": {}}},
+	}
+
+	for _, op := range operations {
+		path := OpenApiPath(op.Path)
+		if document.Paths[path] == nil {
+			document.Paths[path] = make(map[string]*PathItem)
+		}
+		document.Paths[path][strings.ToLower(op.Method)] = buildPathItem(registry, op)
+	}
+
+	return document
+}
+
+func buildPathItem(registry *schemaRegistry, op Operation) *PathItem {
+	item := &PathItem{
+		Summary:     op.Summary,
+		OperationId: operationId(op),
+		Responses:   make(map[string]Response),
+		Deprecated:  op.Deprecated,
+	}
+
+	if op.Tag != "" {
+		item.Tags = []string{op.Tag}
+	}
+	if op.Public {
+		item.Security = []map[string][]string{}
+	}
+	if op.Query != nil {
+		item.Parameters = append(item.Parameters, parameters(registry, op.Query, "form", "query")...)
+	}
+	if op.Uri != nil {
+		item.Parameters = append(item.Parameters, parameters(registry, op.Uri, "uri", "path")...)
+	}
+	if op.Body != nil {
+		item.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: registry.schemaFor(reflect.TypeOf(op.Body))}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := Response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		schema := registry.schemaFor(reflect.TypeOf(op.Response))
+		if op.ResponseKey != "" {
+			schema = &Schema{Type: "object", Properties: map[string]*Schema{op.ResponseKey: schema}}
+		}
+		contentType := op.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success.Content = map[string]MediaType{contentType: {Schema: schema}}
+	}
+	item.Responses[fmt.Sprint(status)] = success
+
+	errorResponse := map[string]MediaType{"application/json": {Schema: errorSchema}}
+	item.Responses["400"] = Response{Description: "Bad Request", Content: errorResponse}
+	if !op.Public {
+		item.Responses["401"] = Response{Description: "Unauthorized", Content: errorResponse}
+		item.Responses["403"] = Response{Description: "Forbidden", Content: errorResponse}
+		item.Responses["429"] = Response{Description: "Too Many Requests", Content: errorResponse}
+	}
+
+	return item
+}
+
+func parameters(registry *schemaRegistry, params any, tagKey string, in string) []Parameter {
+	t := reflect.TypeOf(params)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var result []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, ok := fieldName(field, tagKey)
+		if !ok {
+			continue
+		}
+
+		schema := registry.schemaFor(field.Type)
+		if def := field.Tag.Get("default"); def != "" {
+			schema.Default = defaultValue(def, field.Type)
+		}
+
+		result = append(result, Parameter{
+			Name:     name,
+			In:       in,
+			Required: in == "path" || isRequired(field),
+			Schema:   schema,
+		})
+	}
+
+	return result
+}
+
+// defaultValue default tags are strings, non string fields should show their typed value
+func defaultValue(def string, t reflect.Type) any {
+	if t.Kind() == reflect.String {
+		return def
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(def), &value); err != nil {
+		return def
+	}
+	return value
+}
+
+// OpenApiPath converts gin's :param segments to OpenAPI's {param}
+func OpenApiPath(ginPath string) string {
+	return ginParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+func operationId(op Operation) string {
+	id := strings.ToLower(op.Method)
+	for _, segment := range strings.FieldsFunc(op.Path, func(r rune) bool { return r == '/' || r == ':' }) {
+		id += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return id
+}
+
+// Verify compares the document to the routes gin has registered, routes under ignored prefixes aren't checked
+func (d *Document) Verify(routes gin.RoutesInfo, ignored ...string) error {
+	registered := make(map[string]bool)
+	var missing []string
+
+	for _, route := range routes {
+		if hasAnyPrefix(route.Path, ignored) {
+			continue
+		}
+
+		path := OpenApiPath(route.Path)
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+
+		if _, ok := d.Paths[path][method]; !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+
+	var stale []string
+	for path, methods := range d.Paths {
+		for method := range methods {
+			if !registered[method+" "+path] {
+				stale = append(stale, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+
+	if len(missing) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(stale)
+	return fmt.Errorf("openapi document out of sync with routes, undocumented: %v, not registered: %v", missing, stale)
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
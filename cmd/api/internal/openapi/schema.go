@@ -0,0 +1,154 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	polyModels "github.com/polygon-io/client-go/rest/models"
+)
+
+// Schema the subset of JSON Schema the generator produces
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Default              any                `json:"default,omitempty"`
+}
+
+// knownSchemas types whose json form doesn't match their go shape
+var knownSchemas = map[reflect.Type]Schema{
+	reflect.TypeOf(time.Time{}):         {Type: "string", Format: "date-time"},
+	reflect.TypeOf(polyModels.Date{}):   {Type: "string", Format: "date"},
+	reflect.TypeOf(polyModels.Millis{}): {Type: "integer", Format: "int64"},
+	reflect.TypeOf(polyModels.Nanos{}):  {Type: "integer", Format: "int64"},
+}
+
+// schemaRegistry builds schemas for go types, named structs are added to components and referenced
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+func (r *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if known, ok := knownSchemas[t]; ok {
+		return &known
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + r.register(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// register adds a named struct to components, the placeholder is stored first so recursive types terminate
+func (r *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := r.components[name]; taken {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+
+	r.names[t] = name
+	r.components[name] = &Schema{}
+	*r.components[name] = *r.structSchema(t)
+
+	return name
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.addFields(schema, t, "json")
+	return schema
+}
+
+// addFields flattens embedded structs the same way encoding/json does
+func (r *schemaRegistry) addFields(schema *Schema, t reflect.Type, tagKey string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get(tagKey) == "" {
+			r.addFields(schema, field.Type, tagKey)
+			continue
+		}
+
+		name, ok := fieldName(field, tagKey)
+		if !ok {
+			continue
+		}
+
+		schema.Properties[name] = r.schemaFor(field.Type)
+		if isRequired(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// fieldName the name a field is bound under for the tag, false if the field isn't bound
+func fieldName(field reflect.StructField, tagKey string) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	tag := field.Tag.Get(tagKey)
+	if tag == "-" {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		if tagKey != "json" {
+			return "", false
+		}
+		name = field.Name
+	}
+
+	return name, true
+}
+
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// SwaggerUI page that renders the document served at /openapi.json
+//
+//go:embed swagger.html
+var SwaggerUI []byte
+
+//go:embed swagger-ui
+var swaggerAssets embed.FS
+
+// SwaggerAssets the swagger-ui-dist 5.18.2 files the page loads, they're served by the api itself so /docs
+// works offline and always runs the version checked in here
+var SwaggerAssets, _ = fs.Sub(swaggerAssets, "swagger-ui")
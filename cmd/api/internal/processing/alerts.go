@@ -0,0 +1,68 @@
+package processing
+
+import (
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"math"
+)
+
+// sides of the sma a close can be on
+const (
+	SideAbove = "above"
+	SideBelow = "below"
+)
+
+// AlertEvaluation the outcome of checking an alert against the latest bar, State is stored for the next check
+type AlertEvaluation struct {
+	Triggered bool
+	State     string
+	Value     float64
+	Message   string
+}
+
+// EvaluateAlert checks the alert against the latest daily bar's open and close, sma is only used by
+// the sma cross conditions. A cross only fires when the previous state was the other side so the first
+// check just records where the close is
+func EvaluateAlert(alert Alert, open float64, close float64, sma float64) AlertEvaluation {
+	switch alert.Condition {
+	case AlertPriceAbove:
+		return AlertEvaluation{
+			Triggered: close > alert.Threshold,
+			Value:     close,
+			Message:   fmt.Sprintf("%s closed at %.2f, above %.2f", alert.Ticker, close, alert.Threshold),
+		}
+	case AlertPriceBelow:
+		return AlertEvaluation{
+			Triggered: close < alert.Threshold,
+			Value:     close,
+			Message:   fmt.Sprintf("%s closed at %.2f, below %.2f", alert.Ticker, close, alert.Threshold),
+		}
+	case AlertPercentMove:
+		move := percentage(close-open, open)
+		return AlertEvaluation{
+			Triggered: open != 0 && math.Abs(move) >= alert.Threshold,
+			Value:     move,
+			Message:   fmt.Sprintf("%s moved %.2f%% in a day", alert.Ticker, move),
+		}
+	case AlertSmaCrossAbove, AlertSmaCrossBelow:
+		state := SideBelow
+		if close > sma {
+			state = SideAbove
+		}
+
+		target := SideAbove
+		if alert.Condition == AlertSmaCrossBelow {
+			target = SideBelow
+		}
+
+		return AlertEvaluation{
+			Triggered: alert.LastState != "" && alert.LastState != target && state == target,
+			State:     state,
+			Value:     sma,
+			Message: fmt.Sprintf("%s closed at %.2f, crossing %s its %d day sma of %.2f", alert.Ticker, close,
+				target, alert.Window, sma),
+		}
+	}
+
+	return AlertEvaluation{}
+}
@@ -0,0 +1,56 @@
+package processing
+
+import (
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"math"
+	"testing"
+)
+
+func TestEvaluateAlert(t *testing.T) {
+	tests := []struct {
+		name          string
+		alert         Alert
+		open          float64
+		close         float64
+		sma           float64
+		wantTriggered bool
+		wantState     string
+		wantValue     float64
+	}{
+		{"price above", Alert{Condition: AlertPriceAbove, Threshold: 100}, 95, 101, 0, true, "", 101},
+		{"price at the threshold isn't above", Alert{Condition: AlertPriceAbove, Threshold: 100}, 95, 100, 0, false, "", 100},
+		{"price below", Alert{Condition: AlertPriceBelow, Threshold: 100}, 105, 99, 0, true, "", 99},
+		{"price at the threshold isn't below", Alert{Condition: AlertPriceBelow, Threshold: 100}, 105, 100, 0, false, "", 100},
+
+		{"move up past the threshold", Alert{Condition: AlertPercentMove, Threshold: 5}, 100, 106, 0, true, "", 6},
+		{"move down past the threshold", Alert{Condition: AlertPercentMove, Threshold: 5}, 100, 94, 0, true, "", -6},
+		{"move of exactly the threshold", Alert{Condition: AlertPercentMove, Threshold: 5}, 100, 105, 0, true, "", 5},
+		{"move inside the threshold", Alert{Condition: AlertPercentMove, Threshold: 5}, 100, 104, 0, false, "", 4},
+		{"no open can't move", Alert{Condition: AlertPercentMove, Threshold: 5}, 0, 10, 0, false, "", 0},
+
+		{"first check only records the side", Alert{Condition: AlertSmaCrossAbove}, 0, 105, 100, false, SideAbove, 100},
+		{"cross above", Alert{Condition: AlertSmaCrossAbove, LastState: SideBelow}, 0, 105, 100, true, SideAbove, 100},
+		{"staying above isn't a cross", Alert{Condition: AlertSmaCrossAbove, LastState: SideAbove}, 0, 105, 100, false,
+			SideAbove, 100},
+		{"crossing the other way", Alert{Condition: AlertSmaCrossAbove, LastState: SideAbove}, 0, 95, 100, false,
+			SideBelow, 100},
+		{"closing on the sma is below", Alert{Condition: AlertSmaCrossAbove, LastState: SideBelow}, 0, 100, 100, false,
+			SideBelow, 100},
+		{"cross below", Alert{Condition: AlertSmaCrossBelow, LastState: SideAbove}, 0, 95, 100, true, SideBelow, 100},
+		{"first check below doesn't fire", Alert{Condition: AlertSmaCrossBelow}, 0, 95, 100, false, SideBelow, 100},
+		{"staying below isn't a cross", Alert{Condition: AlertSmaCrossBelow, LastState: SideBelow}, 0, 95, 100, false,
+			SideBelow, 100},
+
+		{"unknown condition", Alert{Condition: "volume_above", Threshold: 1}, 100, 200, 0, false, "", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := EvaluateAlert(test.alert, test.open, test.close, test.sma)
+			if got.Triggered != test.wantTriggered || got.State != test.wantState || math.Abs(got.Value-test.wantValue) > tolerance {
+				t.Fatalf("got triggered %t, state %q, value %g, want %t, %q, %g", got.Triggered, got.State, got.Value,
+					test.wantTriggered, test.wantState, test.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package processing
+
+import (
+	"errors"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// TradingDaysPerYear used to annualise daily figures
+const TradingDaysPerYear = 252
+
+// ClosePrice a ticker's close on a trading day, Day is midnight UTC of the trading date
+type ClosePrice struct {
+	Day   time.Time
+	Close float64
+}
+
+// CashFlow money paid in (negative) or taken out (positive) by the investor, as used for money-weighted return
+type CashFlow struct {
+	Day    time.Time
+	Amount float64
+}
+
+// TradingDays every day any of the tickers has a close between from and to inclusive, in order
+func TradingDays(closes map[string][]ClosePrice, from time.Time, to time.Time) []time.Time {
+	seen := make(map[time.Time]bool)
+	var days []time.Time
+
+	for _, prices := range closes {
+		for _, price := range prices {
+			if seen[price.Day] || price.Day.Before(from) || price.Day.After(to) {
+				continue
+			}
+			seen[price.Day] = true
+			days = append(days, price.Day)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+	return days
+}
+
+// PriceReturns the daily returns of holding a single ticker over the trading days, a missing close is
+// carried forward from the day before
+func PriceReturns(prices []ClosePrice, days []time.Time) []float64 {
+	closeOn := make(map[time.Time]float64, len(prices))
+	for _, price := range prices {
+		closeOn[price.Day] = price.Close
+	}
+
+	values := make([]float64, len(days))
+	for i, day := range days {
+		if close, ok := closeOn[day]; ok {
+			values[i] = close
+		} else if i > 0 {
+			values[i] = values[i-1]
+		}
+	}
+
+	return DailyReturns(values, make([]float64, len(values)))
+}
+
+// ValueSeries replays the transactions over the trading days and values the holdings at each day's close.
+// Transactions up to and including the first day make up the starting value, later ones are external flows
+// on the first trading day on or after they executed: buys pay money in, sells and dividends take it out.
+// A ticker missing a close that day is valued at its last close or, failing that, its last trade price
+func ValueSeries(transactions []Transaction, closes map[string][]ClosePrice, days []time.Time) (values []float64, flows []float64) {
+	ordered := make([]Transaction, len(transactions))
+	copy(ordered, transactions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].ExecutedAt.Before(ordered[j].ExecutedAt)
+	})
+
+	closeOn := make(map[string]map[time.Time]float64, len(closes))
+	for ticker, prices := range closes {
+		closeOn[ticker] = make(map[time.Time]float64, len(prices))
+		for _, price := range prices {
+			closeOn[ticker][price.Day] = price.Close
+		}
+	}
+
+	held := make(map[string]float64)
+	lastPrice := make(map[string]float64)
+	values = make([]float64, len(days))
+	flows = make([]float64, len(days))
+	next := 0
+
+	for i, day := range days {
+		endOfDay := day.AddDate(0, 0, 1)
+
+		for ; next < len(ordered) && ordered[next].ExecutedAt.Before(endOfDay); next++ {
+			transaction := ordered[next]
+			flow := 0.0
+
+			switch transaction.Type {
+			case TransactionBuy:
+				held[transaction.Ticker] += transaction.Quantity
+				lastPrice[transaction.Ticker] = transaction.Price
+				flow = transaction.Quantity*transaction.Price + transaction.Fees
+			case TransactionSell:
+				held[transaction.Ticker] -= transaction.Quantity
+				lastPrice[transaction.Ticker] = transaction.Price
+				flow = -(transaction.Quantity*transaction.Price - transaction.Fees)
+			case TransactionDividend:
+				flow = -transaction.Amount
+			case TransactionSplit:
+				held[transaction.Ticker] *= transaction.Ratio
+				if price, ok := lastPrice[transaction.Ticker]; ok {
+					lastPrice[transaction.Ticker] = price / transaction.Ratio
+				}
+			}
+
+			if i > 0 {
+				flows[i] += flow
+			}
+		}
+
+		for ticker, quantity := range held {
+			if close, ok := closeOn[ticker][day]; ok {
+				lastPrice[ticker] = close
+			}
+			values[i] += quantity * lastPrice[ticker]
+		}
+	}
+
+	return values, flows
+}
+
+// DailyReturns the return of each day after the first, flows are assumed to arrive at the end of the day as
+// trades are valued at the close. Days that start with nothing invested have a return of 0
+func DailyReturns(values []float64, flows []float64) []float64 {
+	if len(values) < 2 {
+		return nil
+	}
+
+	returns := make([]float64, len(values)-1)
+	for i := 1; i < len(values); i++ {
+		if values[i-1] <= 0 {
+			continue
+		}
+		returns[i-1] = (values[i]-flows[i])/values[i-1] - 1
+	}
+
+	return returns
+}
+
+// TimeWeightedReturn chains the daily returns so flows in and out don't affect the result
+func TimeWeightedReturn(returns []float64) float64 {
+	growth := 1.0
+	for _, r := range returns {
+		growth *= 1 + r
+	}
+	return growth - 1
+}
+
+// MoneyWeightedReturn the annualised internal rate of return of the cash flows, the rate at which their
+// present value is 0. Found with newton's method, falling back to bisection when it doesn't converge
+func MoneyWeightedReturn(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, errors.New("at least two cash flows are needed")
+	}
+
+	start := flows[0].Day
+	years := make([]float64, len(flows))
+	hasIn, hasOut := false, false
+	for i, flow := range flows {
+		years[i] = flow.Day.Sub(start).Hours() / 24 / 365
+		hasIn = hasIn || flow.Amount < 0
+		hasOut = hasOut || flow.Amount > 0
+	}
+	if !hasIn || !hasOut {
+		return 0, errors.New("cash flows must include money both paid in and taken out")
+	}
+
+	presentValue := func(rate float64) (value float64, derivative float64) {
+		for i, flow := range flows {
+			discount := math.Pow(1+rate, years[i])
+			value += flow.Amount / discount
+			derivative -= years[i] * flow.Amount / (discount * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	rate := 0.1
+	for i := 0; i < 100; i++ {
+		value, derivative := presentValue(rate)
+		if math.Abs(value) < 1e-9 {
+			return rate, nil
+		}
+		if derivative == 0 {
+			break
+		}
+
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-12 {
+			return next, nil
+		}
+		rate = next
+	}
+
+	//bisection between just above -100% and a rate high enough to bracket the root
+	low, high := -0.9999, 10.0
+	lowValue, _ := presentValue(low)
+	highValue, _ := presentValue(high)
+	if lowValue*highValue > 0 {
+		return 0, errors.New("money-weighted return doesn't converge for these cash flows")
+	}
+
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		midValue, _ := presentValue(mid)
+		if math.Abs(midValue) < 1e-9 || (high-low)/2 < 1e-12 {
+			return mid, nil
+		}
+		if midValue*lowValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+
+	return (low + high) / 2, nil
+}
+
+// AnnualisedVolatility the sample standard deviation of the daily returns scaled to a year
+func AnnualisedVolatility(returns []float64) float64 {
+	return standardDeviation(returns) * math.Sqrt(TradingDaysPerYear)
+}
+
+// SharpeRatio the annualised excess return over the risk free rate per unit of volatility. riskFreeRate
+// is annual, e.g. 0.04 for 4%
+func SharpeRatio(returns []float64, riskFreeRate float64) float64 {
+	deviation := standardDeviation(returns)
+	if deviation == 0 {
+		return 0
+	}
+
+	excess := mean(returns) - riskFreeRate/TradingDaysPerYear
+	return excess / deviation * math.Sqrt(TradingDaysPerYear)
+}
+
+// SortinoRatio like the sharpe ratio but only penalising returns below the risk free rate
+func SortinoRatio(returns []float64, riskFreeRate float64) float64 {
+	if len(returns) == 0 {
+		return 0
+	}
+
+	target := riskFreeRate / TradingDaysPerYear
+	sumSquares := 0.0
+	for _, r := range returns {
+		if r < target {
+			sumSquares += (r - target) * (r - target)
+		}
+	}
+
+	downside := math.Sqrt(sumSquares / float64(len(returns)))
+	if downside == 0 {
+		return 0
+	}
+
+	return (mean(returns) - target) / downside * math.Sqrt(TradingDaysPerYear)
+}
+
+// MaxDrawdown the largest fall from a peak in the growth of the daily returns, as a fraction of the peak
+// e.g. 0.2 for a 20% fall
+func MaxDrawdown(returns []float64) float64 {
+	growth, peak, drawdown := 1.0, 1.0, 0.0
+
+	for _, r := range returns {
+		growth *= 1 + r
+		peak = math.Max(peak, growth)
+		if peak > 0 {
+			drawdown = math.Max(drawdown, (peak-growth)/peak)
+		}
+	}
+
+	return drawdown
+}
+
+// Beta how much the returns move with the benchmark's, the covariance of the two over the benchmark's
+// variance. Both slices must cover the same days
+func Beta(returns []float64, benchmark []float64) float64 {
+	n := min(len(returns), len(benchmark))
+	if n < 2 {
+		return 0
+	}
+	returns, benchmark = returns[:n], benchmark[:n]
+
+	meanReturns, meanBenchmark := mean(returns), mean(benchmark)
+	covariance, variance := 0.0, 0.0
+	for i := 0; i < n; i++ {
+		covariance += (returns[i] - meanReturns) * (benchmark[i] - meanBenchmark)
+		variance += (benchmark[i] - meanBenchmark) * (benchmark[i] - meanBenchmark)
+	}
+
+	if variance == 0 {
+		return 0
+	}
+	return covariance / variance
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func standardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	m := mean(values)
+	sumSquares := 0.0
+	for _, v := range values {
+		sumSquares += (v - m) * (v - m)
+	}
+	return math.Sqrt(sumSquares / float64(len(values)-1))
+}
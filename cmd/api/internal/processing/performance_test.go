@@ -0,0 +1,274 @@
+package processing
+
+import (
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"math"
+	"testing"
+	"time"
+)
+
+const tolerance = 1e-6
+
+func day(offset int) time.Time {
+	return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, offset)
+}
+
+func TestDailyReturns(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		flows  []float64
+		want   []float64
+	}{
+		{"empty", nil, nil, nil},
+		{"single point", []float64{100}, []float64{0}, nil},
+		{"no flows", []float64{100, 110, 99}, []float64{0, 0, 0}, []float64{0.1, -0.1}},
+		{"flows are taken out of the day's growth", []float64{100, 260}, []float64{0, 150}, []float64{0.1}},
+		{"nothing invested", []float64{0, 100, 110}, []float64{0, 100, 0}, []float64{0, 0.1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := DailyReturns(test.values, test.flows)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-test.want[i]) > tolerance {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeWeightedReturn(t *testing.T) {
+	tests := []struct {
+		name    string
+		returns []float64
+		want    float64
+	}{
+		{"empty", nil, 0},
+		{"single point", []float64{0.05}, 0.05},
+		{"zero returns", []float64{0, 0, 0}, 0},
+		{"returns are chained", []float64{0.1, -0.1}, -0.01},
+		{"total loss", []float64{0.5, -1}, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := TimeWeightedReturn(test.returns); math.Abs(got-test.want) > tolerance {
+				t.Fatalf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMoneyWeightedReturn(t *testing.T) {
+	tests := []struct {
+		name    string
+		flows   []CashFlow
+		want    float64
+		wantErr bool
+	}{
+		{"no flows", nil, 0, true},
+		{"single flow", []CashFlow{{day(0), -100}}, 0, true},
+		{"nothing taken out", []CashFlow{{day(0), -100}, {day(365), -10}}, 0, true},
+		{"nothing paid in", []CashFlow{{day(0), 100}, {day(365), 10}}, 0, true},
+		{"zero value flows", []CashFlow{{day(0), 0}, {day(365), 0}}, 0, true},
+		{"one year", []CashFlow{{day(0), -100}, {day(365), 110}}, 0.1, false},
+		{"part of a year is annualised", []CashFlow{{day(0), -100}, {day(182), 110}},
+			math.Pow(1.1, 365/182.0) - 1, false},
+		{"flow during the period", []CashFlow{{day(0), -100}, {day(365), -100}, {day(730), 231}}, 0.1, false},
+		//newton's first step from 10% overshoots below -100%, so the rate is found by bisection
+		{"bisection fallback", []CashFlow{{day(0), -100}, {day(365), 1}}, -0.99, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := MoneyWeightedReturn(test.flows)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("got %g, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-test.want) > tolerance {
+				t.Fatalf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAnnualisedVolatility(t *testing.T) {
+	tests := []struct {
+		name    string
+		returns []float64
+		want    float64
+	}{
+		{"empty", nil, 0},
+		{"single point", []float64{0.05}, 0},
+		{"zero returns", []float64{0, 0, 0}, 0},
+		{"constant returns", []float64{0.01, 0.01, 0.01}, 0},
+		{"sample deviation scaled to a year", []float64{0.01, -0.01}, 0.224499443},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := AnnualisedVolatility(test.returns); math.Abs(got-test.want) > tolerance {
+				t.Fatalf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSharpeRatio(t *testing.T) {
+	tests := []struct {
+		name         string
+		returns      []float64
+		riskFreeRate float64
+		want         float64
+	}{
+		{"empty", nil, 0.04, 0},
+		{"single point", []float64{0.05}, 0.04, 0},
+		{"zero returns", []float64{0, 0, 0}, 0.04, 0},
+		{"no risk free rate", []float64{0.01, -0.01, 0.02, 0}, 0, 6.148170460},
+		{"risk free rate is taken off daily", []float64{0.01, -0.01, 0.02, 0}, 0.04, 5.952990445},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := SharpeRatio(test.returns, test.riskFreeRate); math.Abs(got-test.want) > tolerance {
+				t.Fatalf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSortinoRatio(t *testing.T) {
+	tests := []struct {
+		name         string
+		returns      []float64
+		riskFreeRate float64
+		want         float64
+	}{
+		{"empty", nil, 0.04, 0},
+		{"zero returns", []float64{0, 0, 0}, 0, 0},
+		{"no downside", []float64{0.01, 0.02}, 0, 0},
+		{"single point below the target", []float64{-0.01}, 0, -math.Sqrt(TradingDaysPerYear)},
+		{"no risk free rate", []float64{0.01, -0.01, 0.02, 0}, 0, math.Sqrt(TradingDaysPerYear)},
+		{"returns below the risk free rate count as downside", []float64{0.01, -0.01, 0.02, 0}, 0.04, 15.128543677},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := SortinoRatio(test.returns, test.riskFreeRate); math.Abs(got-test.want) > tolerance {
+				t.Fatalf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		returns []float64
+		want    float64
+	}{
+		{"empty", nil, 0},
+		{"single point", []float64{-0.1}, 0.1},
+		{"zero returns", []float64{0, 0, 0}, 0},
+		{"only rising", []float64{0.1, 0.2}, 0},
+		{"fall from a later peak", []float64{0.1, -0.5, 0.2}, 0.5},
+		{"largest of several falls", []float64{-0.1, 0.5, -0.2, -0.25, 1}, 0.4},
+		{"total loss", []float64{0.1, -1}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MaxDrawdown(test.returns); math.Abs(got-test.want) > tolerance {
+				t.Fatalf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBeta(t *testing.T) {
+	tests := []struct {
+		name      string
+		returns   []float64
+		benchmark []float64
+		want      float64
+	}{
+		{"empty", nil, nil, 0},
+		{"single point", []float64{0.02}, []float64{0.01}, 0},
+		{"zero returns", []float64{0, 0, 0}, []float64{0.01, -0.01, 0.02}, 0},
+		{"flat benchmark", []float64{0.01, -0.01, 0.02}, []float64{0, 0, 0}, 0},
+		{"moves with the benchmark", []float64{0.01, -0.01, 0.02}, []float64{0.01, -0.01, 0.02}, 1},
+		{"twice the benchmark", []float64{0.02, -0.02, 0.04}, []float64{0.01, -0.01, 0.02}, 2},
+		{"against the benchmark", []float64{-0.005, 0.005, -0.01}, []float64{0.01, -0.01, 0.02}, -0.5},
+		{"extra days are ignored", []float64{0.02, -0.02, 0.04, 0.5}, []float64{0.01, -0.01, 0.02}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Beta(test.returns, test.benchmark); math.Abs(got-test.want) > tolerance {
+				t.Fatalf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValueSeriesWithSplits(t *testing.T) {
+	at := func(offset int, hour int) time.Time {
+		return day(offset).Add(time.Duration(hour) * time.Hour)
+	}
+	buy := func(quantity float64, price float64, executedAt time.Time) Transaction {
+		return Transaction{Ticker: "NVDA", Type: TransactionBuy, Quantity: quantity, Price: price, ExecutedAt: executedAt}
+	}
+	//the row SplitRepository.ApplySplit writes for a split executing on day 2
+	split := func(ratio float64) Transaction {
+		return SplitTransaction("split", "portfolio", Split{Ticker: "NVDA", ExecutionDate: day(2), Ratio: ratio}, at(2, 13))
+	}
+	closes := func(prices ...float64) map[string][]ClosePrice {
+		var closes []ClosePrice
+		for offset, price := range prices {
+			if price != 0 {
+				closes = append(closes, ClosePrice{Day: day(offset), Close: price})
+			}
+		}
+		return map[string][]ClosePrice{"NVDA": closes}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		closes       map[string][]ClosePrice
+		wantValues   []float64
+		wantFlows    []float64
+	}{
+		{"split", []Transaction{buy(10, 400, at(0, 15)), split(4)}, closes(400, 420, 106, 110),
+			[]float64{4000, 4200, 4240, 4400}, []float64{0, 0, 0, 0}},
+		{"buy on the execution date is after the split",
+			[]Transaction{buy(4, 110, at(2, 15)), split(4), buy(10, 400, at(0, 15))}, closes(400, 420, 110, 120), []float64{4000, 4200, 4840, 5280}, []float64{0, 0, 440, 0}},
+		{"no close on the execution date values the last close split", []Transaction{buy(10, 400, at(0, 15)), split(4)},
+			closes(400, 420, 0, 110), []float64{4000, 4200, 4200, 4400}, []float64{0, 0, 0, 0}},
+		{"reverse split", []Transaction{buy(100, 2, at(0, 15)), split(0.1)}, closes(2, 2.1, 21, 22),
+			[]float64{200, 210, 210, 220}, []float64{0, 0, 0, 0}},
+	}
+
+	days := []time.Time{day(0), day(1), day(2), day(3)}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, flows := ValueSeries(test.transactions, test.closes, days)
+			for i := range days {
+				if math.Abs(values[i]-test.wantValues[i]) > tolerance || math.Abs(flows[i]-test.wantFlows[i]) > tolerance {
+					t.Fatalf("got values %v and flows %v, want %v and %v", values, flows, test.wantValues, test.wantFlows)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package processing
+
+import (
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"sort"
+	"time"
+)
+
+// quantityTolerance float quantities within this of zero are treated as zero so a position sold in full closes
+const quantityTolerance = 1e-9
+
+// ComputePositions replays the transactions oldest first and works out what's held of each ticker using
+// the fifo or average cost basis method. A sell for more than is held is an error. Closed positions are
+// returned too so realised P&L and dividends aren't lost
+func ComputePositions(transactions []Transaction, method string) ([]Position, error) {
+	if method != CostBasisFifo && method != CostBasisAverage {
+		return nil, fmt.Errorf("unknown cost basis method %q", method)
+	}
+
+	ordered := make([]Transaction, len(transactions))
+	copy(ordered, transactions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].ExecutedAt.Before(ordered[j].ExecutedAt)
+	})
+
+	positions := make(map[string]*Position)
+	var order []string
+
+	for _, transaction := range ordered {
+		position, ok := positions[transaction.Ticker]
+		if !ok {
+			position = &Position{Ticker: transaction.Ticker, Lots: []Lot{}}
+			positions[transaction.Ticker] = position
+			order = append(order, transaction.Ticker)
+		}
+
+		var err error
+		switch transaction.Type {
+		case TransactionBuy:
+			buy(position, transaction, method)
+		case TransactionSell:
+			err = sell(position, transaction)
+		case TransactionDividend:
+			position.Dividends += transaction.Amount
+		case TransactionSplit:
+			err = split(position, transaction)
+		default:
+			err = fmt.Errorf("unknown transaction type %q", transaction.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s on %s: %w", transaction.Type, transaction.Ticker,
+				transaction.ExecutedAt.Format(time.DateOnly), err)
+		}
+	}
+
+	result := make([]Position, 0, len(order))
+	for _, ticker := range order {
+		position := positions[ticker]
+		summarise(position)
+		result = append(result, *position)
+	}
+
+	return result, nil
+}
+
+// buy adds a lot, fees are part of the cost. With average cost the lots are merged into one
+func buy(position *Position, transaction Transaction, method string) {
+	cost := transaction.Quantity*transaction.Price + transaction.Fees
+	lot := Lot{
+		Quantity:     transaction.Quantity,
+		CostPerShare: cost / transaction.Quantity,
+		AcquiredAt:   transaction.ExecutedAt,
+	}
+
+	if method == CostBasisAverage && len(position.Lots) == 1 {
+		held := position.Lots[0]
+		quantity := held.Quantity + lot.Quantity
+		position.Lots[0] = Lot{
+			Quantity:     quantity,
+			CostPerShare: (held.Quantity*held.CostPerShare + cost) / quantity,
+			AcquiredAt:   held.AcquiredAt,
+		}
+		return
+	}
+
+	position.Lots = append(position.Lots, lot)
+}
+
+// sell takes shares from the oldest lots first, with average cost there's only one lot. Fees reduce the proceeds
+func sell(position *Position, transaction Transaction) error {
+	held := 0.0
+	for _, lot := range position.Lots {
+		held += lot.Quantity
+	}
+	if transaction.Quantity > held+quantityTolerance {
+		return fmt.Errorf("selling %g shares but only %g are held", transaction.Quantity, held)
+	}
+
+	remaining := transaction.Quantity
+	costOfSold := 0.0
+
+	for remaining > quantityTolerance && len(position.Lots) > 0 {
+		lot := &position.Lots[0]
+		taken := min(lot.Quantity, remaining)
+
+		costOfSold += taken * lot.CostPerShare
+		lot.Quantity -= taken
+		remaining -= taken
+
+		if lot.Quantity <= quantityTolerance {
+			position.Lots = position.Lots[1:]
+		}
+	}
+
+	proceeds := transaction.Quantity*transaction.Price - transaction.Fees
+	position.RealisedPnl += proceeds - costOfSold
+	return nil
+}
+
+// split multiplies every lot's shares by the ratio and divides their cost per share, the cost basis is unchanged
+func split(position *Position, transaction Transaction) error {
+	if transaction.Ratio <= 0 {
+		return fmt.Errorf("split ratio must be positive, got %g", transaction.Ratio)
+	}
+
+	for i := range position.Lots {
+		position.Lots[i].Quantity *= transaction.Ratio
+		position.Lots[i].CostPerShare /= transaction.Ratio
+	}
+	return nil
+}
+
+func summarise(position *Position) {
+	position.Quantity, position.CostBasis = 0, 0
+	for _, lot := range position.Lots {
+		position.Quantity += lot.Quantity
+		position.CostBasis += lot.Quantity * lot.CostPerShare
+	}
+
+	if position.Quantity > quantityTolerance {
+		position.AverageCost = position.CostBasis / position.Quantity
+	} else {
+		position.Quantity, position.CostBasis, position.AverageCost = 0, 0, 0
+	}
+}
+
+// ValuePosition values the position at close, the day change is the move from the session's open to its close
+func ValuePosition(position Position, open float64, close float64) PositionValuation {
+	valuation := PositionValuation{
+		Position:      position,
+		Price:         close,
+		MarketValue:   position.Quantity * close,
+		UnrealisedPnl: position.Quantity*close - position.CostBasis,
+		DayChange:     position.Quantity * (close - open),
+	}
+
+	valuation.UnrealisedPnlPercent = percentage(valuation.UnrealisedPnl, position.CostBasis)
+	valuation.DayChangePercent = percentage(close-open, open)
+	return valuation
+}
+
+// TotalValuation sums the positions, positions that couldn't be priced only count toward realised P&L and dividends
+func TotalValuation(positions []PositionValuation) ValuationTotals {
+	var totals ValuationTotals
+	openingValue := 0.0
+
+	for _, position := range positions {
+		totals.RealisedPnl += position.RealisedPnl
+		totals.Dividends += position.Dividends
+		if position.PriceError != "" {
+			continue
+		}
+
+		totals.MarketValue += position.MarketValue
+		totals.CostBasis += position.CostBasis
+		totals.UnrealisedPnl += position.UnrealisedPnl
+		totals.DayChange += position.DayChange
+		openingValue += position.MarketValue - position.DayChange
+	}
+
+	totals.UnrealisedPnlPercent = percentage(totals.UnrealisedPnl, totals.CostBasis)
+	totals.DayChangePercent = percentage(totals.DayChange, openingValue)
+	return totals
+}
+
+func percentage(change float64, base float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return change / base * 100
+}
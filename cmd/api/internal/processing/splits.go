@@ -0,0 +1,63 @@
+package processing
+
+import (
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"time"
+)
+
+// SplitDue whether a split of a ticker first held at since should be applied on today. It's due from the
+// morning it executes, splits on or before since happened before anything was stored against the ticker
+func SplitDue(split Split, since time.Time, today time.Time) bool {
+	return !split.ExecutionDate.After(today) && since.Before(split.ExecutionDate)
+}
+
+// HeldBeforeSplit whether the transaction means the portfolio held the ticker when the split executed.
+// Transactions on the execution date are already at the new share count
+func HeldBeforeSplit(transaction Transaction, split Split) bool {
+	return transaction.Ticker == split.Ticker && transaction.ExecutedAt.Before(split.ExecutionDate)
+}
+
+// SplitRecorded whether the transactions already have a split of the ticker on the split's execution date,
+// added by hand or by an earlier run
+func SplitRecorded(transactions []Transaction, split Split) bool {
+	executionDate := split.ExecutionDate.Format(time.DateOnly)
+	for _, transaction := range transactions {
+		if transaction.Type == TransactionSplit && transaction.Ticker == split.Ticker &&
+			transaction.ExecutedAt.UTC().Format(time.DateOnly) == executionDate {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitsToRecord the splits a portfolio needs a split transaction for: those that executed after one of
+// held and aren't already in recorded, the portfolio's transactions of the ticker
+func SplitsToRecord(held []Transaction, recorded []Transaction, splits []Split) []Split {
+	var missing []Split
+	for _, split := range splits {
+		if SplitRecorded(recorded, split) {
+			continue
+		}
+		for _, transaction := range held {
+			if HeldBeforeSplit(transaction, split) {
+				missing = append(missing, split)
+				break
+			}
+		}
+	}
+	return missing
+}
+
+// SplitTransaction the transaction recording the split in a portfolio, it executes at the start of the
+// execution date so the day's trades are after it
+func SplitTransaction(id string, portfolioId string, split Split, createdAt time.Time) Transaction {
+	return Transaction{
+		Id:          id,
+		PortfolioId: portfolioId,
+		Ticker:      split.Ticker,
+		Type:        TransactionSplit,
+		Ratio:       split.Ratio,
+		ExecutedAt:  split.ExecutionDate,
+		CreatedAt:   createdAt,
+	}
+}
@@ -0,0 +1,113 @@
+package processing
+
+import (
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"testing"
+	"time"
+)
+
+func june(day int, hour int) time.Time {
+	return time.Date(2024, time.June, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestSplitDue(t *testing.T) {
+	split := Split{Ticker: "NVDA", ExecutionDate: june(10, 0), Ratio: 10}
+	later := Split{Ticker: "NVDA", ExecutionDate: june(11, 0), Ratio: 10}
+
+	tests := []struct {
+		name  string
+		split Split
+		since time.Time
+		want  bool
+	}{
+		{"executes today", split, june(3, 15), true},
+		{"executes tomorrow", later, june(3, 15), false},
+		{"held since the day before", split, june(9, 20), true},
+		{"first held on the execution date", split, june(10, 14), false},
+		{"first held at the start of the execution date", split, june(10, 0), false},
+		{"executed before it was held", split, june(12, 9), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := SplitDue(test.split, test.since, june(10, 0)); got != test.want {
+				t.Fatalf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitsToRecord(t *testing.T) {
+	split := Split{Ticker: "NVDA", ExecutionDate: june(10, 0), Ratio: 10}
+	earlier := Split{Ticker: "NVDA", ExecutionDate: june(1, 0), Ratio: 2}
+
+	buy := func(ticker string, at time.Time) Transaction {
+		return Transaction{Ticker: ticker, Type: TransactionBuy, Quantity: 1, Price: 100, ExecutedAt: at}
+	}
+	recorded := func(ticker string, at time.Time) Transaction {
+		return Transaction{Ticker: ticker, Type: TransactionSplit, Ratio: 10, ExecutedAt: at}
+	}
+	newYork := time.FixedZone("EDT", -4*60*60)
+
+	tests := []struct {
+		name     string
+		held     []Transaction
+		recorded []Transaction
+		splits   []Split
+		want     []Split
+	}{
+		{"bought the day before", []Transaction{buy("NVDA", june(9, 15))}, nil, []Split{split}, []Split{split}},
+		{"bought on the execution date", []Transaction{buy("NVDA", june(10, 15))}, nil, []Split{split}, nil},
+		{"bought after", []Transaction{buy("NVDA", june(11, 15))}, nil, []Split{split}, nil},
+		{"held another ticker", []Transaction{buy("AAPL", june(9, 15))}, nil, []Split{split}, nil},
+		{"any transaction before counts", []Transaction{{Ticker: "NVDA", Type: TransactionDividend,
+			ExecutedAt: june(9, 15)}}, nil, []Split{split}, []Split{split}},
+		{"already recorded that day", []Transaction{buy("NVDA", june(9, 15))},
+			[]Transaction{recorded("NVDA", june(10, 0))}, []Split{split}, nil},
+		{"recorded by hand later that day", []Transaction{buy("NVDA", june(9, 15))},
+			[]Transaction{recorded("NVDA", june(10, 16))}, []Split{split}, nil},
+		{"recorded that day outside utc", []Transaction{buy("NVDA", june(9, 15))},
+			[]Transaction{recorded("NVDA", time.Date(2024, time.June, 9, 22, 0, 0, 0, newYork))}, []Split{split}, nil},
+		{"recorded on another day", []Transaction{buy("NVDA", june(9, 15))},
+			[]Transaction{recorded("NVDA", june(11, 0))}, []Split{split}, []Split{split}},
+		{"recorded for another ticker", []Transaction{buy("NVDA", june(9, 15))},
+			[]Transaction{recorded("AAPL", june(10, 0))}, []Split{split}, []Split{split}},
+		{"a buy isn't a recorded split", []Transaction{buy("NVDA", june(9, 15))},
+			[]Transaction{buy("NVDA", june(10, 0))}, []Split{split}, []Split{split}},
+		{"backdated before both splits", []Transaction{buy("NVDA", june(1, 0).AddDate(0, 0, -1))},
+			[]Transaction{buy("NVDA", june(12, 15))}, []Split{earlier, split}, []Split{earlier, split}},
+		{"backdated between splits", []Transaction{buy("NVDA", june(5, 15))},
+			[]Transaction{buy("NVDA", june(12, 15))}, []Split{earlier, split}, []Split{split}},
+		{"backdated into a portfolio that has the split", []Transaction{buy("NVDA", june(5, 15))},
+			[]Transaction{buy("NVDA", june(3, 15)), recorded("NVDA", june(10, 0))}, []Split{earlier, split}, nil},
+		{"nothing held", nil, nil, []Split{split}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SplitsToRecord(test.held, test.recorded, test.splits)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitTransaction(t *testing.T) {
+	split := Split{Ticker: "NVDA", ExecutionDate: june(10, 0), SplitFrom: 1, SplitTo: 10, Ratio: 10}
+	got := SplitTransaction("id", "portfolio", split, june(10, 13))
+
+	want := Transaction{Id: "id", PortfolioId: "portfolio", Ticker: "NVDA", Type: TransactionSplit, Ratio: 10,
+		ExecutedAt: june(10, 0), CreatedAt: june(10, 13)}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if !SplitRecorded([]Transaction{got}, split) {
+		t.Fatal("split transaction doesn't count as the split being recorded")
+	}
+}
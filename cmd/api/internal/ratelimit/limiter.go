@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/cache"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	GroupStocks    = "stocks"
+	GroupOpenClose = "openclose"
+	GroupAdmin     = "admin"
+	GroupClient    = "client" //every request per client ip, before it's authenticated
+)
+
+// defaultRules used for any group the config doesn't set, openclose fans out to polygon so it gets far less
+var defaultRules = map[string]RateLimitRule{
+	GroupStocks:    {Requests: 120, WindowSeconds: 60},
+	GroupOpenClose: {Requests: 10, WindowSeconds: 60},
+	GroupAdmin:     {Requests: 30, WindowSeconds: 60},
+	GroupClient:    {Requests: 600, WindowSeconds: 60},
+}
+
+// Limiter fixed window rate limiting keyed by the authenticated user, api key or client ip. Counters are
+// held in this process's memory, so with several instances behind a load balancer each enforces the limits
+// on its own and a caller can make up to the limit against every instance
+type Limiter struct {
+	store cache.Cache
+	rules atomic.Pointer[map[string]RateLimitRule]
+}
+
+func NewLimiter() *Limiter {
+	limiter := &Limiter{store: cache.NewMemory()}
+	limiter.rules.Store(limiterRules(Current()))
+
+	//only the rules are swapped so counters carry on across a reload
+	Subscribe(func(previous AppConfig, next AppConfig) {
+		if !reflect.DeepEqual(previous.RateLimit, next.RateLimit) {
+			limiter.rules.Store(limiterRules(next))
+			log.Info("rate limits changed")
+		}
+	})
+
+	return limiter
+}
+
+func limiterRules(cfg AppConfig) *map[string]RateLimitRule {
+	rules := make(map[string]RateLimitRule, len(defaultRules))
+	for group, rule := range defaultRules {
+		rules[group] = rule
+	}
+	for group, rule := range cfg.RateLimit.Rules {
+		rules[group] = rule
+	}
+
+	return &rules
+}
+
+// Limit middleware enforcing the group's rule per caller, groups without a rule aren't limited
+func (l *Limiter) Limit(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.enforce(c, group, callerKey(c))
+	}
+}
+
+// LimitClient middleware enforcing the client rule per ip. It runs ahead of authentication so anonymous
+// and credential stuffing traffic is limited too, the ip is only taken from X-Forwarded-For when the
+// request came through one of apiSettings.trustedProxies
+func (l *Limiter) LimitClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.enforce(c, GroupClient, "ip:"+c.ClientIP())
+	}
+}
+
+func (l *Limiter) enforce(c *gin.Context, group string, caller string) {
+	rule, ok := (*l.rules.Load())[group]
+	if !ok || rule.Requests <= 0 || rule.WindowSeconds <= 0 {
+		c.Next()
+		return
+	}
+	window := time.Duration(rule.WindowSeconds) * time.Second
+
+	key := fmt.Sprintf("ratelimit:%s:%s", group, caller)
+	count, resetAt := l.store.Increment(key, window)
+
+	remaining := max(int64(rule.Requests)-count, 0)
+	resetIn := int64(math.Ceil(time.Until(resetAt).Seconds()))
+
+	c.Header("RateLimit-Limit", strconv.Itoa(rule.Requests))
+	c.Header("RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	c.Header("RateLimit-Reset", strconv.FormatInt(resetIn, 10))
+
+	if count > int64(rule.Requests) {
+		c.Header("Retry-After", strconv.FormatInt(resetIn, 10))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":       "rate limit exceeded",
+			"retry_after": resetIn,
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// callerKey prefer the most specific identity we have for the caller, only public routes fall back to the ip
+func callerKey(c *gin.Context) string {
+	if p, ok := auth.FromContext(c.Request.Context()); ok {
+		if p.UserId != "" {
+			return "user:" + p.UserId
+		}
+		if p.KeyId != "" {
+			return "key:" + p.KeyId
+		}
+	}
+
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSettings = `{
+	"connectionStrings": {"stocksDb": "test"},
+	"apiSettings": {"key": "test"},
+	"rateLimit": {"rules": {"stocks": {"requests": %d, "windowSeconds": 60}}}
+}`
+
+func writeSettings(t *testing.T, dir string, requests int) {
+	t.Helper()
+
+	settings := []byte(fmt.Sprintf(testSettings, requests))
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), settings, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadKeepsCounters(t *testing.T) {
+	dir := t.TempDir()
+	writeSettings(t, dir, 2)
+	if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	limiter := NewLimiter()
+	router := gin.New()
+	router.GET("/stocks", limiter.Limit(GroupStocks), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stocks", nil))
+		return recorder
+	}
+
+	for i := 1; i <= 2; i++ {
+		if response := request(); response.Code != http.StatusOK {
+			t.Fatalf("request %d got %d, want 200", i, response.Code)
+		}
+	}
+	if response := request(); response.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit got %d, want 429", response.Code)
+	}
+
+	//raising the limit to 4 leaves the caller one more request in the window rather than starting afresh
+	writeSettings(t, dir, 4)
+	if err := configuration.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	response := request()
+	if response.Code != http.StatusOK || response.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("after the reload got %d with %s remaining, want 200 with 0 remaining", response.Code,
+			response.Header().Get("RateLimit-Remaining"))
+	}
+	if response := request(); response.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the reloaded limit got %d, want 429", response.Code)
+	}
+}
@@ -0,0 +1,392 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/labstack/gommon/log"
+	polygonws "github.com/polygon-io/client-go/websocket"
+	wsModels "github.com/polygon-io/client-go/websocket/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// connections that stay up this long reset the reconnect backoff
+const stableAfter = time.Minute
+
+var (
+	ErrUnknownChannel = errors.New("unknown channel")
+	ErrListenerClosed = errors.New("listener is closed")
+)
+
+// Channels every channel a listener can subscribe to
+var Channels = []string{ChannelTrades, ChannelQuotes, ChannelAggregates}
+
+// topics the polygon websocket topic behind each channel
+var topics = map[string]polygonws.Topic{
+	ChannelTrades:     polygonws.StocksTrades,
+	ChannelQuotes:     polygonws.StocksQuotes,
+	ChannelAggregates: polygonws.StocksMinAggs,
+}
+
+var clientLog = log.New("polygon-ws")
+
+// subscription a ticker on a channel
+type subscription struct {
+	channel string
+	ticker  string
+}
+
+// Listener a local consumer of the hub. Messages is closed when the listener is closed or falls more than
+// realtime.clientBuffer messages behind
+type Listener struct {
+	Messages      <-chan MarketMessage
+	messages      chan MarketMessage
+	subscriptions map[subscription]bool
+	dropped       bool
+}
+
+// Dropped reports whether Messages was closed because the listener was too slow
+func (l *Listener) Dropped() bool {
+	return l.dropped
+}
+
+// Hub keeps one connection to polygon's websocket feed and fans its messages out to listeners. A ticker is
+// subscribed upstream only while at least one listener wants it, when the connection drops it's
+// re-established with backoff and everything wanted is subscribed again
+type Hub struct {
+	mu          sync.Mutex
+	listeners   map[*Listener]struct{}
+	subscribers map[subscription]map[*Listener]struct{}
+
+	changed chan struct{} //subscribers changed since the upstream subscriptions were last synced
+	restart chan struct{} //the connection settings changed
+}
+
+func NewHub() *Hub {
+	hub := &Hub{
+		listeners:   make(map[*Listener]struct{}),
+		subscribers: make(map[subscription]map[*Listener]struct{}),
+		changed:     make(chan struct{}, 1),
+		restart:     make(chan struct{}, 1),
+	}
+
+	//reconnect when the key is rotated or the feed moved
+	configuration.Subscribe(func(previous configuration.AppConfig, next configuration.AppConfig) {
+		if previous.ApiSettings.Key != next.ApiSettings.Key || previous.Realtime.Url != next.Realtime.Url {
+			signal(hub.restart)
+		}
+	})
+
+	return hub
+}
+
+// Start connects and stays connected until ctx is done, failed connections are retried after
+// realtime.initialBackoff doubling up to realtime.maxBackoff
+func (h *Hub) Start(ctx context.Context) {
+	go func() {
+		failures := 0
+
+		for {
+			connectedAt := time.Now()
+			err := h.session(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				failures = 0
+				continue
+			}
+
+			if time.Since(connectedAt) >= stableAfter {
+				failures = 0
+			}
+			failures++
+
+			settings := configuration.Current().Realtime
+			wait := schedule.Backoff(failures, time.Duration(settings.InitialBackoff), time.Duration(settings.MaxBackoff))
+			log.Errorf("realtime feed disconnected, reconnecting in %s: %s", wait, err)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-h.restart:
+				timer.Stop()
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Listen registers a listener with nothing subscribed
+func (h *Hub) Listen() *Listener {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	messages := make(chan MarketMessage, configuration.Current().Realtime.ClientBuffer)
+	listener := &Listener{
+		Messages:      messages,
+		messages:      messages,
+		subscriptions: make(map[subscription]bool),
+	}
+	h.listeners[listener] = struct{}{}
+
+	return listener
+}
+
+// Subscribe starts sending the listener the tickers' messages on channel
+func (h *Hub) Subscribe(listener *Listener, channel string, tickers ...string) error {
+	if _, ok := topics[channel]; !ok {
+		return ErrUnknownChannel
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.listeners[listener]; !ok {
+		return ErrListenerClosed
+	}
+
+	for _, ticker := range normalise(tickers) {
+		key := subscription{channel: channel, ticker: ticker}
+		if listener.subscriptions[key] {
+			continue
+		}
+
+		listener.subscriptions[key] = true
+		if h.subscribers[key] == nil {
+			h.subscribers[key] = make(map[*Listener]struct{})
+		}
+		h.subscribers[key][listener] = struct{}{}
+	}
+
+	signal(h.changed)
+	return nil
+}
+
+// Unsubscribe stops sending the listener the tickers' messages on channel
+func (h *Hub) Unsubscribe(listener *Listener, channel string, tickers ...string) error {
+	if _, ok := topics[channel]; !ok {
+		return ErrUnknownChannel
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.listeners[listener]; !ok {
+		return ErrListenerClosed
+	}
+
+	for _, ticker := range normalise(tickers) {
+		h.unsubscribe(listener, subscription{channel: channel, ticker: ticker})
+	}
+
+	signal(h.changed)
+	return nil
+}
+
+// Close stops the listener's messages and releases its subscriptions, it's safe to call after the listener
+// was dropped
+func (h *Hub) Close(listener *Listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(listener)
+}
+
+// session connects once and passes messages on until the connection fails, ctx is done or the settings
+// change. A nil error means the hub should reconnect straight away
+func (h *Hub) session(ctx context.Context) error {
+	cfg := configuration.Current()
+	noRetries := uint64(0)
+
+	client, err := polygonws.New(polygonws.Config{
+		APIKey:     cfg.ApiSettings.Key,
+		Feed:       polygonws.Feed(cfg.Realtime.Url),
+		Market:     polygonws.Stocks,
+		MaxRetries: &noRetries, //the client tries one reconnect, after that the hub backs off and resubscribes
+		Log:        clientLog,
+	})
+	if err != nil {
+		return err
+	}
+
+	//subscriptions made before connecting are sent once the client has authenticated
+	subscribed := make(map[subscription]bool)
+	h.sync(client, subscribed)
+
+	if err := client.Connect(); err != nil {
+		return err
+	}
+	defer client.Close()
+	log.Infof("connected to realtime feed %s", cfg.Realtime.Url)
+
+	output := client.Output()
+	for {
+		select {
+		case message, ok := <-output:
+			if !ok {
+				//the client closed itself, the reason follows on its error channel
+				output = nil
+				continue
+			}
+			if marketMessage, ok := toMarketMessage(message); ok {
+				h.publish(marketMessage)
+			}
+		case err := <-client.Error():
+			return err
+		case <-h.changed:
+			h.sync(client, subscribed)
+		case <-h.restart:
+			log.Info("realtime feed settings changed, reconnecting")
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// sync subscribes and unsubscribes upstream so the connection carries exactly what listeners want,
+// subscribed is what's been subscribed on this connection so far
+func (h *Hub) sync(client *polygonws.Client, subscribed map[subscription]bool) {
+	add := make(map[string][]string)
+	remove := make(map[string][]string)
+
+	h.mu.Lock()
+	for key := range h.subscribers {
+		if !subscribed[key] {
+			subscribed[key] = true
+			add[key.channel] = append(add[key.channel], key.ticker)
+		}
+	}
+	for key := range subscribed {
+		if _, ok := h.subscribers[key]; !ok {
+			delete(subscribed, key)
+			remove[key.channel] = append(remove[key.channel], key.ticker)
+		}
+	}
+	h.mu.Unlock()
+
+	for channel, tickers := range add {
+		if err := client.Subscribe(topics[channel], tickers...); err != nil {
+			log.Errorf("couldn't subscribe to %s of %s: %s", channel, strings.Join(tickers, ","), err)
+		}
+	}
+	for channel, tickers := range remove {
+		if err := client.Unsubscribe(topics[channel], tickers...); err != nil {
+			log.Errorf("couldn't unsubscribe from %s of %s: %s", channel, strings.Join(tickers, ","), err)
+		}
+	}
+}
+
+// publish sends the message to every listener subscribed to it. Listeners whose buffer is full are
+// dropped rather than holding up everyone else
+func (h *Hub) publish(message MarketMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for listener := range h.subscribers[subscription{channel: message.Channel, ticker: message.Ticker}] {
+		select {
+		case listener.messages <- message:
+		default:
+			log.Warnf("dropping realtime listener of %d subscriptions, %d messages behind",
+				len(listener.subscriptions), len(listener.messages))
+			listener.dropped = true
+			h.remove(listener)
+		}
+	}
+}
+
+// remove unregisters the listener and closes its messages, the caller holds mu
+func (h *Hub) remove(listener *Listener) {
+	if _, ok := h.listeners[listener]; !ok {
+		return
+	}
+
+	delete(h.listeners, listener)
+	close(listener.messages)
+
+	for key := range listener.subscriptions {
+		h.unsubscribe(listener, key)
+	}
+	signal(h.changed)
+}
+
+// unsubscribe drops one of the listener's subscriptions, the caller holds mu
+func (h *Hub) unsubscribe(listener *Listener, key subscription) {
+	delete(listener.subscriptions, key)
+	delete(h.subscribers[key], listener)
+	if len(h.subscribers[key]) == 0 {
+		delete(h.subscribers, key)
+	}
+}
+
+// toMarketMessage converts a message decoded by the client, anything that isn't market data is skipped
+func toMarketMessage(message any) (MarketMessage, bool) {
+	switch m := message.(type) {
+	case wsModels.EquityTrade:
+		return MarketMessage{
+			Channel: ChannelTrades,
+			Ticker:  m.Symbol,
+			Data: Trade{
+				Price:     m.Price,
+				Size:      m.Size,
+				Exchange:  m.Exchange,
+				Timestamp: time.UnixMilli(m.Timestamp).UTC(),
+			},
+		}, true
+	case wsModels.EquityQuote:
+		return MarketMessage{
+			Channel: ChannelQuotes,
+			Ticker:  m.Symbol,
+			Data: Quote{
+				BidPrice:  m.BidPrice,
+				BidSize:   m.BidSize,
+				AskPrice:  m.AskPrice,
+				AskSize:   m.AskSize,
+				Timestamp: time.UnixMilli(m.Timestamp).UTC(),
+			},
+		}, true
+	case wsModels.EquityAgg:
+		return MarketMessage{
+			Channel: ChannelAggregates,
+			Ticker:  m.Symbol,
+			Data: MinuteAggregate{
+				Open:   m.Open,
+				High:   m.High,
+				Low:    m.Low,
+				Close:  m.Close,
+				Volume: m.Volume,
+				Vwap:   m.VWAP,
+				Start:  time.UnixMilli(m.StartTimestamp).UTC(),
+				End:    time.UnixMilli(m.EndTimestamp).UTC(),
+			},
+		}, true
+	}
+
+	return MarketMessage{}, false
+}
+
+// normalise upper cases the tickers and skips blanks
+func normalise(tickers []string) []string {
+	normalised := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		if ticker = strings.ToUpper(strings.TrimSpace(ticker)); ticker != "" {
+			normalised = append(normalised, ticker)
+		}
+	}
+	return normalised
+}
+
+// signal wakes whoever waits on ch without blocking, a wake up already pending covers this one
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
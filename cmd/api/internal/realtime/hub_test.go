@@ -0,0 +1,328 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testKey = "test-key"
+
+const testSettings = `{
+	"connectionStrings": {"stocksDb": "test"},
+	"apiSettings": {"key": %q},
+	"realtime": {"url": %q, "initialBackoff": "50ms", "maxBackoff": "200ms", "clientBuffer": 16}
+}`
+
+// useSettings points realtime.url at the fake feed, reconnects back off from 50ms up to 200ms
+func useSettings(t *testing.T, feedUrl string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	settings := []byte(fmt.Sprintf(testSettings, testKey, feedUrl))
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), settings, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// control a message the client sends polygon
+type control struct {
+	Action string `json:"action"`
+	Params string `json:"params"`
+}
+
+type status struct {
+	Event   string `json:"ev"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// fakeFeed speaks enough of polygon's websocket protocol for the hub: it greets, checks the auth message
+// and acknowledges subscriptions. Connections can be refused to make the hub back off
+type fakeFeed struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	refuse   int         //connections to refuse before accepting again
+	attempts []time.Time //when each connection was attempted
+
+	accepted chan *feedConn
+}
+
+// feedConn an authenticated connection, messages has everything the client sent after authenticating
+type feedConn struct {
+	conn     *websocket.Conn
+	writeMu  sync.Mutex
+	messages chan control
+}
+
+func newFakeFeed(t *testing.T) *fakeFeed {
+	feed := &fakeFeed{accepted: make(chan *feedConn, 10)}
+	feed.server = httptest.NewServer(feed)
+	t.Cleanup(feed.server.Close)
+	return feed
+}
+
+func (f *fakeFeed) url() string {
+	return "ws" + strings.TrimPrefix(f.server.URL, "http")
+}
+
+func (f *fakeFeed) refuseNext(connections int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.refuse = connections
+}
+
+func (f *fakeFeed) connectionAttempts() []time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.attempts)
+}
+
+func (f *fakeFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.attempts = append(f.attempts, time.Now())
+	refused := f.refuse > 0
+	if refused {
+		f.refuse--
+	}
+	f.mu.Unlock()
+
+	if refused || r.URL.Path != "/stocks" {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := f.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	fc := &feedConn{conn: conn, messages: make(chan control, 100)}
+	defer close(fc.messages)
+
+	fc.send(status{Event: "status", Status: "connected", Message: "Connected Successfully"})
+
+	var auth control
+	if err := conn.ReadJSON(&auth); err != nil {
+		return
+	}
+	if auth.Action != "auth" || auth.Params != testKey {
+		fc.send(status{Event: "status", Status: "auth_failed", Message: "authentication failed"})
+		return
+	}
+	fc.send(status{Event: "status", Status: "auth_success", Message: "authenticated"})
+	f.accepted <- fc
+
+	for {
+		var message control
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+		fc.messages <- message
+		fc.send(status{Event: "status", Status: "success", Message: message.Action + "d to: " + message.Params})
+	}
+}
+
+// send writes a batch of one message, polygon always sends arrays
+func (fc *feedConn) send(message any) {
+	fc.writeMu.Lock()
+	defer fc.writeMu.Unlock()
+
+	fc.conn.WriteJSON([]any{message})
+}
+
+func (f *fakeFeed) accept(t *testing.T) *feedConn {
+	t.Helper()
+
+	select {
+	case fc := <-f.accepted:
+		return fc
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub didn't connect and authenticate")
+		return nil
+	}
+}
+
+func (fc *feedConn) next(t *testing.T) control {
+	t.Helper()
+
+	select {
+	case message, ok := <-fc.messages:
+		if !ok {
+			t.Fatal("connection closed before the expected message")
+		}
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub didn't send the expected message")
+		return control{}
+	}
+}
+
+func (fc *feedConn) expectNothing(t *testing.T) {
+	t.Helper()
+
+	select {
+	case message := <-fc.messages:
+		t.Fatalf("unexpected %s of %s", message.Action, message.Params)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// subscribed collects the subscribe messages until every topic in want has been seen
+func (fc *feedConn) subscribed(t *testing.T, want ...string) {
+	t.Helper()
+
+	var got []string
+	for len(got) < len(want) {
+		message := fc.next(t)
+		if message.Action != "subscribe" {
+			t.Fatalf("got %s of %s, want subscribes to %v", message.Action, message.Params, want)
+		}
+		got = append(got, strings.Split(message.Params, ",")...)
+	}
+
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("subscribed to %v, want %v", got, want)
+	}
+}
+
+func startHub(t *testing.T, feed *fakeFeed) *Hub {
+	t.Helper()
+
+	useSettings(t, feed.url())
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	hub.Start(ctx)
+
+	return hub
+}
+
+func receive(t *testing.T, listener *Listener) MarketMessage {
+	t.Helper()
+
+	select {
+	case message, ok := <-listener.Messages:
+		if !ok {
+			t.Fatal("listener was closed")
+		}
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener didn't get the message")
+		return MarketMessage{}
+	}
+}
+
+func TestSubscriptionsAreRefCounted(t *testing.T) {
+	feed := newFakeFeed(t)
+	hub := startHub(t, feed)
+	conn := feed.accept(t)
+
+	first, second := hub.Listen(), hub.Listen()
+
+	if err := hub.Subscribe(first, ChannelTrades, "aapl"); err != nil {
+		t.Fatal(err)
+	}
+	conn.subscribed(t, "T.AAPL")
+
+	//already subscribed upstream for the first listener
+	if err := hub.Subscribe(second, ChannelTrades, "AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	conn.expectNothing(t)
+
+	conn.send(map[string]any{"ev": "T", "sym": "AAPL", "p": 190.5, "s": 100, "x": 4, "t": 1700000000000})
+	for _, listener := range []*Listener{first, second} {
+		message := receive(t, listener)
+		trade, ok := message.Data.(Trade)
+		if message.Channel != ChannelTrades || message.Ticker != "AAPL" || !ok || trade.Price != 190.5 {
+			t.Fatalf("got %+v, want the AAPL trade at 190.5", message)
+		}
+	}
+
+	//the second listener still wants it
+	if err := hub.Unsubscribe(first, ChannelTrades, "AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	conn.expectNothing(t)
+
+	hub.Close(second)
+	if message := conn.next(t); message.Action != "unsubscribe" || message.Params != "T.AAPL" {
+		t.Fatalf("got %s of %s, want unsubscribe of T.AAPL once no listener wants it", message.Action, message.Params)
+	}
+}
+
+func TestResubscribesAfterDroppedConnection(t *testing.T) {
+	feed := newFakeFeed(t)
+	hub := startHub(t, feed)
+	conn := feed.accept(t)
+
+	listener := hub.Listen()
+	if err := hub.Subscribe(listener, ChannelTrades, "AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	conn.subscribed(t, "T.AAPL")
+	if err := hub.Subscribe(listener, ChannelQuotes, "MSFT"); err != nil {
+		t.Fatal(err)
+	}
+	conn.subscribed(t, "Q.MSFT")
+
+	//the client's own reconnect is refused so the hub has to reconnect and resubscribe
+	feed.refuseNext(1)
+	conn.conn.Close()
+
+	reconnected := feed.accept(t)
+	reconnected.subscribed(t, "T.AAPL", "Q.MSFT")
+
+	reconnected.send(map[string]any{"ev": "Q", "sym": "MSFT", "bp": 410.1, "ap": 410.2, "t": 1700000000000})
+	if message := receive(t, listener); message.Channel != ChannelQuotes || message.Ticker != "MSFT" {
+		t.Fatalf("got %+v, want the MSFT quote after reconnecting", message)
+	}
+}
+
+func TestReconnectsBackOff(t *testing.T) {
+	feed := newFakeFeed(t)
+	feed.refuseNext(1000)
+	startHub(t, feed)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(feed.connectionAttempts()) < 5 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	attempts := feed.connectionAttempts()
+	if len(attempts) < 5 {
+		t.Fatalf("%d connection attempts, want at least 5", len(attempts))
+	}
+
+	//initialBackoff doubling after each failure, capped at maxBackoff
+	for i, want := range []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond,
+		200 * time.Millisecond} {
+		wait := attempts[i+1].Sub(attempts[i])
+		if wait < want-5*time.Millisecond || wait > want+150*time.Millisecond {
+			t.Fatalf("waited %s before reconnect %d, want %s", wait, i+1, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var (
+	ErrAlertNotFound         = errors.New("alert not found")
+	ErrAlertAlreadyTriggered = errors.New("alert has already triggered for this bar")
+	ErrTooManyAlerts         = errors.New("too many alerts")
+)
+
+const alertColumns = `id, user_id, ticker, alert_condition, threshold, sma_window, cooldown_minutes, active,
+	last_state, last_triggered_at, created_at`
+
+type AlertRepository struct {
+	db *StocksDataBase
+}
+
+func NewAlertRepository(db *StocksDataBase) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+// CreateAlert saves the alert unless the user already has maxPerUser, then it's ErrTooManyAlerts
+func (a *AlertRepository) CreateAlert(alert Alert, maxPerUser int, ctx context.Context) error {
+	query := `INSERT INTO alerts (id, user_id, ticker, alert_condition, threshold, sma_window, cooldown_minutes,
+		active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	return insertWithinLimit(ctx, a.db, "alerts", alert.UserId, maxPerUser, ErrTooManyAlerts, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, alert.Id, alert.UserId, alert.Ticker, alert.Condition, alert.Threshold,
+			alert.Window, alert.CooldownMinutes, alert.Active, alert.CreatedAt)
+		if err != nil {
+			log.Errorf("error executing create alert query: %s", err)
+		}
+		return err
+	})
+}
+
+func (a *AlertRepository) ListAlerts(userId string, ctx context.Context) Response[[]Alert] {
+	query := "SELECT " + alertColumns + " FROM alerts WHERE user_id = ? ORDER BY created_at, id"
+	return a.queryAlerts(ctx, query, userId)
+}
+
+// ListActiveAlerts every active alert, for the evaluator
+func (a *AlertRepository) ListActiveAlerts(ctx context.Context) Response[[]Alert] {
+	query := "SELECT " + alertColumns + " FROM alerts WHERE active ORDER BY ticker"
+	return a.queryAlerts(ctx, query)
+}
+
+func (a *AlertRepository) GetAlert(id string, ctx context.Context) Response[*Alert] {
+	query := "SELECT " + alertColumns + " FROM alerts WHERE id = ?"
+
+	alert, err := scanAlert(a.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAlertNotFound
+		} else {
+			log.Errorf("error executing get alert query: %s", err)
+		}
+
+		return Response[*Alert]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[*Alert]{
+		Data:  alert,
+		Error: nil,
+	}
+}
+
+// DeleteAlert deletes the alert, its trigger history goes with it
+func (a *AlertRepository) DeleteAlert(id string, ctx context.Context) error {
+	result, err := a.db.ExecContext(ctx, "DELETE FROM alerts WHERE id = ?", id)
+	if err != nil {
+		log.Errorf("error executing delete alert query: %s", err)
+		return err
+	}
+
+	return expectOneRow(result, ErrAlertNotFound)
+}
+
+// UpdateAlertState stores which side of its sma the alert's ticker closed on
+func (a *AlertRepository) UpdateAlertState(id string, state string, ctx context.Context) error {
+	if _, err := a.db.ExecContext(ctx, "UPDATE alerts SET last_state = ? WHERE id = ?", state, id); err != nil {
+		log.Errorf("error executing update alert state query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// RecordTrigger saves the trigger and marks the alert as triggered in one transaction. A second trigger
+// for the same bar is rejected with ErrAlertAlreadyTriggered
+func (a *AlertRepository) RecordTrigger(trigger AlertTrigger, state string, ctx context.Context) error {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("error starting transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	insert := `INSERT INTO alert_triggers (id, alert_id, bar_date, price, value, message, triggered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, insert, trigger.Id, trigger.AlertId, trigger.BarDate.Format(time.DateOnly),
+		trigger.Price, trigger.Value, trigger.Message, trigger.TriggeredAt)
+	if isDuplicate(err) {
+		return ErrAlertAlreadyTriggered
+	}
+	if err != nil {
+		log.Errorf("error executing record trigger query: %s", err)
+		return err
+	}
+
+	update := "UPDATE alerts SET last_triggered_at = ?, last_state = ? WHERE id = ?"
+	if _, err := tx.ExecContext(ctx, update, trigger.TriggeredAt, state, trigger.AlertId); err != nil {
+		log.Errorf("error executing update alert query: %s", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListTriggers the alert's trigger history, newest first
+func (a *AlertRepository) ListTriggers(alertId string, ctx context.Context) Response[[]AlertTrigger] {
+	query := `SELECT id, alert_id, bar_date, price, value, message, triggered_at
+		FROM alert_triggers WHERE alert_id = ? ORDER BY triggered_at DESC`
+
+	return a.queryTriggers(ctx, query, alertId)
+}
+
+// ListUserTriggersSince every trigger of the user's alerts after since, oldest first
+func (a *AlertRepository) ListUserTriggersSince(userId string, since time.Time, ctx context.Context) Response[[]AlertTrigger] {
+	query := `SELECT t.id, t.alert_id, t.bar_date, t.price, t.value, t.message, t.triggered_at
+		FROM alert_triggers t JOIN alerts a ON a.id = t.alert_id
+		WHERE a.user_id = ? AND t.triggered_at > ? ORDER BY t.triggered_at`
+
+	return a.queryTriggers(ctx, query, userId, since)
+}
+
+func (a *AlertRepository) queryTriggers(ctx context.Context, query string, args ...any) Response[[]AlertTrigger] {
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]AlertTrigger]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	triggers := []AlertTrigger{}
+	for rows.Next() {
+		var trigger AlertTrigger
+		if err := rows.Scan(&trigger.Id, &trigger.AlertId, &trigger.BarDate, &trigger.Price, &trigger.Value,
+			&trigger.Message, &trigger.TriggeredAt); err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]AlertTrigger]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	return Response[[]AlertTrigger]{
+		Data:  triggers,
+		Error: rows.Err(),
+	}
+}
+
+func (a *AlertRepository) queryAlerts(ctx context.Context, query string, args ...any) Response[[]Alert] {
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]Alert]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	alerts := []Alert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]Alert]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		alerts = append(alerts, *alert)
+	}
+
+	return Response[[]Alert]{
+		Data:  alerts,
+		Error: rows.Err(),
+	}
+}
+
+func scanAlert(row rowScanner) (*Alert, error) {
+	var alert Alert
+	var lastTriggeredAt sql.NullTime
+
+	if err := row.Scan(&alert.Id, &alert.UserId, &alert.Ticker, &alert.Condition, &alert.Threshold, &alert.Window,
+		&alert.CooldownMinutes, &alert.Active, &alert.LastState, &lastTriggeredAt, &alert.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if lastTriggeredAt.Valid {
+		alert.LastTriggeredAt = &lastTriggeredAt.Time
+	}
+
+	return &alert, nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/labstack/gommon/log"
+	"strings"
+	"time"
+)
+
+var ErrApiKeyNotFound = errors.New("api key not found")
+
+type ApiKeyRepository struct {
+	db *StocksDataBase
+}
+
+func NewApiKeyRepository(db *StocksDataBase) *ApiKeyRepository {
+	return &ApiKeyRepository{db: db}
+}
+
+func (a *ApiKeyRepository) CreateApiKey(key ApiKey, ctx context.Context) error {
+	query := `INSERT INTO api_keys (id, name, prefix, key_hash, owner_id, scopes, daily_quota, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := a.db.ExecContext(ctx, query, key.Id, key.Name, key.Prefix, key.Hash, key.OwnerId,
+		strings.Join(key.Scopes, ","), key.DailyQuota, key.CreatedAt)
+	if err != nil {
+		log.Errorf("error executing create api key query: %s", err)
+		return err
+	}
+
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		log.Errorf("error checking rows affected: %s", err)
+		return err
+	}
+	if rowsAff != 1 {
+		return fmt.Errorf("query executed, but change to db does not match. Rows Affected: %d", rowsAff)
+	}
+
+	return nil
+}
+
+func (a *ApiKeyRepository) GetApiKeyByHash(hash string, ctx context.Context) Response[*ApiKey] {
+	query := `SELECT id, name, prefix, key_hash, owner_id, scopes, daily_quota, created_at, revoked_at
+		FROM api_keys WHERE key_hash = ?`
+
+	key, err := scanApiKey(a.db.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrApiKeyNotFound
+		} else {
+			log.Errorf("error executing get api key query: %s", err)
+		}
+
+		return Response[*ApiKey]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[*ApiKey]{
+		Data:  key,
+		Error: nil,
+	}
+}
+
+func (a *ApiKeyRepository) ListApiKeys(ctx context.Context) Response[[]ApiKey] {
+	query := `SELECT id, name, prefix, key_hash, owner_id, scopes, daily_quota, created_at, revoked_at
+		FROM api_keys ORDER BY created_at`
+
+	rows, err := a.db.QueryContext(ctx, query)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]ApiKey]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	keys := []ApiKey{}
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]ApiKey]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		keys = append(keys, *key)
+	}
+
+	return Response[[]ApiKey]{
+		Data:  keys,
+		Error: rows.Err(),
+	}
+}
+
+func (a *ApiKeyRepository) RevokeApiKey(id string, ctx context.Context) error {
+	query := "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"
+
+	result, err := a.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		log.Errorf("error executing revoke api key query: %s", err)
+		return err
+	}
+
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		log.Errorf("error checking rows affected: %s", err)
+		return err
+	}
+	if rowsAff != 1 {
+		return ErrApiKeyNotFound
+	}
+
+	log.Infof("api key %s was revoked", id)
+	return nil
+}
+
+// UseDailyQuota counts a request against the key's quota for the given day in a single statement so
+// concurrent requests can't overshoot it. Data is false, and nothing is counted, once the day's quota is used
+func (a *ApiKeyRepository) UseDailyQuota(id string, day time.Time, quota int, ctx context.Context) Response[bool] {
+	if quota < 1 {
+		return Response[bool]{
+			Data:  false,
+			Error: nil,
+		}
+	}
+
+	//a new row is the day's first request, an existing one is only incremented while under the quota
+	upsert := `INSERT INTO api_key_usage (key_id, day, count) VALUES (?, ?, 1)
+		ON DUPLICATE KEY UPDATE count = IF(count < ?, count + 1, count)`
+
+	result, err := a.db.ExecContext(ctx, upsert, id, day.Format(time.DateOnly), quota)
+	if err != nil {
+		log.Errorf("error executing usage query: %s", err)
+		return Response[bool]{
+			Data:  false,
+			Error: err,
+		}
+	}
+
+	//mysql reports 1 for an insert, 2 for an update and 0 when the row was left as it was
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		log.Errorf("error checking rows affected: %s", err)
+		return Response[bool]{
+			Data:  false,
+			Error: err,
+		}
+	}
+
+	return Response[bool]{
+		Data:  rowsAff > 0,
+		Error: nil,
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanApiKey(row rowScanner) (*ApiKey, error) {
+	var key ApiKey
+	var scopes string
+	var revokedAt sql.NullTime
+
+	if err := row.Scan(&key.Id, &key.Name, &key.Prefix, &key.Hash, &key.OwnerId, &scopes,
+		&key.DailyQuota, &key.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Split(scopes, ",")
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var ErrDigestSubscriptionNotFound = errors.New("digest subscription not found")
+
+const digestColumns = "user_id, email, subscribed, unsubscribe_token, last_sent_at, created_at"
+
+type DigestRepository struct {
+	db *StocksDataBase
+}
+
+func NewDigestRepository(db *StocksDataBase) *DigestRepository {
+	return &DigestRepository{db: db}
+}
+
+// Subscribe opts the user in, or changes their email if they already have a subscription. The
+// unsubscribe token is kept so links in earlier emails still work
+func (d *DigestRepository) Subscribe(subscription DigestSubscription, ctx context.Context) error {
+	query := `INSERT INTO digest_subscriptions (user_id, email, subscribed, unsubscribe_token, created_at)
+		VALUES (?, ?, TRUE, ?, ?) ON DUPLICATE KEY UPDATE email = VALUES(email), subscribed = TRUE`
+
+	_, err := d.db.ExecContext(ctx, query, subscription.UserId, subscription.Email, subscription.UnsubscribeToken,
+		subscription.CreatedAt)
+	if err != nil {
+		log.Errorf("error executing subscribe query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Unsubscribe opts the user out, the subscription is kept so they can opt back in
+func (d *DigestRepository) Unsubscribe(userId string, ctx context.Context) error {
+	if _, err := d.db.ExecContext(ctx, "UPDATE digest_subscriptions SET subscribed = FALSE WHERE user_id = ?", userId); err != nil {
+		log.Errorf("error executing unsubscribe query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// UnsubscribeByToken opts out whoever the token was issued to
+func (d *DigestRepository) UnsubscribeByToken(token string, ctx context.Context) error {
+	query := "UPDATE digest_subscriptions SET subscribed = FALSE WHERE unsubscribe_token = ?"
+	result, err := d.db.ExecContext(ctx, query, token)
+	if err != nil {
+		log.Errorf("error executing unsubscribe query: %s", err)
+		return err
+	}
+
+	//mysql reports 0 rows when they'd already unsubscribed, which is fine
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		var exists bool
+		if err := d.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM digest_subscriptions WHERE unsubscribe_token = ?)",
+			token).Scan(&exists); err != nil {
+			log.Errorf("error checking unsubscribe token: %s", err)
+			return err
+		}
+		if !exists {
+			return ErrDigestSubscriptionNotFound
+		}
+	}
+
+	return nil
+}
+
+func (d *DigestRepository) GetSubscription(userId string, ctx context.Context) Response[*DigestSubscription] {
+	query := "SELECT " + digestColumns + " FROM digest_subscriptions WHERE user_id = ?"
+
+	subscription, err := scanDigestSubscription(d.db.QueryRowContext(ctx, query, userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrDigestSubscriptionNotFound
+		} else {
+			log.Errorf("error executing get subscription query: %s", err)
+		}
+
+		return Response[*DigestSubscription]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[*DigestSubscription]{
+		Data:  subscription,
+		Error: nil,
+	}
+}
+
+// ListDueSubscriptions subscribed users who haven't been sent a digest since before
+func (d *DigestRepository) ListDueSubscriptions(before time.Time, ctx context.Context) Response[[]DigestSubscription] {
+	query := "SELECT " + digestColumns + ` FROM digest_subscriptions
+		WHERE subscribed AND (last_sent_at IS NULL OR last_sent_at < ?) ORDER BY user_id`
+
+	rows, err := d.db.QueryContext(ctx, query, before)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]DigestSubscription]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	subscriptions := []DigestSubscription{}
+	for rows.Next() {
+		subscription, err := scanDigestSubscription(rows)
+		if err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]DigestSubscription]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+
+	return Response[[]DigestSubscription]{
+		Data:  subscriptions,
+		Error: rows.Err(),
+	}
+}
+
+// MarkSent records when the user was last sent a digest
+func (d *DigestRepository) MarkSent(userId string, sentAt time.Time, ctx context.Context) error {
+	if _, err := d.db.ExecContext(ctx, "UPDATE digest_subscriptions SET last_sent_at = ? WHERE user_id = ?", sentAt, userId); err != nil {
+		log.Errorf("error executing mark sent query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func scanDigestSubscription(row rowScanner) (*DigestSubscription, error) {
+	var subscription DigestSubscription
+	var lastSentAt sql.NullTime
+
+	if err := row.Scan(&subscription.UserId, &subscription.Email, &subscription.Subscribed,
+		&subscription.UnsubscribeToken, &lastSentAt, &subscription.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if lastSentAt.Valid {
+		subscription.LastSentAt = &lastSentAt.Time
+	}
+
+	return &subscription, nil
+}
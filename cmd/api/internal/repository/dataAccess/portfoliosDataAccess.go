@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/processing"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+var (
+	ErrPortfolioNotFound   = errors.New("portfolio not found")
+	ErrPortfolioExists     = errors.New("a portfolio with that name already exists")
+	ErrTransactionNotFound = errors.New("transaction not found")
+)
+
+type PortfolioRepository struct {
+	db *StocksDataBase
+}
+
+func NewPortfolioRepository(db *StocksDataBase) *PortfolioRepository {
+	return &PortfolioRepository{db: db}
+}
+
+func (p *PortfolioRepository) CreatePortfolio(portfolio Portfolio, ctx context.Context) error {
+	query := "INSERT INTO portfolios (id, user_id, name, cost_basis, created_at) VALUES (?, ?, ?, ?, ?)"
+
+	_, err := p.db.ExecContext(ctx, query, portfolio.Id, portfolio.UserId, portfolio.Name, portfolio.CostBasis,
+		portfolio.CreatedAt)
+	if isDuplicate(err) {
+		return ErrPortfolioExists
+	}
+	if err != nil {
+		log.Errorf("error executing create portfolio query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func (p *PortfolioRepository) ListPortfolios(userId string, ctx context.Context) Response[[]Portfolio] {
+	query := "SELECT id, user_id, name, cost_basis, created_at FROM portfolios WHERE user_id = ? ORDER BY created_at, id"
+
+	rows, err := p.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]Portfolio]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	portfolios := []Portfolio{}
+	for rows.Next() {
+		portfolio, err := scanPortfolio(rows)
+		if err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]Portfolio]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		portfolios = append(portfolios, *portfolio)
+	}
+
+	return Response[[]Portfolio]{
+		Data:  portfolios,
+		Error: rows.Err(),
+	}
+}
+
+func (p *PortfolioRepository) GetPortfolio(id string, ctx context.Context) Response[*Portfolio] {
+	query := "SELECT id, user_id, name, cost_basis, created_at FROM portfolios WHERE id = ?"
+
+	portfolio, err := scanPortfolio(p.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrPortfolioNotFound
+		} else {
+			log.Errorf("error executing get portfolio query: %s", err)
+		}
+
+		return Response[*Portfolio]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[*Portfolio]{
+		Data:  portfolio,
+		Error: nil,
+	}
+}
+
+// UpdatePortfolio saves the portfolio's name and cost basis method
+func (p *PortfolioRepository) UpdatePortfolio(portfolio Portfolio, ctx context.Context) error {
+	query := "UPDATE portfolios SET name = ?, cost_basis = ? WHERE id = ?"
+
+	result, err := p.db.ExecContext(ctx, query, portfolio.Name, portfolio.CostBasis, portfolio.Id)
+	if isDuplicate(err) {
+		return ErrPortfolioExists
+	}
+	if err != nil {
+		log.Errorf("error executing update portfolio query: %s", err)
+		return err
+	}
+
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		log.Errorf("error checking rows affected: %s", err)
+		return err
+	}
+	if rowsAff == 0 {
+		//mysql reports 0 rows when nothing changed, so check it's there
+		if exists := p.GetPortfolio(portfolio.Id, ctx); exists.Error != nil {
+			return exists.Error
+		}
+	}
+
+	return nil
+}
+
+// DeletePortfolio deletes the portfolio, its transactions go with it
+func (p *PortfolioRepository) DeletePortfolio(id string, ctx context.Context) error {
+	query := "DELETE FROM portfolios WHERE id = ?"
+
+	result, err := p.db.ExecContext(ctx, query, id)
+	if err != nil {
+		log.Errorf("error executing delete portfolio query: %s", err)
+		return err
+	}
+
+	if err := expectOneRow(result, ErrPortfolioNotFound); err != nil {
+		return err
+	}
+
+	log.Infof("portfolio %s was deleted", id)
+	return nil
+}
+
+// AddTransaction records the transaction in one transaction with any splits of its ticker that were applied
+// after it executed, a backdated transaction would otherwise miss splits SplitRepository.ApplySplit has
+// already added to the portfolios holding the ticker then. Splits already recorded for that day are skipped
+func (p *PortfolioRepository) AddTransaction(transaction Transaction, ctx context.Context) error {
+	return inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		err := insertTransaction(ctx, tx, transaction)
+		if isMissingForeignKey(err) {
+			return ErrPortfolioNotFound
+		}
+		if err != nil {
+			log.Errorf("error executing add transaction query: %s", err)
+			return err
+		}
+
+		applied, err := listAppliedSplits(ctx, tx, transaction.Ticker)
+		if err != nil || len(applied) == 0 {
+			return err
+		}
+		recorded, err := lockTransactions(ctx, tx, "portfolio_id = ? AND ticker = ?", transaction.PortfolioId,
+			transaction.Ticker)
+		if err != nil {
+			return err
+		}
+
+		for _, split := range processing.SplitsToRecord([]Transaction{transaction}, recorded, applied) {
+			splitTransaction := processing.SplitTransaction(uuid.NewString(), transaction.PortfolioId, split,
+				transaction.CreatedAt)
+			if err := insertTransaction(ctx, tx, splitTransaction); err != nil {
+				log.Errorf("error executing add applied split query: %s", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListTransactions gets the portfolio's transactions oldest first
+func (p *PortfolioRepository) ListTransactions(portfolioId string, ctx context.Context) Response[[]Transaction] {
+	query := `SELECT id, portfolio_id, ticker, type, quantity, price, fees, amount, ratio, executed_at, created_at
+		FROM portfolio_transactions WHERE portfolio_id = ? ORDER BY executed_at, created_at`
+
+	rows, err := p.db.QueryContext(ctx, query, portfolioId)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]Transaction]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	transactions := []Transaction{}
+	for rows.Next() {
+		var transaction Transaction
+		if err := rows.Scan(&transaction.Id, &transaction.PortfolioId, &transaction.Ticker, &transaction.Type,
+			&transaction.Quantity, &transaction.Price, &transaction.Fees, &transaction.Amount, &transaction.Ratio,
+			&transaction.ExecutedAt, &transaction.CreatedAt); err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]Transaction]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return Response[[]Transaction]{
+		Data:  transactions,
+		Error: rows.Err(),
+	}
+}
+
+func (p *PortfolioRepository) DeleteTransaction(portfolioId string, id string, ctx context.Context) error {
+	query := "DELETE FROM portfolio_transactions WHERE portfolio_id = ? AND id = ?"
+
+	result, err := p.db.ExecContext(ctx, query, portfolioId, id)
+	if err != nil {
+		log.Errorf("error executing delete transaction query: %s", err)
+		return err
+	}
+
+	return expectOneRow(result, ErrTransactionNotFound)
+}
+
+// insertTransaction adds the transaction to its portfolio
+func insertTransaction(ctx context.Context, tx *sql.Tx, transaction Transaction) error {
+	query := `INSERT INTO portfolio_transactions
+		(id, portfolio_id, ticker, type, quantity, price, fees, amount, ratio, executed_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := tx.ExecContext(ctx, query, transaction.Id, transaction.PortfolioId, transaction.Ticker,
+		transaction.Type, transaction.Quantity, transaction.Price, transaction.Fees, transaction.Amount,
+		transaction.Ratio, transaction.ExecutedAt, transaction.CreatedAt)
+	return err
+}
+
+// lockTransactions the transactions matching where, locked until tx ends
+func lockTransactions(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]Transaction, error) {
+	query := `SELECT id, portfolio_id, ticker, type, quantity, price, fees, amount, ratio, executed_at, created_at
+		FROM portfolio_transactions WHERE ` + where + ` ORDER BY executed_at, created_at FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("error executing lock transactions query: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var transaction Transaction
+		if err := rows.Scan(&transaction.Id, &transaction.PortfolioId, &transaction.Ticker, &transaction.Type,
+			&transaction.Quantity, &transaction.Price, &transaction.Fees, &transaction.Amount, &transaction.Ratio,
+			&transaction.ExecutedAt, &transaction.CreatedAt); err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
+
+func scanPortfolio(row rowScanner) (*Portfolio, error) {
+	var portfolio Portfolio
+	if err := row.Scan(&portfolio.Id, &portfolio.UserId, &portfolio.Name, &portfolio.CostBasis,
+		&portfolio.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &portfolio, nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/processing"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+var ErrSplitAlreadyApplied = errors.New("split has already been applied")
+
+// HeldTicker a ticker something is stored against and the date of the earliest of it, splits before then
+// don't affect anything
+type HeldTicker struct {
+	Ticker string
+	Since  time.Time
+}
+
+type SplitRepository struct {
+	db *StocksDataBase
+}
+
+func NewSplitRepository(db *StocksDataBase) *SplitRepository {
+	return &SplitRepository{db: db}
+}
+
+// ListHeldTickers every ticker with portfolio transactions or alerts against it
+func (s *SplitRepository) ListHeldTickers(ctx context.Context) Response[[]HeldTicker] {
+	query := `SELECT ticker, MIN(since) FROM (
+			SELECT ticker, MIN(executed_at) AS since FROM portfolio_transactions GROUP BY ticker
+			UNION ALL
+			SELECT ticker, MIN(created_at) AS since FROM alerts GROUP BY ticker
+		) held GROUP BY ticker ORDER BY ticker`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]HeldTicker]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	held := []HeldTicker{}
+	for rows.Next() {
+		var ticker HeldTicker
+		if err := rows.Scan(&ticker.Ticker, &ticker.Since); err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]HeldTicker]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		held = append(held, ticker)
+	}
+
+	return Response[[]HeldTicker]{
+		Data:  held,
+		Error: rows.Err(),
+	}
+}
+
+// ApplySplit adjusts what's stored from before the split's execution date in one transaction, a split can
+// only be applied once and a second attempt is rejected with ErrSplitAlreadyApplied.
+// Portfolios holding the ticker before the split get a split transaction, unless one was already recorded
+// for that day (see processing.SplitsToRecord), so cost basis is worked out on the new share count while
+// the trades keep the prices they were made at. Price alert thresholds and past triggers are compared with
+// adjusted bars so they're divided by the ratio, percent move values are unchanged by a split
+func (s *SplitRepository) ApplySplit(split Split, ctx context.Context) (SplitAdjustment, error) {
+	adjustment := SplitAdjustment{Split: split}
+	executionDate := split.ExecutionDate.Format(time.DateOnly)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("error starting transaction: %s", err)
+		return adjustment, err
+	}
+	defer tx.Rollback()
+
+	record := "INSERT INTO applied_splits (ticker, execution_date, split_from, split_to) VALUES (?, ?, ?, ?)"
+	_, err = tx.ExecContext(ctx, record, split.Ticker, executionDate, split.SplitFrom, split.SplitTo)
+	if isDuplicate(err) {
+		return adjustment, ErrSplitAlreadyApplied
+	}
+	if err != nil {
+		log.Errorf("error executing record split query: %s", err)
+		return adjustment, err
+	}
+
+	//locked so a transaction added meanwhile waits and then sees the split as applied
+	held, err := lockTransactions(ctx, tx, "ticker = ?", split.Ticker)
+	if err != nil {
+		return adjustment, err
+	}
+	byPortfolio := make(map[string][]Transaction)
+	for _, transaction := range held {
+		byPortfolio[transaction.PortfolioId] = append(byPortfolio[transaction.PortfolioId], transaction)
+	}
+
+	now := time.Now().UTC()
+	for portfolioId, transactions := range byPortfolio {
+		if len(processing.SplitsToRecord(transactions, transactions, []Split{split})) == 0 {
+			continue
+		}
+		splitTransaction := processing.SplitTransaction(uuid.NewString(), portfolioId, split, now)
+		if err := insertTransaction(ctx, tx, splitTransaction); err != nil {
+			log.Errorf("error executing split transaction query: %s", err)
+			return adjustment, err
+		}
+		adjustment.Portfolios++
+	}
+
+	thresholds := `UPDATE alerts SET threshold = threshold / ?
+		WHERE ticker = ? AND alert_condition IN (?, ?) AND created_at < ?`
+	result, err := tx.ExecContext(ctx, thresholds, split.Ratio, split.Ticker, AlertPriceAbove, AlertPriceBelow,
+		executionDate)
+	if err != nil {
+		log.Errorf("error executing adjust thresholds query: %s", err)
+		return adjustment, err
+	}
+	if adjustment.Alerts, err = result.RowsAffected(); err != nil {
+		return adjustment, err
+	}
+
+	triggers := `UPDATE alert_triggers triggers JOIN alerts ON alerts.id = triggers.alert_id
+		SET triggers.price = triggers.price / ?,
+			triggers.value = CASE WHEN alerts.alert_condition = ? THEN triggers.value ELSE triggers.value / ? END
+		WHERE alerts.ticker = ? AND triggers.bar_date < ?`
+	result, err = tx.ExecContext(ctx, triggers, split.Ratio, AlertPercentMove, split.Ratio, split.Ticker,
+		executionDate)
+	if err != nil {
+		log.Errorf("error executing adjust triggers query: %s", err)
+		return adjustment, err
+	}
+	if adjustment.AlertTriggers, err = result.RowsAffected(); err != nil {
+		return adjustment, err
+	}
+
+	counts := `UPDATE applied_splits SET portfolios = ?, alerts = ?, alert_triggers = ?
+		WHERE ticker = ? AND execution_date = ?`
+	if _, err := tx.ExecContext(ctx, counts, adjustment.Portfolios, adjustment.Alerts, adjustment.AlertTriggers,
+		split.Ticker, executionDate); err != nil {
+		log.Errorf("error executing update applied split query: %s", err)
+		return adjustment, err
+	}
+
+	return adjustment, tx.Commit()
+}
+
+// listAppliedSplits the splits of the ticker that have been applied, read with a shared lock so a split
+// being applied meanwhile is waited for
+func listAppliedSplits(ctx context.Context, tx *sql.Tx, ticker string) ([]Split, error) {
+	query := `SELECT ticker, execution_date, split_from, split_to FROM applied_splits WHERE ticker = ?
+		LOCK IN SHARE MODE`
+
+	rows, err := tx.QueryContext(ctx, query, ticker)
+	if err != nil {
+		log.Errorf("error executing list applied splits query: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var splits []Split
+	for rows.Next() {
+		var split Split
+		if err := rows.Scan(&split.Ticker, &split.ExecutionDate, &split.SplitFrom, &split.SplitTo); err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return nil, err
+		}
+		split.Ratio = split.SplitTo / split.SplitFrom
+		splits = append(splits, split)
+	}
+
+	return splits, rows.Err()
+}
@@ -3,9 +3,11 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
 	"github.com/labstack/gommon/log"
+	"time"
 )
 
 type StocksDataBase struct {
@@ -20,50 +22,90 @@ func NewStockRepository(db *StocksDataBase) *StockRepository {
 	return &StockRepository{db: db}
 }
 
-func (s *StockRepository) AddToFavouriteTickers(favouriteStock FavouriteStock, ctx context.Context) error {
+// AddToFavouriteTickers favourites the ticker and adds it to the end of the user's default watchlist, which
+// is created on their first favourite, so the two stay in step. It returns the default watchlist's id
+func (s *StockRepository) AddToFavouriteTickers(favouriteStock FavouriteStock, ctx context.Context) (string, error) {
+	var watchlistId string
+	err := inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		query := "INSERT INTO favourite_tickers (id, ticker) VALUES (?, ?)"
+
+		result, err := tx.ExecContext(ctx, query, favouriteStock.UserId, favouriteStock.Ticker)
+		if err != nil {
+			log.Errorf("error executing query: %s", err)
+			return err
+		}
 
-	query := "INSERT INTO favourite_tickers (id, ticker) VALUES (?, ?)"
+		rowsAff, err := result.RowsAffected()
+		if err != nil {
+			log.Errorf("error checking rows affected: %s", err)
+			return err
+		}
+		if rowsAff != 1 {
+			return fmt.Errorf("query executed, but change to db does not match. Rows Affected: %d", rowsAff)
+		}
 
-	result, err := s.db.ExecContext(ctx, query, favouriteStock.UserId, favouriteStock.Ticker)
-	if err != nil {
-		log.Errorf("error executing query: %s", err)
-		return err
-	}
+		if watchlistId, err = lockDefaultWatchlist(ctx, tx, favouriteStock.UserId); err != nil {
+			return err
+		}
 
-	rowsAff, err := result.RowsAffected()
+		ticker := WatchlistTicker{Ticker: favouriteStock.Ticker, AddedAt: time.Now().UTC()}
+		if err := appendWatchlistTicker(ctx, tx, watchlistId, ticker); err != nil && !errors.Is(err, ErrWatchlistTickerExists) {
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.Errorf("error checking rows affected: %s", err)
-		return err
+		return "", err
 	}
-	if rowsAff != 1 {
-		return fmt.Errorf("query executed, but change to db does not match. Rows Affected: %d", rowsAff)
 
-	}
-
-	return nil
+	return watchlistId, nil
 }
-func (s *StockRepository) DeleteStockFromFavouriteTickers(favouriteStock FavouriteStock, ctx context.Context) error {
-	query := "DELETE FROM favourite_tickers WHERE id = ? AND  ticker = ?"
 
-	rows, err := s.db.ExecContext(ctx, query, favouriteStock.UserId, favouriteStock.Ticker)
-	if err != nil {
-		log.Errorf("error executing remove from favourite query: %s", err)
-		return err
-	}
+// DeleteStockFromFavouriteTickers unfavourites the ticker and removes it from the user's default watchlist,
+// returning the default watchlist's id or "" when the user doesn't have one
+func (s *StockRepository) DeleteStockFromFavouriteTickers(favouriteStock FavouriteStock, ctx context.Context) (string, error) {
+	var watchlistId string
+	err := inTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		query := "DELETE FROM favourite_tickers WHERE id = ? AND  ticker = ?"
+
+		rows, err := tx.ExecContext(ctx, query, favouriteStock.UserId, favouriteStock.Ticker)
+		if err != nil {
+			log.Errorf("error executing remove from favourite query: %s", err)
+			return err
+		}
+
+		//check if the query was actually successful
+		row, err := rows.RowsAffected()
+		if err != nil {
+			log.Errorf("error checking rows affected: %s", err)
+			return err
+		}
+		if row != 1 {
+			rowErr := fmt.Errorf("query executed, but change to db does not match. Rows Affected: %d", row)
+			return rowErr
+		}
 
-	//check if the query was actually successful
-	row, err := rows.RowsAffected()
+		defaultQuery := "SELECT id FROM watchlists WHERE user_id = ? AND is_default FOR UPDATE"
+		if err := tx.QueryRowContext(ctx, defaultQuery, favouriteStock.UserId).Scan(&watchlistId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			log.Errorf("error locking default watchlist: %s", err)
+			return err
+		}
+
+		if err := removeWatchlistTicker(ctx, tx, watchlistId, favouriteStock.Ticker); err != nil &&
+			!errors.Is(err, ErrWatchlistTickerNotFound) {
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.Errorf("error checking rows affected: %s", err)
-		return err
-	}
-	if row != 1 {
-		rowErr := fmt.Errorf("query executed, but change to db does not match. Rows Affected: %d", row)
-		return rowErr
+		return "", err
 	}
 
 	log.Infof("%s, was removed from %s favourites", favouriteStock.Ticker, favouriteStock.UserId)
-	return nil
+	return watchlistId, nil
 }
 func (s *StockRepository) GetFavouriteTickers(id string, ctx context.Context) Response[[]string] {
 	query := "SELECT ticker FROM favourite_tickers WHERE id = ?"
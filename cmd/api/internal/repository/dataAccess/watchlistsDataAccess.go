@@ -0,0 +1,484 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"slices"
+	"time"
+)
+
+var (
+	ErrWatchlistNotFound       = errors.New("watchlist not found")
+	ErrWatchlistExists         = errors.New("a watchlist with that name already exists")
+	ErrWatchlistTickerNotFound = errors.New("ticker isn't in the watchlist")
+	ErrWatchlistTickerExists   = errors.New("ticker is already in the watchlist")
+	ErrWatchlistOrderMismatch  = errors.New("order must contain every ticker in the watchlist exactly once")
+)
+
+// mysql error numbers for unique and foreign key violations and deadlocks
+const (
+	duplicateEntry    = 1062
+	missingForeignKey = 1452
+	deadlock          = 1213
+)
+
+// DefaultWatchlistName the name a user's default watchlist, which mirrors their favourites, is created with
+const DefaultWatchlistName = "Favourites"
+
+type WatchlistRepository struct {
+	db *StocksDataBase
+}
+
+func NewWatchlistRepository(db *StocksDataBase) *WatchlistRepository {
+	return &WatchlistRepository{db: db}
+}
+
+func (w *WatchlistRepository) CreateWatchlist(watchlist Watchlist, ctx context.Context) error {
+	query := "INSERT INTO watchlists (id, user_id, name, is_default, created_at) VALUES (?, ?, ?, ?, ?)"
+
+	_, err := w.db.ExecContext(ctx, query, watchlist.Id, watchlist.UserId, watchlist.Name, watchlist.IsDefault,
+		watchlist.CreatedAt)
+	if isDuplicate(err) {
+		return ErrWatchlistExists
+	}
+	if err != nil {
+		log.Errorf("error executing create watchlist query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// ListWatchlists gets every watchlist the user has with its tickers in order
+func (w *WatchlistRepository) ListWatchlists(userId string, ctx context.Context) Response[[]Watchlist] {
+	query := `SELECT w.id, w.user_id, w.name, w.is_default, w.created_at, t.ticker, t.position, t.note, t.added_at
+		FROM watchlists w LEFT JOIN watchlist_tickers t ON t.watchlist_id = w.id
+		WHERE w.user_id = ? ORDER BY w.is_default DESC, w.created_at, w.id, t.position`
+
+	watchlists, err := w.queryWatchlists(ctx, query, userId)
+	if err != nil {
+		return Response[[]Watchlist]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]Watchlist]{
+		Data:  watchlists,
+		Error: nil,
+	}
+}
+
+func (w *WatchlistRepository) GetWatchlist(id string, ctx context.Context) Response[*Watchlist] {
+	query := `SELECT w.id, w.user_id, w.name, w.is_default, w.created_at, t.ticker, t.position, t.note, t.added_at
+		FROM watchlists w LEFT JOIN watchlist_tickers t ON t.watchlist_id = w.id
+		WHERE w.id = ? ORDER BY t.position`
+
+	watchlists, err := w.queryWatchlists(ctx, query, id)
+	if err == nil && len(watchlists) == 0 {
+		err = ErrWatchlistNotFound
+	}
+	if err != nil {
+		return Response[*Watchlist]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[*Watchlist]{
+		Data:  &watchlists[0],
+		Error: nil,
+	}
+}
+
+func (w *WatchlistRepository) RenameWatchlist(id string, name string, ctx context.Context) error {
+	query := "UPDATE watchlists SET name = ? WHERE id = ?"
+
+	result, err := w.db.ExecContext(ctx, query, name, id)
+	if isDuplicate(err) {
+		return ErrWatchlistExists
+	}
+	if err != nil {
+		log.Errorf("error executing rename watchlist query: %s", err)
+		return err
+	}
+
+	return w.expectUpdated(ctx, result, ErrWatchlistNotFound,
+		"SELECT EXISTS(SELECT 1 FROM watchlists WHERE id = ?)", id)
+}
+
+// DeleteWatchlist deletes the watchlist, its tickers go with it
+func (w *WatchlistRepository) DeleteWatchlist(id string, ctx context.Context) error {
+	query := "DELETE FROM watchlists WHERE id = ?"
+
+	result, err := w.db.ExecContext(ctx, query, id)
+	if err != nil {
+		log.Errorf("error executing delete watchlist query: %s", err)
+		return err
+	}
+
+	if err := expectOneRow(result, ErrWatchlistNotFound); err != nil {
+		return err
+	}
+
+	log.Infof("watchlist %s was deleted", id)
+	return nil
+}
+
+// AddWatchlistTicker adds the ticker to the end of the watchlist, a ticker added to the default watchlist
+// is favourited too
+func (w *WatchlistRepository) AddWatchlistTicker(id string, ticker WatchlistTicker, ctx context.Context) error {
+	return inTransaction(ctx, w.db, func(tx *sql.Tx) error {
+		userId, isDefault, err := lockWatchlist(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := appendWatchlistTicker(ctx, tx, id, ticker); err != nil {
+			return err
+		}
+
+		if isDefault {
+			return addFavourite(ctx, tx, userId, ticker.Ticker)
+		}
+		return nil
+	})
+}
+
+func (w *WatchlistRepository) UpdateWatchlistTickerNote(id string, ticker string, note string, ctx context.Context) error {
+	query := "UPDATE watchlist_tickers SET note = ? WHERE watchlist_id = ? AND ticker = ?"
+
+	result, err := w.db.ExecContext(ctx, query, note, id, ticker)
+	if err != nil {
+		log.Errorf("error executing update watchlist note query: %s", err)
+		return err
+	}
+
+	return w.expectUpdated(ctx, result, ErrWatchlistTickerNotFound,
+		"SELECT EXISTS(SELECT 1 FROM watchlist_tickers WHERE watchlist_id = ? AND ticker = ?)", id, ticker)
+}
+
+// RemoveWatchlistTicker removes the ticker and closes the gap it leaves in the ordering, a ticker removed
+// from the default watchlist is unfavourited too
+func (w *WatchlistRepository) RemoveWatchlistTicker(id string, ticker string, ctx context.Context) error {
+	return inTransaction(ctx, w.db, func(tx *sql.Tx) error {
+		userId, isDefault, err := lockWatchlist(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := removeWatchlistTicker(ctx, tx, id, ticker); err != nil {
+			return err
+		}
+
+		if isDefault {
+			return removeFavourite(ctx, tx, userId, ticker)
+		}
+		return nil
+	})
+}
+
+// ReorderWatchlist sets the order of the watchlist's tickers, tickers must hold every ticker in the
+// watchlist exactly once
+func (w *WatchlistRepository) ReorderWatchlist(id string, tickers []string, ctx context.Context) error {
+	return inTransaction(ctx, w.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "SELECT ticker FROM watchlist_tickers WHERE watchlist_id = ? FOR UPDATE", id)
+		if err != nil {
+			log.Errorf("error reading watchlist tickers: %s", err)
+			return err
+		}
+
+		var existing []string
+		var ticker string
+		for rows.Next() {
+			if err := rows.Scan(&ticker); err != nil {
+				rows.Close()
+				log.Errorf("error scanning row: %s", err)
+				return err
+			}
+			existing = append(existing, ticker)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		requested := slices.Clone(tickers)
+		slices.Sort(existing)
+		slices.Sort(requested)
+		if !slices.Equal(existing, requested) {
+			return ErrWatchlistOrderMismatch
+		}
+
+		query := "UPDATE watchlist_tickers SET position = ? WHERE watchlist_id = ? AND ticker = ?"
+		for position, ticker := range tickers {
+			if _, err := tx.ExecContext(ctx, query, position, id, ticker); err != nil {
+				log.Errorf("error executing reorder watchlist query: %s", err)
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// queryWatchlists runs a watchlists joined to tickers query, rows for the same watchlist must be adjacent
+func (w *WatchlistRepository) queryWatchlists(ctx context.Context, query string, args ...any) ([]Watchlist, error) {
+	rows, err := w.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	watchlists := []Watchlist{}
+	for rows.Next() {
+		var watchlist Watchlist
+		var ticker, note sql.NullString
+		var position sql.NullInt64
+		var addedAt sql.NullTime
+
+		if err := rows.Scan(&watchlist.Id, &watchlist.UserId, &watchlist.Name, &watchlist.IsDefault,
+			&watchlist.CreatedAt, &ticker, &position, &note, &addedAt); err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return nil, err
+		}
+
+		if len(watchlists) == 0 || watchlists[len(watchlists)-1].Id != watchlist.Id {
+			watchlist.Tickers = []WatchlistTicker{}
+			watchlists = append(watchlists, watchlist)
+		}
+
+		//watchlists without tickers come back with a row of nulls from the left join
+		if ticker.Valid {
+			last := &watchlists[len(watchlists)-1]
+			last.Tickers = append(last.Tickers, WatchlistTicker{
+				Ticker:   ticker.String,
+				Position: int(position.Int64),
+				Note:     note.String,
+				AddedAt:  addedAt.Time,
+			})
+		}
+	}
+
+	return watchlists, rows.Err()
+}
+
+// lockWatchlist locks the watchlist's row until the transaction ends so changes to its tickers are made one
+// at a time, their positions are worked out from what's already there
+func lockWatchlist(ctx context.Context, tx *sql.Tx, id string) (userId string, isDefault bool, err error) {
+	query := "SELECT user_id, is_default FROM watchlists WHERE id = ? FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&userId, &isDefault); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, ErrWatchlistNotFound
+		}
+		log.Errorf("error locking watchlist: %s", err)
+		return "", false, err
+	}
+
+	return userId, isDefault, nil
+}
+
+// lockDefaultWatchlist locks the user's default watchlist, creating it on their first favourite
+func lockDefaultWatchlist(ctx context.Context, tx *sql.Tx, userId string) (string, error) {
+	var id string
+	query := "SELECT id FROM watchlists WHERE user_id = ? AND is_default FOR UPDATE"
+
+	err := tx.QueryRowContext(ctx, query, userId).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Errorf("error locking default watchlist: %s", err)
+		return "", err
+	}
+
+	id = uuid.NewString()
+	insert := "INSERT INTO watchlists (id, user_id, name, is_default, created_at) VALUES (?, ?, ?, TRUE, ?)"
+	if _, err := tx.ExecContext(ctx, insert, id, userId, DefaultWatchlistName, time.Now().UTC()); err != nil {
+		if isDuplicate(err) {
+			return "", fmt.Errorf("can't create the default watchlist: %w", ErrWatchlistExists)
+		}
+		log.Errorf("error executing create default watchlist query: %s", err)
+		return "", err
+	}
+
+	return id, nil
+}
+
+// appendWatchlistTicker adds the ticker after the last one, the watchlist must be locked
+func appendWatchlistTicker(ctx context.Context, tx *sql.Tx, id string, ticker WatchlistTicker) error {
+	query := `INSERT INTO watchlist_tickers (watchlist_id, ticker, position, note, added_at)
+		SELECT ?, ?, COALESCE(MAX(position) + 1, 0), ?, ? FROM watchlist_tickers WHERE watchlist_id = ?`
+
+	_, err := tx.ExecContext(ctx, query, id, ticker.Ticker, ticker.Note, ticker.AddedAt, id)
+	if isDuplicate(err) {
+		return ErrWatchlistTickerExists
+	}
+	if err != nil {
+		log.Errorf("error executing add watchlist ticker query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// removeWatchlistTicker removes the ticker and moves those after it up one, the watchlist must be locked
+func removeWatchlistTicker(ctx context.Context, tx *sql.Tx, id string, ticker string) error {
+	var position int
+	positionQuery := "SELECT position FROM watchlist_tickers WHERE watchlist_id = ? AND ticker = ?"
+	if err := tx.QueryRowContext(ctx, positionQuery, id, ticker).Scan(&position); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWatchlistTickerNotFound
+		}
+		log.Errorf("error reading watchlist ticker position: %s", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM watchlist_tickers WHERE watchlist_id = ? AND ticker = ?",
+		id, ticker); err != nil {
+		log.Errorf("error executing remove watchlist ticker query: %s", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE watchlist_tickers SET position = position - 1 WHERE watchlist_id = ? AND position > ?",
+		id, position); err != nil {
+		log.Errorf("error reordering watchlist after remove: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// addFavourite favourites the ticker unless it already is, for tickers added to the default watchlist
+func addFavourite(ctx context.Context, tx *sql.Tx, userId string, ticker string) error {
+	query := `INSERT INTO favourite_tickers (id, ticker) SELECT ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM favourite_tickers WHERE id = ? AND ticker = ?)`
+
+	if _, err := tx.ExecContext(ctx, query, userId, ticker, userId, ticker); err != nil {
+		log.Errorf("error executing add favourite query: %s", err)
+		return err
+	}
+	return nil
+}
+
+// removeFavourite unfavourites the ticker, for tickers removed from the default watchlist
+func removeFavourite(ctx context.Context, tx *sql.Tx, userId string, ticker string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM favourite_tickers WHERE id = ? AND ticker = ?", userId, ticker); err != nil {
+		log.Errorf("error executing remove favourite query: %s", err)
+		return err
+	}
+	return nil
+}
+
+func inTransaction(ctx context.Context, db *StocksDataBase, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("error starting transaction: %s", err)
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Errorf("error rolling back transaction: %s", rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertWithinLimit runs insert in a transaction after locking and counting the user's rows in table, a
+// concurrent insert for the same user waits on the lock so two can't both see room for one more.
+// tooMany is returned when the user already has limit rows. InnoDB can pick one of two racing inserts into
+// an empty range as a deadlock victim, that one is retried
+func insertWithinLimit(ctx context.Context, db *StocksDataBase, table string, userId string, limit int,
+	tooMany error, insert func(tx *sql.Tx) error) error {
+	const attempts = 3
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = inTransaction(ctx, db, func(tx *sql.Tx) error {
+			var count int
+			query := "SELECT COUNT(*) FROM " + table + " WHERE user_id = ? FOR UPDATE"
+			if err := tx.QueryRowContext(ctx, query, userId).Scan(&count); err != nil {
+				log.Errorf("error locking %s for %s: %s", table, userId, err)
+				return err
+			}
+			if count >= limit {
+				return fmt.Errorf("%w, users can have at most %d", tooMany, limit)
+			}
+
+			return insert(tx)
+		})
+		if !isDeadlock(err) {
+			return err
+		}
+		log.Warnf("deadlock inserting into %s for %s, attempt %d of %d", table, userId, attempt, attempts)
+	}
+
+	return err
+}
+
+// expectUpdated checks an update matched a row, mysql reports 0 rows affected when the values didn't
+// change so existsQuery is used to tell that apart from a missing row
+func (w *WatchlistRepository) expectUpdated(ctx context.Context, result sql.Result, notFound error,
+	existsQuery string, args ...any) error {
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		log.Errorf("error checking rows affected: %s", err)
+		return err
+	}
+	if rowsAff > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := w.db.QueryRowContext(ctx, existsQuery, args...).Scan(&exists); err != nil {
+		log.Errorf("error checking row exists: %s", err)
+		return err
+	}
+	if !exists {
+		return notFound
+	}
+
+	return nil
+}
+
+// expectOneRow returns notFound when the statement didn't change exactly one row
+func expectOneRow(result sql.Result, notFound error) error {
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		log.Errorf("error checking rows affected: %s", err)
+		return err
+	}
+	if rowsAff == 0 {
+		return notFound
+	}
+	if rowsAff != 1 {
+		return fmt.Errorf("query executed, but change to db does not match. Rows Affected: %d", rowsAff)
+	}
+
+	return nil
+}
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntry
+}
+
+func isDeadlock(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == deadlock
+}
+
+func isMissingForeignKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == missingForeignKey
+}
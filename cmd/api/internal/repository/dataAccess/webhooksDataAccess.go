@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/labstack/gommon/log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrTooManyWebhooks  = errors.New("too many webhooks")
+)
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.last_error, d.last_status_code, d.created_at, d.delivered_at`
+
+type WebhookRepository struct {
+	db *StocksDataBase
+}
+
+func NewWebhookRepository(db *StocksDataBase) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// CreateWebhook saves the webhook unless the user already has maxPerUser, then it's ErrTooManyWebhooks
+func (w *WebhookRepository) CreateWebhook(webhook Webhook, maxPerUser int, ctx context.Context) error {
+	query := "INSERT INTO webhooks (id, user_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+
+	return insertWithinLimit(ctx, w.db, "webhooks", webhook.UserId, maxPerUser, ErrTooManyWebhooks, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, webhook.Id, webhook.UserId, webhook.Url, webhook.Secret,
+			strings.Join(webhook.Events, ","), webhook.CreatedAt)
+		if err != nil {
+			log.Errorf("error executing create webhook query: %s", err)
+		}
+		return err
+	})
+}
+
+// ListWebhooks the user's webhooks, secrets aren't loaded
+func (w *WebhookRepository) ListWebhooks(userId string, ctx context.Context) Response[[]Webhook] {
+	query := "SELECT id, user_id, url, '', events, created_at FROM webhooks WHERE user_id = ? ORDER BY created_at, id"
+	return w.queryWebhooks(ctx, query, userId)
+}
+
+// ListSubscribedWebhooks the user's webhooks that want eventType, with their secrets
+func (w *WebhookRepository) ListSubscribedWebhooks(userId string, eventType string, ctx context.Context) Response[[]Webhook] {
+	query := `SELECT id, user_id, url, secret, events, created_at FROM webhooks
+		WHERE user_id = ? AND FIND_IN_SET(?, events) > 0`
+	return w.queryWebhooks(ctx, query, userId, eventType)
+}
+
+// GetWebhook gets the webhook without its secret
+func (w *WebhookRepository) GetWebhook(id string, ctx context.Context) Response[*Webhook] {
+	query := "SELECT id, user_id, url, '', events, created_at FROM webhooks WHERE id = ?"
+
+	webhook, err := scanWebhook(w.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrWebhookNotFound
+		} else {
+			log.Errorf("error executing get webhook query: %s", err)
+		}
+
+		return Response[*Webhook]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[*Webhook]{
+		Data:  webhook,
+		Error: nil,
+	}
+}
+
+// DeleteWebhook deletes the webhook, its deliveries go with it
+func (w *WebhookRepository) DeleteWebhook(id string, ctx context.Context) error {
+	result, err := w.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		log.Errorf("error executing delete webhook query: %s", err)
+		return err
+	}
+
+	return expectOneRow(result, ErrWebhookNotFound)
+}
+
+// EnqueueDeliveries adds the deliveries to the outbox, a webhook is only sent each event once
+func (w *WebhookRepository) EnqueueDeliveries(deliveries []WebhookDelivery, ctx context.Context) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	query := `INSERT IGNORE INTO webhook_deliveries (id, webhook_id, event_id, event_type, payload, status, attempts,
+		next_attempt_at, created_at) VALUES ` + strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?, ?, ?), ", len(deliveries)), ", ")
+
+	args := make([]any, 0, len(deliveries)*9)
+	for _, delivery := range deliveries {
+		args = append(args, delivery.Id, delivery.WebhookId, delivery.EventId, delivery.EventType, delivery.Payload,
+			delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt)
+	}
+
+	if _, err := w.db.ExecContext(ctx, query, args...); err != nil {
+		log.Errorf("error executing enqueue deliveries query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDueDeliveries takes up to limit pending deliveries that are due and pushes their next attempt back
+// by lease so another worker won't pick them up while they're being sent. Rows another worker has locked
+// are skipped
+func (w *WebhookRepository) ClaimDueDeliveries(limit int, lease time.Duration, ctx context.Context) Response[[]DueDelivery] {
+	var claimed []DueDelivery
+
+	err := w.inTransaction(ctx, func(tx *sql.Tx) error {
+		now := time.Now().UTC()
+		query := "SELECT " + deliveryColumns + `, h.url, h.secret
+			FROM webhook_deliveries d JOIN webhooks h ON h.id = d.webhook_id
+			WHERE d.status = ? AND d.next_attempt_at <= ?
+			ORDER BY d.next_attempt_at LIMIT ? FOR UPDATE OF d SKIP LOCKED`
+
+		rows, err := tx.QueryContext(ctx, query, DeliveryPending, now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var ids []any
+		for rows.Next() {
+			var due DueDelivery
+			if err := scanDelivery(rows, &due.WebhookDelivery, &due.Url, &due.Secret); err != nil {
+				return err
+			}
+			claimed = append(claimed, due)
+			ids = append(ids, due.Id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		update := "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		_, err = tx.ExecContext(ctx, update, append([]any{now.Add(lease)}, ids...)...)
+		return err
+	})
+	if err != nil {
+		log.Errorf("error claiming due deliveries: %s", err)
+		return Response[[]DueDelivery]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]DueDelivery]{
+		Data:  claimed,
+		Error: nil,
+	}
+}
+
+// UpdateDelivery saves the delivery's status and attempts, after it's been sent or when it's replayed
+func (w *WebhookRepository) UpdateDelivery(delivery WebhookDelivery, ctx context.Context) error {
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?,
+		last_status_code = ?, delivered_at = ? WHERE id = ?`
+
+	_, err := w.db.ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt,
+		truncate(delivery.LastError, 1024), delivery.LastStatusCode, delivery.DeliveredAt, delivery.Id)
+	if err != nil {
+		log.Errorf("error executing update delivery query: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// ListDeliveries the webhook's deliveries newest first, status filters them when set
+func (w *WebhookRepository) ListDeliveries(webhookId string, status string, limit int, ctx context.Context) Response[[]WebhookDelivery] {
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries d WHERE d.webhook_id = ?"
+	args := []any{webhookId}
+	if status != "" {
+		query += " AND d.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY d.created_at DESC, d.id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := w.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]WebhookDelivery]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var delivery WebhookDelivery
+		if err := scanDelivery(rows, &delivery); err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]WebhookDelivery]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return Response[[]WebhookDelivery]{
+		Data:  deliveries,
+		Error: rows.Err(),
+	}
+}
+
+func (w *WebhookRepository) GetDelivery(webhookId string, id string, ctx context.Context) Response[*WebhookDelivery] {
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries d WHERE d.id = ? AND d.webhook_id = ?"
+
+	var delivery WebhookDelivery
+	if err := scanDelivery(w.db.QueryRowContext(ctx, query, id, webhookId), &delivery); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrDeliveryNotFound
+		} else {
+			log.Errorf("error executing get delivery query: %s", err)
+		}
+
+		return Response[*WebhookDelivery]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[*WebhookDelivery]{
+		Data:  &delivery,
+		Error: nil,
+	}
+}
+
+func (w *WebhookRepository) queryWebhooks(ctx context.Context, query string, args ...any) Response[[]Webhook] {
+	rows, err := w.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("error executing query: %s", err)
+		return Response[[]Webhook]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			log.Errorf("error scanning row: %s", err)
+			return Response[[]Webhook]{
+				Data:  nil,
+				Error: err,
+			}
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+
+	return Response[[]Webhook]{
+		Data:  webhooks,
+		Error: rows.Err(),
+	}
+}
+
+func (w *WebhookRepository) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Errorf("error starting transaction: %s", err)
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Errorf("error rolling back transaction: %s", rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	var webhook Webhook
+	var events string
+
+	if err := row.Scan(&webhook.Id, &webhook.UserId, &webhook.Url, &webhook.Secret, &events,
+		&webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	webhook.Events = strings.Split(events, ",")
+
+	return &webhook, nil
+}
+
+// scanDelivery scans the delivery columns followed by any extra columns the query selected
+func scanDelivery(row rowScanner, delivery *WebhookDelivery, extra ...any) error {
+	var lastStatusCode sql.NullInt64
+	var deliveredAt sql.NullTime
+
+	dest := append([]any{&delivery.Id, &delivery.WebhookId, &delivery.EventId, &delivery.EventType, &delivery.Payload,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &lastStatusCode,
+		&delivery.CreatedAt, &deliveredAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+
+	if lastStatusCode.Valid {
+		code := int(lastStatusCode.Int64)
+		delivery.LastStatusCode = &code
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+
+	return nil
+}
+
+// truncate cuts s down to at most max bytes so it fits its column
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id          CHAR(36)     NOT NULL PRIMARY KEY,
+    name        VARCHAR(100) NOT NULL,
+    prefix      VARCHAR(16)  NOT NULL,
+    key_hash    CHAR(64)     NOT NULL UNIQUE,
+    owner_id    VARCHAR(64)  NOT NULL,
+    scopes      VARCHAR(255) NOT NULL,
+    daily_quota INT          NOT NULL,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at  DATETIME     NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id CHAR(36) NOT NULL,
+    day    DATE     NOT NULL,
+    count  INT      NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS watchlists (
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    user_id    VARCHAR(64)  NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    is_default BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY watchlists_user_name (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS watchlist_tickers (
+    watchlist_id CHAR(36)     NOT NULL,
+    ticker       VARCHAR(16)  NOT NULL,
+    position     INT          NOT NULL,
+    note         VARCHAR(500) NOT NULL DEFAULT '',
+    added_at     DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (watchlist_id, ticker),
+    FOREIGN KEY (watchlist_id) REFERENCES watchlists (id) ON DELETE CASCADE
+);
+
+-- every user with favourites gets a default watchlist holding them, from then on the repositories keep the
+-- two in step
+INSERT IGNORE INTO watchlists (id, user_id, name, is_default)
+SELECT UUID(), users.id, 'Favourites', TRUE
+FROM (SELECT DISTINCT id FROM favourite_tickers) users;
+
+INSERT IGNORE INTO watchlist_tickers (watchlist_id, ticker, position)
+SELECT w.id, f.ticker, ROW_NUMBER() OVER (PARTITION BY f.id ORDER BY f.ticker) - 1
+FROM (SELECT DISTINCT id, ticker FROM favourite_tickers) f
+JOIN watchlists w ON w.user_id = f.id AND w.is_default;
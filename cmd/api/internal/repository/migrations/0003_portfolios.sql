@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS portfolios (
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    user_id    VARCHAR(64)  NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    cost_basis VARCHAR(10)  NOT NULL DEFAULT 'fifo',
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY portfolios_user_name (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS portfolio_transactions (
+    id           CHAR(36)       NOT NULL PRIMARY KEY,
+    portfolio_id CHAR(36)       NOT NULL,
+    ticker       VARCHAR(16)    NOT NULL,
+    type         VARCHAR(10)    NOT NULL,
+    quantity     DECIMAL(24, 8) NOT NULL DEFAULT 0,
+    price        DECIMAL(24, 8) NOT NULL DEFAULT 0,
+    fees         DECIMAL(24, 8) NOT NULL DEFAULT 0,
+    amount       DECIMAL(24, 8) NOT NULL DEFAULT 0,
+    ratio        DECIMAL(24, 8) NOT NULL DEFAULT 0,
+    executed_at  DATETIME       NOT NULL,
+    created_at   DATETIME       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX portfolio_transactions_portfolio (portfolio_id, executed_at),
+    FOREIGN KEY (portfolio_id) REFERENCES portfolios (id) ON DELETE CASCADE
+);
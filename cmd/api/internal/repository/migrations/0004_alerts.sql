@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS alerts (
+    id                CHAR(36)       NOT NULL PRIMARY KEY,
+    user_id           VARCHAR(64)    NOT NULL,
+    ticker            VARCHAR(16)    NOT NULL,
+    alert_condition   VARCHAR(20)    NOT NULL,
+    threshold         DECIMAL(24, 8) NOT NULL DEFAULT 0,
+    sma_window        INT            NOT NULL DEFAULT 0,
+    cooldown_minutes  INT            NOT NULL,
+    active            BOOLEAN        NOT NULL DEFAULT TRUE,
+    last_state        VARCHAR(10)    NOT NULL DEFAULT '',
+    last_triggered_at DATETIME       NULL,
+    created_at        DATETIME       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX alerts_user (user_id),
+    INDEX alerts_active (active)
+);
+
+CREATE TABLE IF NOT EXISTS alert_triggers (
+    id           CHAR(36)       NOT NULL PRIMARY KEY,
+    alert_id     CHAR(36)       NOT NULL,
+    bar_date     DATE           NOT NULL,
+    price        DECIMAL(24, 8) NOT NULL,
+    value        DECIMAL(24, 8) NOT NULL,
+    message      VARCHAR(255)   NOT NULL,
+    triggered_at DATETIME       NOT NULL,
+    UNIQUE KEY alert_triggers_alert_bar (alert_id, bar_date),
+    FOREIGN KEY (alert_id) REFERENCES alerts (id) ON DELETE CASCADE
+);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         CHAR(36)      NOT NULL PRIMARY KEY,
+    user_id    VARCHAR(64)   NOT NULL,
+    url        VARCHAR(2048) NOT NULL,
+    secret     VARCHAR(128)  NOT NULL,
+    events     VARCHAR(255)  NOT NULL,
+    created_at DATETIME      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX webhooks_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               CHAR(36)      NOT NULL PRIMARY KEY,
+    webhook_id       CHAR(36)      NOT NULL,
+    event_id         CHAR(36)      NOT NULL,
+    event_type       VARCHAR(64)   NOT NULL,
+    payload          MEDIUMTEXT    NOT NULL,
+    status           VARCHAR(10)   NOT NULL DEFAULT 'pending',
+    attempts         INT           NOT NULL DEFAULT 0,
+    next_attempt_at  DATETIME(3)   NOT NULL,
+    last_error       VARCHAR(1024) NOT NULL DEFAULT '',
+    last_status_code INT           NULL,
+    created_at       DATETIME(3)   NOT NULL,
+    delivered_at     DATETIME(3)   NULL,
+    UNIQUE KEY webhook_deliveries_event (webhook_id, event_id),
+    INDEX webhook_deliveries_due (status, next_attempt_at),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id           VARCHAR(64)  NOT NULL PRIMARY KEY,
+    email             VARCHAR(254) NOT NULL,
+    subscribed        BOOLEAN      NOT NULL DEFAULT TRUE,
+    unsubscribe_token CHAR(64)     NOT NULL,
+    last_sent_at      DATETIME     NULL,
+    created_at        DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY digest_subscriptions_token (unsubscribe_token),
+    INDEX digest_subscriptions_subscribed (subscribed)
+);
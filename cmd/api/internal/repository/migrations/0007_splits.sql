@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS applied_splits (
+    ticker         VARCHAR(16)    NOT NULL,
+    execution_date DATE           NOT NULL,
+    split_from     DECIMAL(24, 8) NOT NULL,
+    split_to       DECIMAL(24, 8) NOT NULL,
+    portfolios     INT            NOT NULL DEFAULT 0,
+    alerts         INT            NOT NULL DEFAULT 0,
+    alert_triggers INT            NOT NULL DEFAULT 0,
+    applied_at     DATETIME       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ticker, execution_date)
+);
+
+CREATE INDEX portfolio_transactions_ticker ON portfolio_transactions (ticker, executed_at);
+CREATE INDEX alerts_ticker ON alerts (ticker);
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed *.sql
+var scripts embed.FS
+
+// Apply runs every embedded migration that hasn't been recorded in schema_migrations yet, in file name order
+func Apply(ctx context.Context, db *sql.DB) error {
+	createQuery := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    VARCHAR(100) NOT NULL PRIMARY KEY,
+		applied_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	if _, err := db.ExecContext(ctx, createQuery); err != nil {
+		log.Errorf("error creating schema_migrations: %s", err)
+		return err
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(scripts, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if applied[file] {
+			continue
+		}
+
+		if err := applyFile(ctx, db, file); err != nil {
+			return fmt.Errorf("migration %s failed: %w", file, err)
+		}
+		log.Infof("applied migration %s", file)
+	}
+
+	return nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		log.Errorf("error reading schema_migrations: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	var version string
+
+	for rows.Next() {
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// applyFile runs each statement in the file, the mysql driver doesn't allow multiple statements per exec
+func applyFile(ctx context.Context, db *sql.DB, file string) error {
+	script, err := scripts.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range strings.Split(string(script), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (?)", file)
+	return err
+}
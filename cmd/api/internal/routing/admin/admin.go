@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/admin"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AdminHandler struct {
+	apiKeyRepo   *repository.ApiKeyRepository
+	authenticate gin.HandlerFunc
+	limiter      *ratelimit.Limiter
+}
+
+func SetUpAdminHandler(apiKeyRepo *repository.ApiKeyRepository, authenticate gin.HandlerFunc,
+	limiter *ratelimit.Limiter) *AdminHandler {
+	return &AdminHandler{
+		apiKeyRepo:   apiKeyRepo,
+		authenticate: authenticate,
+		limiter:      limiter,
+	}
+}
+
+func (a *AdminHandler) RegisterRoutes(version *versions.Version) {
+	middleware := []gin.HandlerFunc{a.authenticate, a.limiter.Limit(ratelimit.GroupAdmin), auth.RequireScope(auth.ScopeAdmin)}
+
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			Method: http.MethodGet, Path: "/admin/keys",
+			Middleware: middleware,
+			Handler: func(c *gin.Context) {
+				admin.ListApiKeys(c, *a.apiKeyRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "admin", Summary: "List issued api keys",
+				ResponseKey: "data", Response: []models.ApiKey{},
+			},
+		},
+
+		versions.Route{
+			Method: http.MethodGet, Path: "/admin/features",
+			Middleware: middleware,
+			Handler:    admin.ListFeatureFlags,
+			Doc: openapi.Operation{
+				Tag: "admin", Summary: "List feature flags and whether each is on for a user",
+				Query: dtos.FeatureFlagsDto{}, ResponseKey: "data", Response: []models.FeatureEvaluation{},
+			},
+		},
+
+		//********** POST/PUT/PATCH COMMANDS **********
+		versions.Route{
+			Method: http.MethodPost, Path: "/admin/keys",
+			Middleware: middleware,
+			Handler: func(c *gin.Context) {
+				admin.IssueApiKey(c, *a.apiKeyRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "admin", Summary: "Issue a new api key, the key is only returned once",
+				Body: dtos.IssueApiKeyDto{}, Status: http.StatusCreated, ResponseKey: "created", Response: dtos.IssuedApiKeyDto{},
+			},
+		},
+
+		//********** DELETE COMMANDS**********
+		versions.Route{
+			Method: http.MethodDelete, Path: "/admin/keys/:id",
+			Middleware: middleware,
+			Handler: func(c *gin.Context) {
+				admin.RevokeApiKey(c, *a.apiKeyRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "admin", Summary: "Revoke an api key",
+				Uri: dtos.ApiKeyIdDto{}, ResponseKey: "revoked", Response: "",
+			},
+		},
+	)
+}
@@ -0,0 +1,93 @@
+package alerts
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/alert"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+)
+
+type AlertHandler struct {
+	alertRepo    *repository.AlertRepository
+	polyClient   *intergration.PolygonApi
+	authenticate gin.HandlerFunc
+	limiter      *ratelimit.Limiter
+}
+
+func SetUpAlertHandler(repo *repository.AlertRepository, polyClient *intergration.PolygonApi,
+	authenticate gin.HandlerFunc, limiter *ratelimit.Limiter) *AlertHandler {
+	return &AlertHandler{
+		alertRepo:    repo,
+		polyClient:   polyClient,
+		authenticate: authenticate,
+		limiter:      limiter,
+	}
+}
+
+// middleware every alert route runs, followed by any route specific middleware
+func (a *AlertHandler) middleware(extra ...gin.HandlerFunc) []gin.HandlerFunc {
+	return slices.Concat([]gin.HandlerFunc{a.authenticate, a.limiter.Limit(ratelimit.GroupStocks),
+		auth.RequireScope(auth.ScopeAlerts)}, extra)
+}
+
+func (a *AlertHandler) RegisterRoutes(version *versions.Version) {
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			Method: http.MethodGet, Path: "/alerts",
+			Middleware: a.middleware(),
+			Handler: func(c *gin.Context) {
+				alert.ListAlerts(c, *a.alertRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "alerts", Summary: "List the caller's price alerts",
+				Query: dtos.ListAlertsDto{}, ResponseKey: "data", Response: []models.Alert{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/alerts/:id/triggers",
+			Middleware: a.middleware(),
+			Handler: func(c *gin.Context) {
+				alert.ListTriggers(c, *a.alertRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "alerts", Summary: "List when an alert triggered, newest first",
+				Uri: dtos.AlertIdDto{}, ResponseKey: "data", Response: []models.AlertTrigger{},
+			},
+		},
+
+		//********** POST/PUT/PATCH COMMANDS **********
+		versions.Route{
+			Method: http.MethodPost, Path: "/alerts",
+			Middleware: a.middleware(),
+			Handler: func(c *gin.Context) {
+				alert.CreateAlert(c, *a.alertRepo, a.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "alerts", Summary: "Create a price, percent move or sma cross alert",
+				Body: dtos.CreateAlertDto{}, Status: http.StatusCreated, ResponseKey: "created", Response: models.Alert{},
+			},
+		},
+
+		//********** DELETE COMMANDS**********
+		versions.Route{
+			Method: http.MethodDelete, Path: "/alerts/:id",
+			Middleware: a.middleware(),
+			Handler: func(c *gin.Context) {
+				alert.DeleteAlert(c, *a.alertRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "alerts", Summary: "Delete an alert and its trigger history",
+				Uri: dtos.AlertIdDto{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+	)
+}
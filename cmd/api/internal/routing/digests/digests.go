@@ -0,0 +1,101 @@
+package digests
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/digest"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+)
+
+type DigestHandler struct {
+	digestRepo   *repository.DigestRepository
+	authenticate gin.HandlerFunc
+	limiter      *ratelimit.Limiter
+}
+
+func SetUpDigestHandler(repo *repository.DigestRepository, authenticate gin.HandlerFunc,
+	limiter *ratelimit.Limiter) *DigestHandler {
+	return &DigestHandler{
+		digestRepo:   repo,
+		authenticate: authenticate,
+		limiter:      limiter,
+	}
+}
+
+// middleware every signed in digest route runs, followed by any route specific middleware
+func (d *DigestHandler) middleware(extra ...gin.HandlerFunc) []gin.HandlerFunc {
+	return slices.Concat([]gin.HandlerFunc{d.authenticate, d.limiter.Limit(ratelimit.GroupStocks),
+		auth.RequireScope(auth.ScopeFavourites)}, extra)
+}
+
+func (d *DigestHandler) RegisterRoutes(version *versions.Version) {
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			Method: http.MethodGet, Path: "/digest",
+			Middleware: d.middleware(),
+			Handler: func(c *gin.Context) {
+				digest.GetSubscription(c, *d.digestRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "digest", Summary: "Get the caller's daily email digest subscription",
+				Query: dtos.DigestSubscriptionDto{}, ResponseKey: "data", Response: models.DigestSubscription{},
+			},
+		},
+		versions.Route{
+			//linked from the digest email so it's signed by the token rather than a key
+			Method: http.MethodGet, Path: "/digest/unsubscribe",
+			Middleware: []gin.HandlerFunc{d.limiter.Limit(ratelimit.GroupStocks)},
+			Handler:    digest.ConfirmUnsubscribe,
+			Doc: openapi.Operation{
+				Tag: "digest", Summary: "Page asking to confirm unsubscribing from the daily email digest",
+				Query: dtos.UnsubscribeDigestDto{}, Response: "", ContentType: "text/html", Public: true,
+			},
+		},
+
+		//********** POST/PUT/PATCH COMMANDS **********
+		versions.Route{
+			//posted by the confirmation page and by mail clients' one-click unsubscribe
+			Method: http.MethodPost, Path: "/digest/unsubscribe",
+			Middleware: []gin.HandlerFunc{d.limiter.Limit(ratelimit.GroupStocks)},
+			Handler: func(c *gin.Context) {
+				digest.UnsubscribeByToken(c, *d.digestRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "digest", Summary: "Unsubscribe from the daily email digest using the link's token",
+				Query: dtos.UnsubscribeDigestDto{}, ResponseKey: "deleted", Response: "", Public: true,
+			},
+		},
+		versions.Route{
+			Method: http.MethodPut, Path: "/digest",
+			Middleware: d.middleware(),
+			Handler: func(c *gin.Context) {
+				digest.Subscribe(c, *d.digestRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "digest", Summary: "Subscribe to the daily email digest of favourites and fired alerts",
+				Body: dtos.SubscribeDigestDto{}, ResponseKey: "updated", Response: models.DigestSubscription{},
+			},
+		},
+
+		//********** DELETE COMMANDS**********
+		versions.Route{
+			Method: http.MethodDelete, Path: "/digest",
+			Middleware: d.middleware(),
+			Handler: func(c *gin.Context) {
+				digest.Unsubscribe(c, *d.digestRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "digest", Summary: "Unsubscribe from the daily email digest",
+				Query: dtos.DigestSubscriptionDto{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+	)
+}
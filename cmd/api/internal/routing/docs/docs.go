@@ -0,0 +1,30 @@
+package docs
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Paths the docs routes, these aren't part of the document they serve
+var Paths = []string{"/openapi.json", "/docs"}
+
+type DocsHandler struct {
+	document *openapi.Document
+}
+
+func SetUpDocsHandler(document *openapi.Document) *DocsHandler {
+	return &DocsHandler{
+		document: document,
+	}
+}
+
+func (d *DocsHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, d.document)
+	})
+	router.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.SwaggerUI)
+	})
+	router.StaticFS("/docs/assets", http.FS(openapi.SwaggerAssets))
+}
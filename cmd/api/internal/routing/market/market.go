@@ -0,0 +1,48 @@
+package market
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/market"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+)
+
+type MarketHandler struct {
+	authenticate gin.HandlerFunc
+	limiter      *ratelimit.Limiter
+}
+
+func SetUpMarketHandler(authenticate gin.HandlerFunc, limiter *ratelimit.Limiter) *MarketHandler {
+	return &MarketHandler{
+		authenticate: authenticate,
+		limiter:      limiter,
+	}
+}
+
+// middleware every market route runs, followed by any route specific middleware
+func (m *MarketHandler) middleware(extra ...gin.HandlerFunc) []gin.HandlerFunc {
+	return slices.Concat([]gin.HandlerFunc{m.authenticate, m.limiter.Limit(ratelimit.GroupStocks),
+		auth.RequireScope(auth.ScopeMarketData)}, extra)
+}
+
+func (m *MarketHandler) RegisterRoutes(version *versions.Version) {
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			Method: http.MethodGet, Path: "/market/status",
+			Middleware: m.middleware(),
+			Handler: func(c *gin.Context) {
+				market.GetStatus(c)
+			},
+			Doc: openapi.Operation{
+				Tag: "market", Summary: "Get whether US equities are open, pre-market, after-hours or closed and the next open and close",
+				ResponseKey: "data", Response: models.MarketStatus{},
+			},
+		},
+	)
+}
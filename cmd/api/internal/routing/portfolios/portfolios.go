@@ -0,0 +1,164 @@
+package portfolios
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/portfolio"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+)
+
+type PortfolioHandler struct {
+	portfolioRepo *repository.PortfolioRepository
+	polyClient    *intergration.PolygonApi
+	authenticate  gin.HandlerFunc
+	limiter       *ratelimit.Limiter
+}
+
+func SetUpPortfolioHandler(repo *repository.PortfolioRepository, polyClient *intergration.PolygonApi,
+	authenticate gin.HandlerFunc, limiter *ratelimit.Limiter) *PortfolioHandler {
+	return &PortfolioHandler{
+		portfolioRepo: repo,
+		polyClient:    polyClient,
+		authenticate:  authenticate,
+		limiter:       limiter,
+	}
+}
+
+// middleware every portfolio route runs, followed by any route specific middleware
+func (p *PortfolioHandler) middleware(extra ...gin.HandlerFunc) []gin.HandlerFunc {
+	return slices.Concat([]gin.HandlerFunc{p.authenticate, p.limiter.Limit(ratelimit.GroupStocks),
+		auth.RequireScope(auth.ScopePortfolios)}, extra)
+}
+
+func (p *PortfolioHandler) RegisterRoutes(version *versions.Version) {
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			Method: http.MethodGet, Path: "/portfolios",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.ListPortfolios(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "List the caller's portfolios",
+				Query: dtos.ListPortfoliosDto{}, ResponseKey: "data", Response: []models.Portfolio{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/portfolios/:id",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.GetPortfolio(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Get a portfolio and its positions",
+				Uri: dtos.PortfolioIdDto{}, ResponseKey: "data", Response: struct {
+					Portfolio models.Portfolio  `json:"portfolio"`
+					Positions []models.Position `json:"positions"`
+				}{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/portfolios/:id/transactions",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.ListTransactions(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "List a portfolio's transactions oldest first",
+				Uri: dtos.PortfolioIdDto{}, ResponseKey: "data", Response: []models.Transaction{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/portfolios/:id/valuation",
+			Middleware: p.middleware(p.limiter.Limit(ratelimit.GroupOpenClose)),
+			Handler: func(c *gin.Context) {
+				portfolio.GetValuation(c, *p.portfolioRepo, p.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Value a portfolio's open positions at the previous close",
+				Uri: dtos.PortfolioIdDto{}, ResponseKey: "data", Response: models.PortfolioValuation{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/portfolios/:id/performance",
+			Middleware: p.middleware(p.limiter.Limit(ratelimit.GroupOpenClose)),
+			Handler: func(c *gin.Context) {
+				portfolio.GetPerformance(c, *p.portfolioRepo, p.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Get a portfolio's returns, risk ratios and beta against a benchmark over a period",
+				Uri: dtos.PortfolioIdDto{}, Query: dtos.PortfolioPerformanceDto{}, ResponseKey: "data",
+				Response: models.PortfolioPerformance{},
+			},
+		},
+
+		//********** POST/PUT/PATCH COMMANDS **********
+		versions.Route{
+			Method: http.MethodPost, Path: "/portfolios",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.CreatePortfolio(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Create a portfolio",
+				Body: dtos.CreatePortfolioDto{}, Status: http.StatusCreated, ResponseKey: "created", Response: models.Portfolio{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodPatch, Path: "/portfolios/:id",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.UpdatePortfolio(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Rename a portfolio or change its cost basis method",
+				Uri: dtos.PortfolioIdDto{}, Body: dtos.UpdatePortfolioDto{}, ResponseKey: "updated", Response: models.Portfolio{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodPost, Path: "/portfolios/:id/transactions",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.AddTransaction(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Record a buy, sell, dividend or split",
+				Uri: dtos.PortfolioIdDto{}, Body: dtos.AddTransactionDto{}, Status: http.StatusCreated,
+				ResponseKey: "created", Response: models.Transaction{},
+			},
+		},
+
+		//********** DELETE COMMANDS**********
+		versions.Route{
+			Method: http.MethodDelete, Path: "/portfolios/:id",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.DeletePortfolio(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Delete a portfolio and its transactions",
+				Uri: dtos.PortfolioIdDto{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+		versions.Route{
+			Method: http.MethodDelete, Path: "/portfolios/:id/transactions/:transactionId",
+			Middleware: p.middleware(),
+			Handler: func(c *gin.Context) {
+				portfolio.DeleteTransaction(c, *p.portfolioRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "portfolios", Summary: "Delete a transaction",
+				Uri: dtos.PortfolioTransactionUriDto{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+	)
+}
@@ -1,26 +1,111 @@
 package routing
 
 import (
+	"context"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/calendar"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/events"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/notify"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/realtime"
 	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/admin"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/alerts"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/digests"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/docs"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/market"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/portfolios"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/sockets"
 	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/stocks"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/watchlists"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/webhooks"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/alert"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/digest"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stream"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/webhook"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/tickers"
 	integration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
 	"github.com/gin-gonic/gin"
 	"github.com/labstack/gommon/log"
+	"slices"
+	"strings"
+	"time"
 )
 
-func NewRouter(stockRepo *repository.StockRepository) error {
+// defaults for when the unversioned routes were deprecated and will be removed
+const (
+	defaultDeprecatedSince = "2026-10-19"
+	defaultSunset          = "2027-04-19"
+)
+
+// Repositories the data access the handlers are built from
+type Repositories struct {
+	Stocks     *repository.StockRepository
+	ApiKeys    *repository.ApiKeyRepository
+	Watchlists *repository.WatchlistRepository
+	Portfolios *repository.PortfolioRepository
+	Alerts     *repository.AlertRepository
+	Webhooks   *repository.WebhookRepository
+	Digests    *repository.DigestRepository
+	Splits     *repository.SplitRepository
+}
+
+// api the router and the shared pieces its background jobs are started with
+type api struct {
+	router   *gin.Engine
+	document *openapi.Document
+	feed     *stream.Feed
+	hub      *realtime.Hub
+}
+
+func NewRouter(repos Repositories) error {
 	log.Info("api starting up...")
-	router := gin.Default()
 
 	log.Info("connecting to polygon api...")
 	polyClient := integration.ConnectToPolygonApi()
 
-	//Stock Controller
-	stockHandler := stocks.SetUpStockHandler(stockRepo, polyClient)
-	stockHandler.RegisterRoutes(router)
+	app, err := buildApi(repos, polyClient)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	router := app.router
+
+	//Background jobs, events published on the bus are written to the webhook outbox for the worker to send
+	outbox := webhook.NewOutbox(repos.Webhooks)
+	outbox.Start(context.Background())
+	events.Shared.Subscribe(outbox.Enqueue)
+	webhook.NewWorker(repos.Webhooks).Start(context.Background())
+
+	//holidays and early closes, every date worked out from the last trading session depends on it
+	calendar.Shared.Start(context.Background(), polyClient)
+
+	//every active stock for search, until it's loaded searches go to polygon
+	tickers.Shared.Start(context.Background(), polyClient)
+
+	//splits of held tickers, stored prices are adjusted to match polygon's split adjusted bars
+	stock.NewSplitAdjuster(repos.Splits, polyClient).Start(context.Background())
+
+	alertEvaluator := alert.NewEvaluator(repos.Alerts, polyClient)
+	alertEvaluator.Subscribe(alert.PublishTrigger)
+	alertEvaluator.Start(context.Background())
+
+	app.feed.Start(context.Background())
+
+	//the websocket feed needs a polygon plan that includes it
+	if Current().Realtime.Enabled {
+		app.hub.Start(context.Background())
+	}
+
+	digest.NewJob(repos.Stocks, repos.Alerts, repos.Digests, polyClient, notify.NewNotifier()).Start(context.Background())
 
 	server := "localhost:8080"
-	err := router.Run(server)
+	err = router.Run(server)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -30,3 +115,118 @@ func NewRouter(stockRepo *repository.StockRepository) error {
 
 	return nil
 }
+
+// buildApi registers every route and builds the openapi document from them, nothing is started so the
+// routes can be checked against the document without a database or polygon
+func buildApi(repos Repositories, polyClient *integration.PolygonApi) (*api, error) {
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(requestLog), gin.Recovery())
+
+	//bearer tokens are tried first, api keys are for service to service callers
+	var authenticators []auth.Authenticator
+	if jwtAuthenticator := auth.NewJwtAuthenticator(); jwtAuthenticator != nil {
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+	authenticators = append(authenticators, auth.NewApiKeyAuthenticator(repos.ApiKeys))
+	authenticate := auth.Middleware(authenticators...)
+
+	limiter := ratelimit.NewLimiter()
+
+	//X-Forwarded-For is only believed from configured proxies, otherwise the client ip could be spoofed
+	if err := router.SetTrustedProxies(Current().ApiSettings.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	//every request is limited per client ip before it's authenticated, route groups then limit per caller
+	router.Use(limiter.LimitClient())
+
+	deprecation, err := unversionedDeprecation()
+	if err != nil {
+		return nil, err
+	}
+
+	//v1, a later version should start from v1.Extend("v2") and only Handle or Remove the routes that change
+	v1 := versions.New("v1")
+
+	//Realtime hub, one polygon websocket connection fanned out to every local listener
+	hub := realtime.NewHub()
+
+	//Stock Controller, the feed is shared by every price stream
+	feed := stream.NewFeed(polyClient)
+	stockHandler := stocks.SetUpStockHandler(repos.Stocks, polyClient, feed, authenticate, limiter)
+	stockHandler.RegisterRoutes(v1)
+
+	//Socket Controller, fed by the same feed and hub as the streams
+	socketHandler := sockets.SetUpSocketHandler(feed, hub, authenticate, limiter)
+	socketHandler.RegisterRoutes(v1)
+
+	//Market Controller, status comes from the market calendar
+	marketHandler := market.SetUpMarketHandler(authenticate, limiter)
+	marketHandler.RegisterRoutes(v1)
+
+	//Watchlist Controller
+	watchlistHandler := watchlists.SetUpWatchlistHandler(repos.Watchlists, polyClient, authenticate, limiter)
+	watchlistHandler.RegisterRoutes(v1)
+
+	//Portfolio Controller
+	portfolioHandler := portfolios.SetUpPortfolioHandler(repos.Portfolios, polyClient, authenticate, limiter)
+	portfolioHandler.RegisterRoutes(v1)
+
+	//Alert Controller
+	alertHandler := alerts.SetUpAlertHandler(repos.Alerts, polyClient, authenticate, limiter)
+	alertHandler.RegisterRoutes(v1)
+
+	//Webhook Controller
+	webhookHandler := webhooks.SetUpWebhookHandler(repos.Webhooks, authenticate, limiter)
+	webhookHandler.RegisterRoutes(v1)
+
+	//Digest Controller
+	digestHandler := digests.SetUpDigestHandler(repos.Digests, authenticate, limiter)
+	digestHandler.RegisterRoutes(v1)
+
+	//Admin Controller
+	adminHandler := admin.SetUpAdminHandler(repos.ApiKeys, authenticate, limiter)
+	adminHandler.RegisterRoutes(v1)
+
+	//the original unversioned paths stay as deprecated aliases of v1 until the sunset date
+	operations := slices.Concat(v1.Mount(router), v1.MountDeprecatedAlias(router, deprecation))
+
+	//Docs, routes_test checks every registered route is in the document
+	document := openapi.Build("GoApi", "1.0.0", auth.ApiKeyHeader(), operations)
+	docsHandler := docs.SetUpDocsHandler(document)
+	docsHandler.RegisterRoutes(router)
+
+	return &api{router: router, document: document, feed: feed, hub: hub}, nil
+}
+
+// unversionedDeprecation reads the deprecation and sunset dates for the unversioned routes
+func unversionedDeprecation() (versions.Deprecation, error) {
+	versioning := Current().Versioning
+	since, sunset := versioning.DeprecatedSince, versioning.Sunset
+	if since == "" {
+		since = defaultDeprecatedSince
+	}
+	if sunset == "" {
+		sunset = defaultSunset
+	}
+
+	sinceDate, err := time.Parse(time.DateOnly, since)
+	if err != nil {
+		return versions.Deprecation{}, err
+	}
+	sunsetDate, err := time.Parse(time.DateOnly, sunset)
+	if err != nil {
+		return versions.Deprecation{}, err
+	}
+
+	return versions.Deprecation{Since: sinceDate, Sunset: sunsetDate}, nil
+}
+
+// requestLog gin's request log line without the query string, unsubscribe links and the like carry tokens in it
+func requestLog(param gin.LogFormatterParams) string {
+	path, _, _ := strings.Cut(param.Path, "?")
+
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"), param.StatusCode, param.Latency, param.ClientIP,
+		param.Method, path, param.ErrorMessage)
+}
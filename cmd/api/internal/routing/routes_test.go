@@ -0,0 +1,53 @@
+package routing
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/docs"
+	integration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestEveryRouteIsDocumented fails when a route is registered without being in the openapi document, or
+// the document describes a route that no longer exists
+func TestEveryRouteIsDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	app, err := buildApi(Repositories{}, integration.ConnectToPolygonApi())
+	if err != nil {
+		t.Fatalf("building the api: %s", err)
+	}
+
+	if err := app.document.Verify(app.router.Routes(), docs.Paths...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDocsServeEmbeddedSwaggerUi the docs page shouldn't depend on anything outside the api
+func TestDocsServeEmbeddedSwaggerUi(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	app, err := buildApi(Repositories{}, integration.ConnectToPolygonApi())
+	if err != nil {
+		t.Fatalf("building the api: %s", err)
+	}
+
+	page := httptest.NewRecorder()
+	app.router.ServeHTTP(page, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if page.Code != http.StatusOK {
+		t.Fatalf("/docs got %d, want 200", page.Code)
+	}
+	if body := page.Body.String(); strings.Contains(body, "://") {
+		t.Fatalf("/docs loads something from another host: %s", body)
+	}
+
+	for _, asset := range []string{"swagger-ui.css", "swagger-ui-bundle.js", "favicon-32x32.png"} {
+		response := httptest.NewRecorder()
+		app.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/docs/assets/"+asset, nil))
+		if response.Code != http.StatusOK || response.Body.Len() == 0 {
+			t.Fatalf("%s got %d with %d bytes, want it served", asset, response.Code, response.Body.Len())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package sockets
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/realtime"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stream"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SocketHandler struct {
+	feed         *stream.Feed
+	hub          *realtime.Hub
+	authenticate gin.HandlerFunc
+	limiter      *ratelimit.Limiter
+}
+
+func SetUpSocketHandler(feed *stream.Feed, hub *realtime.Hub, authenticate gin.HandlerFunc,
+	limiter *ratelimit.Limiter) *SocketHandler {
+	return &SocketHandler{
+		feed:         feed,
+		hub:          hub,
+		authenticate: authenticate,
+		limiter:      limiter,
+	}
+}
+
+func (s *SocketHandler) RegisterRoutes(version *versions.Version) {
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			//browsers pass their token as a subprotocol, new WebSocket(url, ["goapi", "bearer." + token])
+			Method: http.MethodGet, Path: "/ws",
+			Middleware: []gin.HandlerFunc{
+				auth.ProtocolToken(),
+				s.authenticate,
+				s.limiter.Limit(ratelimit.GroupStocks),
+				auth.RequireScope(auth.ScopeMarketData),
+			},
+			Handler: func(c *gin.Context) {
+				stream.ServeSocket(c, s.feed, s.hub)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Open a websocket, send {action, channel, tickers} to subscribe to prices, trades, quotes, aggregates or alerts",
+				Status: http.StatusSwitchingProtocols, Response: models.SocketFrame{},
+			},
+		},
+	)
+}
@@ -1,51 +1,199 @@
 package stocks
 
 import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/features"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
 	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
 	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stream"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
 	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
 	"github.com/gin-gonic/gin"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"slices"
 )
 
 type StockHandler struct {
-	stockRepo  *repository.StockRepository
-	polyClient *intergration.PolygonApi
+	stockRepo    *repository.StockRepository
+	polyClient   *intergration.PolygonApi
+	feed         *stream.Feed
+	authenticate gin.HandlerFunc
+	limiter      *ratelimit.Limiter
 }
 
-func SetUpStockHandler(repo *repository.StockRepository, polyClient *intergration.PolygonApi) *StockHandler {
+func SetUpStockHandler(repo *repository.StockRepository, polyClient *intergration.PolygonApi, feed *stream.Feed,
+	authenticate gin.HandlerFunc, limiter *ratelimit.Limiter) *StockHandler {
 	return &StockHandler{
-		stockRepo:  repo,
-		polyClient: polyClient,
+		stockRepo:    repo,
+		polyClient:   polyClient,
+		feed:         feed,
+		authenticate: authenticate,
+		limiter:      limiter,
 	}
 }
 
-func (s *StockHandler) RegisterRoutes(router *gin.Engine) {
-	stockHandler := router.Group("/stocks")
-	{
+// middleware every stock route runs, followed by any route specific middleware
+func (s *StockHandler) middleware(extra ...gin.HandlerFunc) []gin.HandlerFunc {
+	return slices.Concat([]gin.HandlerFunc{s.authenticate, s.limiter.Limit(ratelimit.GroupStocks)}, extra)
+}
+
+func (s *StockHandler) RegisterRoutes(version *versions.Version) {
+	marketData := auth.RequireScope(auth.ScopeMarketData)
+	favourites := auth.RequireScope(auth.ScopeFavourites)
+
+	version.Handle(
 		//********** GET COMMANDS**********
-		stockHandler.GET("info/tickerdetails", func(c *gin.Context) {
-			stock.GetTickerDetails(c, s.polyClient)
-		})
-		stockHandler.GET("daily/openclose", func(c *gin.Context) {
-			stock.GetFavouriteStocksOpenClose(c, *s.stockRepo, s.polyClient)
-		})
-		stockHandler.GET("daily/changeFromYesterday", func(c *gin.Context) {
-			stock.GetPreviousDayClose(c, s.polyClient)
-		})
-		stockHandler.GET("indicators/sma", func(c *gin.Context) {
-			stock.GetSimpleMovingAverage(c, s.polyClient)
-		})
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/info/tickerdetails",
+			Middleware: s.middleware(marketData),
+			Handler: func(c *gin.Context) {
+				stock.GetTickerDetails(c, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Get details for a ticker",
+				Query: dtos.TickerDetailsDto{}, ResponseKey: "data", Response: polyModels.GetTickerDetailsResponse{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/search",
+			Middleware: s.middleware(marketData),
+			Handler: func(c *gin.Context) {
+				stock.SearchTickers(c, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Search tickers by the start of their symbol or company name",
+				Query: dtos.SearchTickersDto{}, ResponseKey: "data", Response: []models.TickerSummary{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/news",
+			Middleware: s.middleware(marketData, features.Require(features.News)),
+			Handler: func(c *gin.Context) {
+				stock.GetTickerNews(c, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Get news articles mentioning a ticker, newest first and paged with next_cursor",
+				Query: dtos.TickerNewsDto{}, ResponseKey: "data", Response: models.NewsPage{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/dividends",
+			Middleware: s.middleware(marketData, features.Require(features.CorporateActions)),
+			Handler: func(c *gin.Context) {
+				stock.GetDividends(c, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Get a ticker's cash dividends, newest ex-dividend date first",
+				Query: dtos.CorporateActionsDto{}, ResponseKey: "data", Response: []models.Dividend{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/splits",
+			Middleware: s.middleware(marketData, features.Require(features.CorporateActions)),
+			Handler: func(c *gin.Context) {
+				stock.GetSplits(c, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Get a ticker's stock splits, newest first",
+				Query: dtos.CorporateActionsDto{}, ResponseKey: "data", Response: []models.Split{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/favourites/news",
+			Middleware: s.middleware(favourites, features.Require(features.News)),
+			Handler: func(c *gin.Context) {
+				stock.GetFavouritesNews(c, *s.stockRepo, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "favourites", Summary: "Get news articles mentioning any of the caller's favourites, newest first and paged with next_cursor",
+				Query: dtos.FavouritesNewsDto{}, ResponseKey: "data", Response: models.NewsPage{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/daily/openclose",
+			Middleware: s.middleware(favourites, s.limiter.Limit(ratelimit.GroupOpenClose)),
+			Handler: func(c *gin.Context) {
+				stock.GetFavouriteStocksOpenClose(c, *s.stockRepo, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "favourites", Summary: "Get yesterday's open and close for the caller's favourite tickers",
+				Query: dtos.GetFavouriteStocksOpenCloseDto{}, ResponseKey: "data", Response: []polyModels.GetDailyOpenCloseAggResponse{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/daily/changeFromYesterday",
+			Middleware: s.middleware(marketData),
+			Handler: func(c *gin.Context) {
+				stock.GetPreviousDayClose(c, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Get the previous day's close for a ticker",
+				Query: dtos.PreviousCloseRequestDto{}, ResponseKey: "data", Response: polyModels.GetPreviousCloseAggResponse{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/indicators/sma",
+			Middleware: s.middleware(marketData, features.Require(features.Indicators)),
+			Handler: func(c *gin.Context) {
+				stock.GetSimpleMovingAverage(c, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Get the simple moving average for a ticker",
+				Query: dtos.SimpleMovingAverageDto{}, ResponseKey: "success", Response: dtos.MovingAverageDto{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/stream",
+			Middleware: s.middleware(marketData),
+			Handler: func(c *gin.Context) {
+				stream.StreamTickers(c, s.feed)
+			},
+			Doc: openapi.Operation{
+				Tag: "stocks", Summary: "Stream price updates for tickers as server-sent events, resumable with Last-Event-ID",
+				Query: dtos.StreamTickersDto{}, Response: models.PriceUpdate{}, ContentType: "text/event-stream",
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/stocks/favourites/stream",
+			Middleware: s.middleware(favourites),
+			Handler: func(c *gin.Context) {
+				stream.StreamFavourites(c, *s.stockRepo, s.feed)
+			},
+			Doc: openapi.Operation{
+				Tag: "favourites", Summary: "Stream price updates for the caller's favourites as server-sent events, resumable with Last-Event-ID",
+				Query: dtos.StreamFavouritesDto{}, Response: models.PriceUpdate{}, ContentType: "text/event-stream",
+			},
+		},
 
 		//********** POST/PUT/PATCH COMMANDS **********
-		stockHandler.POST("/favourites/add", func(c *gin.Context) {
-			stock.FavouriteTicker(c, *s.stockRepo)
-		})
+		versions.Route{
+			Method: http.MethodPost, Path: "/stocks/favourites/add",
+			Middleware: s.middleware(favourites),
+			Handler: func(c *gin.Context) {
+				stock.FavouriteTicker(c, *s.stockRepo, s.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "favourites", Summary: "Add a ticker to the caller's favourites",
+				Body: models.FavouriteStock{}, Status: http.StatusCreated, ResponseKey: "created", Response: "",
+			},
+		},
 
 		//********** DELETE COMMANDS**********
-		stockHandler.DELETE("/favourites/delete", func(c *gin.Context) {
-			stock.UnFavouriteTicker(c, *s.stockRepo)
-		})
-
-	}
-
+		versions.Route{
+			Method: http.MethodDelete, Path: "/stocks/favourites/delete",
+			Middleware: s.middleware(favourites),
+			Handler: func(c *gin.Context) {
+				stock.UnFavouriteTicker(c, *s.stockRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "favourites", Summary: "Remove a ticker from the caller's favourites",
+				Query: models.FavouriteStock{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+	)
 }
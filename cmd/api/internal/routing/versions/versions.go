@@ -0,0 +1,135 @@
+package versions
+
+import (
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// Route a single endpoint, Path is relative to the version prefix e.g. /stocks/info/tickerdetails.
+// Doc's Method and Path are filled in when the route is mounted
+type Route struct {
+	Method     string
+	Path       string
+	Middleware []gin.HandlerFunc
+	Handler    gin.HandlerFunc
+	Doc        openapi.Operation
+}
+
+// Version the routes served under one api version. A later version can Extend an earlier one and
+// then Handle or Remove individual routes, everything else is reused as is
+type Version struct {
+	name   string
+	order  []string
+	routes map[string]Route
+}
+
+// Deprecation the headers sent on a deprecated mount of a version
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+}
+
+func New(name string) *Version {
+	return &Version{
+		name:   name,
+		routes: make(map[string]Route),
+	}
+}
+
+func (v *Version) Name() string {
+	return v.name
+}
+
+// Prefix the path the version is mounted under, e.g. /v1
+func (v *Version) Prefix() string {
+	return "/" + v.name
+}
+
+// Handle adds routes to the version, a route with the same method and path replaces the existing one
+func (v *Version) Handle(routes ...Route) {
+	for _, route := range routes {
+		key := routeKey(route.Method, route.Path)
+		if _, exists := v.routes[key]; !exists {
+			v.order = append(v.order, key)
+		}
+		v.routes[key] = route
+	}
+}
+
+// Remove drops a route so it isn't served by this version
+func (v *Version) Remove(method string, path string) {
+	key := routeKey(method, path)
+	delete(v.routes, key)
+
+	for i, existing := range v.order {
+		if existing == key {
+			v.order = append(v.order[:i], v.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Extend starts a new version with every route of this one
+func (v *Version) Extend(name string) *Version {
+	next := New(name)
+	for _, key := range v.order {
+		next.Handle(v.routes[key])
+	}
+	return next
+}
+
+// Mount registers the version under its prefix and returns the documented operations
+func (v *Version) Mount(router *gin.Engine) []openapi.Operation {
+	return v.mount(router, v.Prefix(), nil)
+}
+
+// MountDeprecatedAlias registers the version without a prefix, every response carries Deprecation and
+// Sunset headers plus a link to the versioned route
+func (v *Version) MountDeprecatedAlias(router *gin.Engine, deprecation Deprecation) []openapi.Operation {
+	return v.mount(router, "", &deprecation)
+}
+
+func (v *Version) mount(router *gin.Engine, prefix string, deprecation *Deprecation) []openapi.Operation {
+	operations := make([]openapi.Operation, 0, len(v.order))
+
+	for _, key := range v.order {
+		route := v.routes[key]
+		path := prefix + route.Path
+
+		var handlers []gin.HandlerFunc
+		if deprecation != nil {
+			handlers = append(handlers, deprecationHeaders(*deprecation, v.Prefix()))
+		}
+		handlers = append(handlers, route.Middleware...)
+		handlers = append(handlers, route.Handler)
+
+		router.Handle(route.Method, path, handlers...)
+
+		doc := route.Doc
+		doc.Method = route.Method
+		doc.Path = path
+		doc.Deprecated = deprecation != nil
+		operations = append(operations, doc)
+	}
+
+	return operations
+}
+
+func deprecationHeaders(deprecation Deprecation, successorPrefix string) gin.HandlerFunc {
+	deprecated := fmt.Sprintf("@%d", deprecation.Since.Unix())
+	sunset := deprecation.Sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecated)
+		c.Header("Sunset", sunset)
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
+
+func routeKey(method string, path string) string {
+	return method + " " + path
+}
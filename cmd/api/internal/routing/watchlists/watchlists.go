@@ -0,0 +1,161 @@
+package watchlists
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/watchlist"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/gin-gonic/gin"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"slices"
+)
+
+type WatchlistHandler struct {
+	watchlistRepo *repository.WatchlistRepository
+	polyClient    *intergration.PolygonApi
+	authenticate  gin.HandlerFunc
+	limiter       *ratelimit.Limiter
+}
+
+func SetUpWatchlistHandler(repo *repository.WatchlistRepository, polyClient *intergration.PolygonApi,
+	authenticate gin.HandlerFunc, limiter *ratelimit.Limiter) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlistRepo: repo,
+		polyClient:    polyClient,
+		authenticate:  authenticate,
+		limiter:       limiter,
+	}
+}
+
+// middleware every watchlist route runs, followed by any route specific middleware
+func (w *WatchlistHandler) middleware(extra ...gin.HandlerFunc) []gin.HandlerFunc {
+	return slices.Concat([]gin.HandlerFunc{w.authenticate, w.limiter.Limit(ratelimit.GroupStocks),
+		auth.RequireScope(auth.ScopeFavourites)}, extra)
+}
+
+func (w *WatchlistHandler) RegisterRoutes(version *versions.Version) {
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			Method: http.MethodGet, Path: "/watchlists",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.ListWatchlists(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "List the caller's watchlists",
+				Query: dtos.ListWatchlistsDto{}, ResponseKey: "data", Response: []models.Watchlist{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/watchlists/:id",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.GetWatchlist(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Get a watchlist",
+				Uri: dtos.WatchlistIdDto{}, ResponseKey: "data", Response: models.Watchlist{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/watchlists/:id/openclose",
+			Middleware: w.middleware(w.limiter.Limit(ratelimit.GroupOpenClose)),
+			Handler: func(c *gin.Context) {
+				watchlist.GetWatchlistOpenClose(c, *w.watchlistRepo, w.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Get yesterday's open and close for every ticker in a watchlist",
+				Uri: dtos.WatchlistIdDto{}, ResponseKey: "data", Response: []polyModels.GetDailyOpenCloseAggResponse{},
+			},
+		},
+
+		//********** POST/PUT/PATCH COMMANDS **********
+		versions.Route{
+			Method: http.MethodPost, Path: "/watchlists",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.CreateWatchlist(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Create a watchlist",
+				Body: dtos.CreateWatchlistDto{}, Status: http.StatusCreated, ResponseKey: "created", Response: models.Watchlist{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodPatch, Path: "/watchlists/:id",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.RenameWatchlist(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Rename a watchlist",
+				Uri: dtos.WatchlistIdDto{}, Body: dtos.RenameWatchlistDto{}, ResponseKey: "updated", Response: "",
+			},
+		},
+		versions.Route{
+			Method: http.MethodPost, Path: "/watchlists/:id/tickers",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.AddTicker(c, *w.watchlistRepo, w.polyClient)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Add a ticker to the end of a watchlist",
+				Uri: dtos.WatchlistIdDto{}, Body: dtos.AddWatchlistTickerDto{}, Status: http.StatusCreated,
+				ResponseKey: "created", Response: "",
+			},
+		},
+		versions.Route{
+			Method: http.MethodPatch, Path: "/watchlists/:id/tickers/:ticker",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.UpdateTickerNote(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Set the note on a ticker in a watchlist",
+				Uri: dtos.WatchlistTickerUriDto{}, Body: dtos.WatchlistTickerNoteDto{}, ResponseKey: "updated", Response: "",
+			},
+		},
+		versions.Route{
+			Method: http.MethodPut, Path: "/watchlists/:id/order",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.ReorderTickers(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Reorder the tickers in a watchlist",
+				Uri: dtos.WatchlistIdDto{}, Body: dtos.ReorderWatchlistDto{}, ResponseKey: "updated", Response: []string{},
+			},
+		},
+
+		//********** DELETE COMMANDS**********
+		versions.Route{
+			Method: http.MethodDelete, Path: "/watchlists/:id",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.DeleteWatchlist(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Delete a watchlist, the default watchlist can't be deleted",
+				Uri: dtos.WatchlistIdDto{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+		versions.Route{
+			Method: http.MethodDelete, Path: "/watchlists/:id/tickers/:ticker",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				watchlist.RemoveTicker(c, *w.watchlistRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "watchlists", Summary: "Remove a ticker from a watchlist",
+				Uri: dtos.WatchlistTickerUriDto{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+	)
+}
@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/openapi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/ratelimit"
+	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing/versions"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/webhook"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+)
+
+type WebhookHandler struct {
+	webhookRepo  *repository.WebhookRepository
+	authenticate gin.HandlerFunc
+	limiter      *ratelimit.Limiter
+}
+
+func SetUpWebhookHandler(repo *repository.WebhookRepository, authenticate gin.HandlerFunc,
+	limiter *ratelimit.Limiter) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo:  repo,
+		authenticate: authenticate,
+		limiter:      limiter,
+	}
+}
+
+// middleware every webhook route runs, followed by any route specific middleware
+func (w *WebhookHandler) middleware(extra ...gin.HandlerFunc) []gin.HandlerFunc {
+	return slices.Concat([]gin.HandlerFunc{w.authenticate, w.limiter.Limit(ratelimit.GroupStocks),
+		auth.RequireScope(auth.ScopeWebhooks)}, extra)
+}
+
+func (w *WebhookHandler) RegisterRoutes(version *versions.Version) {
+	version.Handle(
+		//********** GET COMMANDS**********
+		versions.Route{
+			Method: http.MethodGet, Path: "/webhooks",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				webhook.ListWebhooks(c, *w.webhookRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "webhooks", Summary: "List the caller's webhooks",
+				Query: dtos.ListWebhooksDto{}, ResponseKey: "data", Response: []models.Webhook{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodGet, Path: "/webhooks/:id/deliveries",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				webhook.ListDeliveries(c, *w.webhookRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "webhooks", Summary: "List a webhook's deliveries newest first",
+				Uri: dtos.WebhookIdDto{}, Query: dtos.ListDeliveriesDto{}, ResponseKey: "data",
+				Response: []models.WebhookDelivery{},
+			},
+		},
+
+		//********** POST/PUT/PATCH COMMANDS **********
+		versions.Route{
+			Method: http.MethodPost, Path: "/webhooks",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				webhook.CreateWebhook(c, *w.webhookRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "webhooks", Summary: "Register an endpoint to be sent signed events, the secret is only returned here",
+				Body: dtos.CreateWebhookDto{}, Status: http.StatusCreated, ResponseKey: "created", Response: models.Webhook{},
+			},
+		},
+		versions.Route{
+			Method: http.MethodPost, Path: "/webhooks/:id/deliveries/:deliveryId/replay",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				webhook.ReplayDelivery(c, *w.webhookRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "webhooks", Summary: "Send a delivery again with a fresh set of attempts",
+				Uri: dtos.WebhookDeliveryUriDto{}, Status: http.StatusAccepted, ResponseKey: "updated",
+				Response: models.WebhookDelivery{},
+			},
+		},
+
+		//********** DELETE COMMANDS**********
+		versions.Route{
+			Method: http.MethodDelete, Path: "/webhooks/:id",
+			Middleware: w.middleware(),
+			Handler: func(c *gin.Context) {
+				webhook.DeleteWebhook(c, *w.webhookRepo)
+			},
+			Doc: openapi.Operation{
+				Tag: "webhooks", Summary: "Delete a webhook and its deliveries",
+				Uri: dtos.WebhookIdDto{}, ResponseKey: "deleted", Response: "",
+			},
+		},
+	)
+}
@@ -0,0 +1,14 @@
+// Package schedule works out when background work runs next and runs it: retries backing off after
+// failures, and jobs that run every interval or daily at a set time
+package schedule
+
+import "time"
+
+// Backoff doubles the wait after each failure, capped at maxBackoff. The first failure waits initialBackoff
+func Backoff(failures int, initialBackoff time.Duration, maxBackoff time.Duration) time.Duration {
+	wait := initialBackoff
+	for i := 1; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
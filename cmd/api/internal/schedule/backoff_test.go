@@ -0,0 +1,29 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{"no failures yet", 0, time.Second},
+		{"first failure", 1, time.Second},
+		{"doubles", 2, 2 * time.Second},
+		{"keeps doubling", 4, 8 * time.Second},
+		{"capped", 6, 30 * time.Second},
+		{"stays capped without overflowing", 1000, 30 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Backoff(test.failures, time.Second, 30*time.Second); got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"context"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+// Loop calls fn straight away and again each time the wait it returns has passed, until ctx is done. It
+// returns at once, fn runs on its own goroutine
+func Loop(ctx context.Context, fn func(ctx context.Context) time.Duration) {
+	go func() {
+		for {
+			wait := fn(ctx)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// NowAndEvery calls fn straight away and then every interval until ctx is done, interval is called before
+// each wait so reloads are picked up
+func NowAndEvery(ctx context.Context, interval func() time.Duration, fn func(ctx context.Context)) {
+	Loop(ctx, func(ctx context.Context) time.Duration {
+		fn(ctx)
+		return interval()
+	})
+}
+
+// Every calls fn each time interval has passed until ctx is done, the first call is after one interval.
+// interval is called before each wait so reloads are picked up
+func Every(ctx context.Context, interval func() time.Duration, fn func(ctx context.Context)) {
+	first := true
+	Loop(ctx, func(ctx context.Context) time.Duration {
+		if !first {
+			fn(ctx)
+		}
+		first = false
+		return interval()
+	})
+}
+
+// Daily calls fn straight away and then every day at the hh:mm UTC at returns until ctx is done. fn is
+// given the time it was due, the most recent at so a run missed while the api was down can be caught up.
+// at is called before each wait so reloads are picked up
+func Daily(ctx context.Context, at func() string, fn func(ctx context.Context, due time.Time)) {
+	Loop(ctx, func(ctx context.Context) time.Duration {
+		last, next := DailyTimes(time.Now().UTC(), at())
+		fn(ctx, last)
+		return time.Until(next)
+	})
+}
+
+// DailyTimes the most recent time at or before now a daily hh:mm UTC schedule was due and the next time
+// after now. An invalid at is logged and taken as midnight
+func DailyTimes(now time.Time, at string) (last time.Time, next time.Time) {
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		log.Errorf("invalid daily time %q, using midnight: %s", at, err)
+	}
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+	if now.Before(today) {
+		return today.AddDate(0, 0, -1), today
+	}
+	return today, today.AddDate(0, 0, 1)
+}
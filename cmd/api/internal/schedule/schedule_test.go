@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDailyTimes(t *testing.T) {
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		at       string
+		wantLast time.Time
+		wantNext time.Time
+	}{
+		{"before today's time", at(5, 6, 59), "07:00", at(4, 7, 0), at(5, 7, 0)},
+		{"at today's time", at(5, 7, 0), "07:00", at(5, 7, 0), at(6, 7, 0)},
+		{"after today's time", at(5, 18, 30), "07:00", at(5, 7, 0), at(6, 7, 0)},
+		{"minutes", at(5, 7, 10), "07:15", at(4, 7, 15), at(5, 7, 15)},
+		{"across a month", time.Date(2024, time.February, 29, 23, 0, 0, 0, time.UTC), "07:00",
+			time.Date(2024, time.February, 29, 7, 0, 0, 0, time.UTC), at(1, 7, 0)},
+		{"now outside utc", time.Date(2024, time.March, 5, 3, 0, 0, 0, time.FixedZone("EST", -5*60*60)), "07:00",
+			at(5, 7, 0), at(6, 7, 0)},
+		{"invalid time is midnight", at(5, 12, 0), "7am", at(5, 0, 0), at(6, 0, 0)},
+		{"out of range time is midnight", at(5, 12, 0), "25:00", at(5, 0, 0), at(6, 0, 0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			last, next := DailyTimes(test.now, test.at)
+			if !last.Equal(test.wantLast) || !next.Equal(test.wantNext) {
+				t.Fatalf("got %s and %s, want %s and %s", last, next, test.wantLast, test.wantNext)
+			}
+		})
+	}
+}
+
+func TestEvery(t *testing.T) {
+	const interval = 100 * time.Millisecond
+
+	tests := []struct {
+		name       string
+		start      func(ctx context.Context, interval func() time.Duration, fn func(ctx context.Context))
+		wantWaited []bool //whether each call came at least an interval after the one before, or the start
+	}{
+		{"now and every", NowAndEvery, []bool{false, true, true}},
+		{"every", Every, []bool{true, true, true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls := make(chan time.Time, len(test.wantWaited))
+			started := time.Now()
+			test.start(ctx, func() time.Duration { return interval }, func(ctx context.Context) {
+				select {
+				case calls <- time.Now():
+				default:
+				}
+			})
+
+			previous := started
+			for call, wantWaited := range test.wantWaited {
+				select {
+				case at := <-calls:
+					if waited := at.Sub(previous) >= interval; waited != wantWaited {
+						t.Fatalf("call %d came %s after the last, want waited %t", call+1, at.Sub(previous), wantWaited)
+					}
+					previous = at
+				case <-time.After(5 * time.Second):
+					t.Fatalf("got %d calls, want %d", call, len(test.wantWaited))
+				}
+			}
+		})
+	}
+}
+
+func TestLoopWaitsAsReturned(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := atomic.Int32{}
+	done := make(chan struct{})
+	Loop(ctx, func(ctx context.Context) time.Duration {
+		if calls.Add(1) == 3 {
+			close(done)
+			return time.Hour
+		}
+		return 0
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("got %d calls, want 3 straight after each other", calls.Load())
+	}
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("got %d calls, want 3 until the hour has passed", got)
+	}
+}
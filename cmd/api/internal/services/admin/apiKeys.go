@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"errors"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"time"
+)
+
+const defaultDailyQuota = 1000
+
+// IssueApiKey creates a new api key, the raw key is only returned in this response
+func IssueApiKey(c *gin.Context, keyDb ApiKeyRepository) {
+	ctx := c.Request.Context()
+	var request IssueApiKeyDto
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	for _, scope := range request.Scopes {
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"validation error": "unknown scope " + scope})
+			return
+		}
+	}
+
+	if request.DailyQuota == 0 {
+		request.DailyQuota = Current().Auth.DefaultDailyQuota
+		if request.DailyQuota == 0 {
+			request.DailyQuota = defaultDailyQuota
+		}
+	}
+
+	rawKey, prefix, hash, err := auth.GenerateApiKey()
+	if err != nil {
+		log.Errorf("error generating api key: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate api key"})
+		return
+	}
+
+	key := ApiKey{
+		Id:         uuid.NewString(),
+		Name:       request.Name,
+		Prefix:     prefix,
+		Hash:       hash,
+		OwnerId:    request.OwnerId,
+		Scopes:     request.Scopes,
+		DailyQuota: request.DailyQuota,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- keyDb.CreateApiKey(key, ctx)
+	}()
+
+	select {
+	case err := <-ch:
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"created": IssuedApiKeyDto{Key: rawKey, ApiKey: key}})
+}
+
+// ListApiKeys lists every issued key, hashes are never returned
+func ListApiKeys(c *gin.Context, keyDb ApiKeyRepository) {
+	ctx := c.Request.Context()
+
+	respChan := make(chan *Response[[]ApiKey], 1)
+	go func() {
+		keys := keyDb.ListApiKeys(ctx)
+		respChan <- &keys
+	}()
+
+	select {
+	case result := <-respChan:
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": result.Data})
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+}
+
+// RevokeApiKey revokes a key so it can no longer authenticate
+func RevokeApiKey(c *gin.Context, keyDb ApiKeyRepository) {
+	ctx := c.Request.Context()
+	var request ApiKeyIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- keyDb.RevokeApiKey(request.Id, ctx)
+	}()
+
+	select {
+	case err := <-ch:
+		if errors.Is(err, ErrApiKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no active api key with that id"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": request.Id})
+}
@@ -0,0 +1,22 @@
+package admin
+
+import (
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/features"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+// ListFeatureFlags lists every configured flag with whether it's on for the given user
+func ListFeatureFlags(c *gin.Context) {
+	var request FeatureFlagsDto
+
+	if err := c.ShouldBindQuery(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": features.EvaluateAll(request.UserId)})
+}
@@ -0,0 +1,210 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/respond"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/tickers"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"time"
+)
+
+// statuses the status each alert error is written with
+var statuses = respond.Statuses{
+	ErrAlertNotFound: http.StatusNotFound,
+	ErrTooManyAlerts: http.StatusConflict,
+}
+
+// ListAlerts lists the caller's alerts
+func ListAlerts(c *gin.Context, alertDb AlertRepository) {
+	ctx := c.Request.Context()
+	var params ListAlertsDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respChan := make(chan *Response[[]Alert], 1)
+	go func() {
+		alerts := alertDb.ListAlerts(userId, ctx)
+		respChan <- &alerts
+	}()
+
+	select {
+	case result := <-respChan:
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": result.Data})
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+}
+
+// CreateAlert registers an alert for the caller, users can have up to alerts.maxPerUser
+func CreateAlert(c *gin.Context, alertDb AlertRepository, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var request CreateAlertDto
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := validateAlert(request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, request.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	ticker, ok := tickers.ValidTicker(c, pa, request.Ticker)
+	if !ok {
+		return
+	}
+
+	settings := configuration.Current().Alerts
+	cooldown := int(time.Duration(settings.DefaultCooldown).Minutes())
+	if request.CooldownMinutes != nil {
+		cooldown = *request.CooldownMinutes
+	}
+
+	alert := Alert{
+		Id:              uuid.NewString(),
+		UserId:          userId,
+		Ticker:          ticker,
+		Condition:       request.Condition,
+		Threshold:       request.Threshold,
+		Window:          request.Window,
+		CooldownMinutes: cooldown,
+		Active:          true,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	if !respond.Run(c, statuses, func() error { return alertDb.CreateAlert(alert, settings.MaxPerUser, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"created": alert})
+}
+
+// ListTriggers lists when one of the caller's alerts fired, newest first
+func ListTriggers(c *gin.Context, alertDb AlertRepository) {
+	ctx := c.Request.Context()
+	var request AlertIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedAlert(c, alertDb, request.Id); !ok {
+		return
+	}
+
+	respChan := make(chan *Response[[]AlertTrigger], 1)
+	go func() {
+		triggers := alertDb.ListTriggers(request.Id, ctx)
+		respChan <- &triggers
+	}()
+
+	select {
+	case result := <-respChan:
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": result.Data})
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+}
+
+// DeleteAlert deletes one of the caller's alerts and its trigger history
+func DeleteAlert(c *gin.Context, alertDb AlertRepository) {
+	ctx := c.Request.Context()
+	var request AlertIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedAlert(c, alertDb, request.Id); !ok {
+		return
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- alertDb.DeleteAlert(request.Id, ctx)
+	}()
+
+	select {
+	case err := <-ch:
+		if errors.Is(err, ErrAlertNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": request.Id})
+}
+
+// validateAlert checks the fields the condition needs were given
+func validateAlert(request CreateAlertDto) error {
+	switch request.Condition {
+	case AlertPriceAbove, AlertPriceBelow, AlertPercentMove:
+		if request.Threshold <= 0 {
+			return fmt.Errorf("%s needs a threshold greater than 0", request.Condition)
+		}
+	case AlertSmaCrossAbove, AlertSmaCrossBelow:
+		if request.Window == 0 {
+			return fmt.Errorf("%s needs a window", request.Condition)
+		}
+	}
+	return nil
+}
+
+// ownedAlert loads the alert and checks the caller owns it, admins can use anyone's. Someone else's alert
+// is reported as not found. The error response has been written when ok is false
+func ownedAlert(c *gin.Context, alertDb AlertRepository, id string) (*Alert, bool) {
+	return respond.Owned(c, statuses, ErrAlertNotFound, func(ctx context.Context) Response[*Alert] {
+		return alertDb.GetAlert(id, ctx)
+	}, func(alert *Alert) string { return alert.UserId })
+}
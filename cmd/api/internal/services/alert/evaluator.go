@@ -0,0 +1,176 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/events"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/processing"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"sync"
+	"time"
+)
+
+// Evaluator checks every active alert on a schedule. Tickers are batched so each is fetched once per
+// run however many alerts use it
+type Evaluator struct {
+	alertDb   *AlertRepository
+	processor *stockConcurrency.PolyDataProcessor
+
+	subscribersMu sync.RWMutex
+	subscribers   []func(alert Alert, trigger AlertTrigger)
+}
+
+func NewEvaluator(alertDb *AlertRepository, pa *intergration.PolygonApi) *Evaluator {
+	return &Evaluator{
+		alertDb:   alertDb,
+		processor: stockConcurrency.NewPolyDataProcessor(pa, 10),
+	}
+}
+
+// Subscribe registers fn to run every time an alert triggers
+func (e *Evaluator) Subscribe(fn func(alert Alert, trigger AlertTrigger)) {
+	e.subscribersMu.Lock()
+	defer e.subscribersMu.Unlock()
+
+	e.subscribers = append(e.subscribers, fn)
+}
+
+// Start evaluates the alerts every alerts.evaluationInterval until ctx is done, the interval is read
+// before each wait so reloads are picked up
+func (e *Evaluator) Start(ctx context.Context) {
+	schedule.Every(ctx, func() time.Duration {
+		return time.Duration(configuration.Current().Alerts.EvaluationInterval)
+	}, func(ctx context.Context) {
+		if err := e.Evaluate(ctx); err != nil {
+			log.Errorf("alert evaluation failed: %s", err)
+		}
+	})
+}
+
+// Evaluate checks every active alert once against the latest daily bars
+func (e *Evaluator) Evaluate(ctx context.Context) error {
+	active := e.alertDb.ListActiveAlerts(ctx)
+	if active.Error != nil {
+		return active.Error
+	}
+	if len(active.Data) == 0 {
+		return nil
+	}
+
+	var tickers []string
+	var smaKeys []stockConcurrency.SmaKey
+	seenTickers := make(map[string]bool)
+	seenSmas := make(map[stockConcurrency.SmaKey]bool)
+
+	for _, alert := range active.Data {
+		if !seenTickers[alert.Ticker] {
+			seenTickers[alert.Ticker] = true
+			tickers = append(tickers, alert.Ticker)
+		}
+
+		key := stockConcurrency.SmaKey{Ticker: alert.Ticker, Window: alert.Window}
+		if isSmaCondition(alert.Condition) && !seenSmas[key] {
+			seenSmas[key] = true
+			smaKeys = append(smaKeys, key)
+		}
+	}
+
+	bars, barErrs := e.processor.FetchPreviousCloses(ctx, tickers)
+	smas, smaErrs := e.processor.FetchLatestSmas(ctx, smaKeys)
+
+	for ticker, err := range barErrs {
+		log.Errorf("skipping alerts on %s, couldn't get the latest bar: %s", ticker, err)
+	}
+	for key, err := range smaErrs {
+		log.Errorf("skipping %d day sma alerts on %s, couldn't get the sma: %s", key.Window, key.Ticker, err)
+	}
+
+	triggered := 0
+	for _, alert := range active.Data {
+		bar, ok := bars[alert.Ticker]
+		if !ok {
+			continue
+		}
+
+		sma, ok := smas[stockConcurrency.SmaKey{Ticker: alert.Ticker, Window: alert.Window}]
+		if isSmaCondition(alert.Condition) && !ok {
+			continue
+		}
+
+		evaluation := processing.EvaluateAlert(alert, bar.Open, bar.Close, sma)
+		barDate := time.Time(bar.Timestamp).UTC()
+
+		if !evaluation.Triggered || inCooldown(alert) {
+			if evaluation.State != alert.LastState {
+				if err := e.alertDb.UpdateAlertState(alert.Id, evaluation.State, ctx); err != nil {
+					log.Errorf("error saving state of alert %s: %s", alert.Id, err)
+				}
+			}
+			continue
+		}
+
+		trigger := AlertTrigger{
+			Id:          uuid.NewString(),
+			AlertId:     alert.Id,
+			BarDate:     time.Date(barDate.Year(), barDate.Month(), barDate.Day(), 0, 0, 0, 0, time.UTC),
+			Price:       bar.Close,
+			Value:       evaluation.Value,
+			Message:     evaluation.Message,
+			TriggeredAt: time.Now().UTC(),
+		}
+
+		err := e.alertDb.RecordTrigger(trigger, evaluation.State, ctx)
+		if errors.Is(err, ErrAlertAlreadyTriggered) {
+			continue
+		}
+		if err != nil {
+			log.Errorf("error recording trigger of alert %s: %s", alert.Id, err)
+			continue
+		}
+
+		triggered++
+		log.Infof("alert %s triggered: %s", alert.Id, trigger.Message)
+		e.notify(alert, trigger)
+	}
+
+	log.Infof("evaluated %d alerts across %d tickers, %d triggered", len(active.Data), len(tickers), triggered)
+	return nil
+}
+
+// PublishTrigger publishes the trigger on the shared event bus, subscribe it to an Evaluator
+func PublishTrigger(alert Alert, trigger AlertTrigger) {
+	events.Shared.Publish(events.New(events.AlertTriggered, alert.UserId, struct {
+		Alert   Alert        `json:"alert"`
+		Trigger AlertTrigger `json:"trigger"`
+	}{alert, trigger}))
+}
+
+func (e *Evaluator) notify(alert Alert, trigger AlertTrigger) {
+	e.subscribersMu.RLock()
+	defer e.subscribersMu.RUnlock()
+
+	for _, subscriber := range e.subscribers {
+		subscriber(alert, trigger)
+	}
+}
+
+// inCooldown an alert that triggered within its cooldown can't trigger again yet
+func inCooldown(alert Alert) bool {
+	if alert.LastTriggeredAt == nil {
+		return false
+	}
+
+	cooldown := time.Duration(alert.CooldownMinutes) * time.Minute
+	return time.Since(*alert.LastTriggeredAt) < cooldown
+}
+
+func isSmaCondition(condition string) bool {
+	return condition == AlertSmaCrossAbove || condition == AlertSmaCrossBelow
+}
@@ -0,0 +1,34 @@
+package alert
+
+import (
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"testing"
+	"time"
+)
+
+func TestInCooldown(t *testing.T) {
+	ago := func(d time.Duration) *time.Time {
+		at := time.Now().Add(-d)
+		return &at
+	}
+
+	tests := []struct {
+		name  string
+		alert Alert
+		want  bool
+	}{
+		{"never triggered", Alert{CooldownMinutes: 60}, false},
+		{"triggered inside the cooldown", Alert{CooldownMinutes: 60, LastTriggeredAt: ago(30 * time.Minute)}, true},
+		{"triggered just before the cooldown ends", Alert{CooldownMinutes: 60, LastTriggeredAt: ago(59 * time.Minute)}, true},
+		{"cooldown over", Alert{CooldownMinutes: 60, LastTriggeredAt: ago(61 * time.Minute)}, false},
+		{"no cooldown", Alert{CooldownMinutes: 0, LastTriggeredAt: ago(time.Second)}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := inCooldown(test.alert); got != test.want {
+				t.Fatalf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
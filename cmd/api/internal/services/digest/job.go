@@ -0,0 +1,207 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/notify"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sendTimeout how long sending a single digest can take
+const sendTimeout = 30 * time.Second
+
+// Job emails every subscribed user a digest of their favourites' last session and the alerts that fired
+// since their previous digest, once a day at digest.sendAt
+type Job struct {
+	stockDb   FavouriteStore
+	alertDb   TriggerStore
+	digestDb  SubscriptionStore
+	processor OpenCloseFetcher
+	notifier  notify.Notifier
+}
+
+// FavouriteStore where the job reads each user's favourites from
+type FavouriteStore interface {
+	GetFavouriteTickers(id string, ctx context.Context) Response[[]string]
+}
+
+// TriggerStore where the job reads the alerts that fired from
+type TriggerStore interface {
+	ListUserTriggersSince(userId string, since time.Time, ctx context.Context) Response[[]AlertTrigger]
+}
+
+// SubscriptionStore where the job finds who's due a digest and records it was sent
+type SubscriptionStore interface {
+	ListDueSubscriptions(before time.Time, ctx context.Context) Response[[]DigestSubscription]
+	MarkSent(userId string, sentAt time.Time, ctx context.Context) error
+}
+
+// OpenCloseFetcher gets the favourites' prices, tickers that failed come back as errors
+type OpenCloseFetcher interface {
+	FetchOpenClose(ctx context.Context, tickers []string) ([]*polyModels.GetDailyOpenCloseAggResponse, []error)
+}
+
+func NewJob(stockDb FavouriteStore, alertDb TriggerStore, digestDb SubscriptionStore, pa *intergration.PolygonApi,
+	notifier notify.Notifier) *Job {
+	return &Job{
+		stockDb:   stockDb,
+		alertDb:   alertDb,
+		digestDb:  digestDb,
+		processor: stockConcurrency.NewPolyDataProcessor(pa, 10),
+		notifier:  notifier,
+	}
+}
+
+// Start sends the digests until ctx is done. Anyone missed while the api was down is caught up on start,
+// digest.sendAt is read before each wait so reloads are picked up
+func (j *Job) Start(ctx context.Context) {
+	schedule.Daily(ctx, func() string {
+		return configuration.Current().Digest.SendAt
+	}, func(ctx context.Context, due time.Time) {
+		if err := j.Send(ctx, due); err != nil {
+			log.Errorf("sending digests failed: %s", err)
+		}
+	})
+}
+
+// Send emails a digest to every subscribed user who hasn't had one since scheduledAt. Prices for every
+// favourite across the users are fetched together so a ticker is only fetched once
+func (j *Job) Send(ctx context.Context, scheduledAt time.Time) error {
+	due := j.digestDb.ListDueSubscriptions(scheduledAt, ctx)
+	if due.Error != nil {
+		return due.Error
+	}
+	if len(due.Data) == 0 {
+		return nil
+	}
+
+	favourites := make(map[string][]string, len(due.Data))
+	var tickers []string
+	seen := make(map[string]bool)
+	for _, subscription := range due.Data {
+		userFavourites := j.stockDb.GetFavouriteTickers(subscription.UserId, ctx)
+		if userFavourites.Error != nil {
+			log.Errorf("couldn't get favourites for %s's digest: %s", subscription.UserId, userFavourites.Error)
+			continue
+		}
+
+		favourites[subscription.UserId] = userFavourites.Data
+		for _, ticker := range userFavourites.Data {
+			if !seen[ticker] {
+				seen[ticker] = true
+				tickers = append(tickers, ticker)
+			}
+		}
+	}
+
+	openCloses := make(map[string]*polyModels.GetDailyOpenCloseAggResponse, len(tickers))
+	if len(tickers) > 0 {
+		responses, errs := j.processor.FetchOpenClose(ctx, tickers)
+		for _, response := range responses {
+			openCloses[strings.ToUpper(response.Symbol)] = response
+		}
+		if len(errs) > 0 {
+			log.Warnf("%d of %d tickers couldn't be priced for the digest", len(errs), len(tickers))
+		}
+	}
+
+	sent := 0
+	for _, subscription := range due.Data {
+		userFavourites, ok := favourites[subscription.UserId]
+		if !ok {
+			continue
+		}
+
+		if err := j.sendOne(ctx, subscription, userFavourites, openCloses, scheduledAt); err != nil {
+			log.Errorf("couldn't send %s their digest: %s", subscription.UserId, err)
+			continue
+		}
+		sent++
+	}
+
+	log.Infof("sent %d of %d digests", sent, len(due.Data))
+	return nil
+}
+
+func (j *Job) sendOne(ctx context.Context, subscription DigestSubscription, favourites []string,
+	openCloses map[string]*polyModels.GetDailyOpenCloseAggResponse, scheduledAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	since := scheduledAt.AddDate(0, 0, -1)
+	if subscription.LastSentAt != nil {
+		since = *subscription.LastSentAt
+	}
+
+	alerts := j.alertDb.ListUserTriggersSince(subscription.UserId, since, ctx)
+	if alerts.Error != nil {
+		return alerts.Error
+	}
+
+	unsubscribeUrl := fmt.Sprintf("%s/v1/digest/unsubscribe?token=%s",
+		strings.TrimSuffix(configuration.Current().Digest.BaseUrl, "/"), url.QueryEscape(subscription.UnsubscribeToken))
+
+	digest := Digest{
+		Email:          subscription.Email,
+		Date:           scheduledAt,
+		Tickers:        digestTickers(favourites, openCloses),
+		Alerts:         alerts.Data,
+		UnsubscribeUrl: unsubscribeUrl,
+	}
+
+	text, html, err := notify.Render("digest", digest)
+	if err != nil {
+		return err
+	}
+
+	err = j.notifier.Send(ctx, notify.Message{
+		To:      subscription.Email,
+		Subject: fmt.Sprintf("Your daily digest for %s", scheduledAt.Format("2 January 2006")),
+		Text:    text,
+		Html:    html,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeUrl + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click", //rfc 8058, mail clients post to the link
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return j.digestDb.MarkSent(subscription.UserId, time.Now().UTC(), ctx)
+}
+
+// digestTickers the favourites in order with their moves over the session, open to close
+func digestTickers(favourites []string, openCloses map[string]*polyModels.GetDailyOpenCloseAggResponse) []DigestTicker {
+	tickers := make([]DigestTicker, 0, len(favourites))
+
+	for _, ticker := range favourites {
+		openClose, ok := openCloses[strings.ToUpper(ticker)]
+		if !ok {
+			tickers = append(tickers, DigestTicker{Ticker: ticker, Error: "prices unavailable"})
+			continue
+		}
+
+		digestTicker := DigestTicker{
+			Ticker: ticker,
+			Open:   openClose.Open,
+			Close:  openClose.Close,
+			Change: openClose.Close - openClose.Open,
+		}
+		if openClose.Open != 0 {
+			digestTicker.ChangePercent = digestTicker.Change / openClose.Open * 100
+		}
+		tickers = append(tickers, digestTicker)
+	}
+
+	return tickers
+}
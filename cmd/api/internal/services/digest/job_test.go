@@ -0,0 +1,216 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/notify"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/notify/notifytest"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSettings = `{
+	"connectionStrings": {"stocksDb": "test"},
+	"apiSettings": {"key": "test"},
+	"smtp": {"host": "127.0.0.1", "port": %d, "from": "GoApi <digest@goapi.test>"},
+	"digest": {"baseUrl": "https://goapi.test/"}
+}`
+
+// memoryStore the subscriptions, favourites and alert triggers in memory, queries mirror the repositories
+type memoryStore struct {
+	subscriptions []DigestSubscription
+	favourites    map[string][]string
+	triggers      map[string][]AlertTrigger
+	sent          map[string]bool
+}
+
+func (m *memoryStore) ListDueSubscriptions(before time.Time, ctx context.Context) Response[[]DigestSubscription] {
+	var due []DigestSubscription
+	for _, subscription := range m.subscriptions {
+		if subscription.Subscribed && (subscription.LastSentAt == nil || subscription.LastSentAt.Before(before)) {
+			due = append(due, subscription)
+		}
+	}
+	return Response[[]DigestSubscription]{Data: due}
+}
+
+func (m *memoryStore) MarkSent(userId string, sentAt time.Time, ctx context.Context) error {
+	m.sent[userId] = true
+	return nil
+}
+
+func (m *memoryStore) GetFavouriteTickers(id string, ctx context.Context) Response[[]string] {
+	return Response[[]string]{Data: m.favourites[id]}
+}
+
+func (m *memoryStore) ListUserTriggersSince(userId string, since time.Time, ctx context.Context) Response[[]AlertTrigger] {
+	var triggers []AlertTrigger
+	for _, trigger := range m.triggers[userId] {
+		if trigger.TriggeredAt.After(since) {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return Response[[]AlertTrigger]{Data: triggers}
+}
+
+// fixedPrices open/closes by ticker, any other ticker fails
+type fixedPrices map[string][2]float64
+
+func (f fixedPrices) FetchOpenClose(ctx context.Context, tickers []string) ([]*polyModels.GetDailyOpenCloseAggResponse, []error) {
+	var responses []*polyModels.GetDailyOpenCloseAggResponse
+	var errs []error
+	for _, ticker := range tickers {
+		prices, ok := f[ticker]
+		if !ok {
+			errs = append(errs, errors.New("no prices for "+ticker))
+			continue
+		}
+		responses = append(responses, &polyModels.GetDailyOpenCloseAggResponse{Symbol: ticker, Open: prices[0], Close: prices[1]})
+	}
+	return responses, errs
+}
+
+func TestSend(t *testing.T) {
+	server := notifytest.NewSmtpServer(t)
+
+	dir := t.TempDir()
+	settings := []byte(fmt.Sprintf(testSettings, server.Port()))
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), settings, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(day int, hour int) time.Time {
+		return time.Date(2024, time.March, day, hour, 0, 0, 0, time.UTC)
+	}
+	trigger := func(day int, hour int, message string) AlertTrigger {
+		return AlertTrigger{Message: message, TriggeredAt: at(day, hour)}
+	}
+	scheduledAt := at(5, 7)
+	aliceSent, carolSent := at(4, 18), at(5, 8)
+
+	store := &memoryStore{
+		subscriptions: []DigestSubscription{
+			{UserId: "alice", Email: "alice@goapi.test", Subscribed: true, UnsubscribeToken: "alice-token", LastSentAt: &aliceSent},
+			{UserId: "bob", Email: "bob@goapi.test", Subscribed: true, UnsubscribeToken: "bob-token"},
+			{UserId: "carol", Email: "carol@goapi.test", Subscribed: true, UnsubscribeToken: "carol-token", LastSentAt: &carolSent},
+			{UserId: "dave", Email: "dave@goapi.test", Subscribed: false, UnsubscribeToken: "dave-token"},
+		},
+		favourites: map[string][]string{
+			"alice": {"AAPL", "MSFT"},
+			"bob":   {"TSLA"},
+			"carol": {"AAPL"},
+			"dave":  {"AAPL"},
+		},
+		triggers: map[string][]AlertTrigger{
+			"alice": {trigger(4, 12, "AAPL fired before alice's last digest"), trigger(4, 20, "AAPL fired after alice's last digest")},
+			"bob":   {trigger(3, 12, "TSLA fired two days ago"), trigger(4, 9, "TSLA fired since yesterday's digest")},
+		},
+		sent: make(map[string]bool),
+	}
+	job := &Job{
+		stockDb:   store,
+		alertDb:   store,
+		digestDb:  store,
+		processor: fixedPrices{"AAPL": {100, 102.5}, "TSLA": {200, 190}},
+		notifier:  notify.NewNotifier(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := job.Send(ctx, scheduledAt); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(map[string]string)
+	for len(received) < 2 {
+		select {
+		case message := <-server.Received:
+			to := strings.TrimSuffix(strings.TrimPrefix(message.To, "RCPT TO:<"), ">")
+			received[to] = textPart(t, message.Data)
+		case <-ctx.Done():
+			t.Fatalf("got digests for %d users, want 2", len(received))
+		}
+	}
+	if len(server.Received) != 0 {
+		t.Fatal("a digest went to someone who wasn't due one")
+	}
+
+	tests := []struct {
+		name    string
+		to      string
+		want    []string
+		notWant []string
+	}{
+		{"favourite with prices", "alice@goapi.test", []string{"AAPL: open 100.00, close 102.50 (+2.50, +2.50%)"}, nil},
+		{"favourite without prices", "alice@goapi.test", []string{"MSFT: prices unavailable"}, nil},
+		{"alerts since the last digest", "alice@goapi.test", []string{"4 Mar 20:00 UTC: AAPL fired after alice's last digest"},
+			[]string{"before alice's last digest"}},
+		{"unsubscribe link", "alice@goapi.test", []string{"Unsubscribe: https://goapi.test/v1/digest/unsubscribe?token=alice-token"}, nil},
+		{"falling favourite", "bob@goapi.test", []string{"TSLA: open 200.00, close 190.00 (-10.00, -5.00%)"}, []string{"AAPL"}},
+		{"first digest covers the day before", "bob@goapi.test", []string{"TSLA fired since yesterday's digest"},
+			[]string{"two days ago"}},
+		{"date", "bob@goapi.test", []string{"Your daily digest for Tuesday 5 March 2024"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text, ok := received[test.to]
+			if !ok {
+				t.Fatalf("no digest for %s", test.to)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("digest doesn't have %q:\n%s", want, text)
+				}
+			}
+			for _, notWant := range test.notWant {
+				if strings.Contains(text, notWant) {
+					t.Errorf("digest has %q:\n%s", notWant, text)
+				}
+			}
+		})
+	}
+
+	if !store.sent["alice"] || !store.sent["bob"] || store.sent["carol"] || store.sent["dave"] {
+		t.Fatalf("marked sent %v, want alice and bob", store.sent)
+	}
+}
+
+// textPart the decoded plain text body of the message
+func textPart(t *testing.T, data string) string {
+	t.Helper()
+
+	message, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := multipart.NewReader(message.Body, params["boundary"]).NextRawPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := io.ReadAll(quotedprintable.NewReader(part))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(text)
+}
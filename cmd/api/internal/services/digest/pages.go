@@ -0,0 +1,29 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"github.com/gin-gonic/gin"
+	"html/template"
+	"net/http"
+)
+
+//go:embed pages
+var pageFiles embed.FS
+
+var pages = template.Must(template.ParseFS(pageFiles, "pages/*.html"))
+
+// unsubscribePage the page behind the digest's unsubscribe link, a confirmation until it's submitted
+type unsubscribePage struct {
+	Token        string
+	Unsubscribed bool
+}
+
+func renderPage(c *gin.Context, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := pages.ExecuteTemplate(&buf, name, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
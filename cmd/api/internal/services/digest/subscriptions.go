@@ -0,0 +1,164 @@
+package digest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/respond"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"time"
+)
+
+// statuses the status each digest error is written with
+var statuses = respond.Statuses{
+	ErrDigestSubscriptionNotFound: http.StatusNotFound,
+}
+
+// GetSubscription gets the caller's digest subscription
+func GetSubscription(c *gin.Context, digestDb DigestRepository) {
+	ctx := c.Request.Context()
+	var params DigestSubscriptionDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, ok := respond.Fetch(c, statuses, func(ctx context.Context) Response[*DigestSubscription] {
+		return digestDb.GetSubscription(userId, ctx)
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": subscription})
+}
+
+// Subscribe opts the caller in to the daily digest at email, calling it again changes the email
+func Subscribe(c *gin.Context, digestDb DigestRepository) {
+	ctx := c.Request.Context()
+	var request SubscribeDigestDto
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, request.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := newUnsubscribeToken()
+	if err != nil {
+		log.Errorf("error generating unsubscribe token: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription := DigestSubscription{
+		UserId:           userId,
+		Email:            request.Email,
+		Subscribed:       true,
+		UnsubscribeToken: token,
+		CreatedAt:        time.Now().UTC(),
+	}
+
+	if !respond.Run(c, statuses, func() error { return digestDb.Subscribe(subscription, ctx) }) {
+		return
+	}
+
+	saved, ok := respond.Fetch(c, statuses, func(ctx context.Context) Response[*DigestSubscription] {
+		return digestDb.GetSubscription(userId, ctx)
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": saved})
+}
+
+// Unsubscribe opts the caller out of the daily digest
+func Unsubscribe(c *gin.Context, digestDb DigestRepository) {
+	ctx := c.Request.Context()
+	var params DigestSubscriptionDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !respond.Run(c, statuses, func() error { return digestDb.Unsubscribe(userId, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": "unsubscribed from the daily digest"})
+}
+
+// ConfirmUnsubscribe the page the digest's unsubscribe link opens, it only asks for confirmation as mail
+// scanners and link previews follow links in emails
+func ConfirmUnsubscribe(c *gin.Context) {
+	var params UnsubscribeDigestDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	renderPage(c, http.StatusOK, "unsubscribe.html", unsubscribePage{Token: params.Token})
+}
+
+// UnsubscribeByToken opts out whoever the digest's unsubscribe link was sent to, no sign in needed. It's
+// posted by the confirmation page and by mail clients' one-click unsubscribe, browsers are shown a page
+func UnsubscribeByToken(c *gin.Context, digestDb DigestRepository) {
+	ctx := c.Request.Context()
+	var params UnsubscribeDigestDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if !respond.Run(c, statuses, func() error { return digestDb.UnsubscribeByToken(params.Token, ctx) }) {
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		renderPage(c, http.StatusOK, "unsubscribe.html", unsubscribePage{Unsubscribed: true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": "unsubscribed from the daily digest"})
+}
+
+// newUnsubscribeToken a random token for the unsubscribe link
+func newUnsubscribeToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
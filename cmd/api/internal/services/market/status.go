@@ -0,0 +1,14 @@
+package market
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/calendar"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// GetStatus whether US equities are open, pre-market, after-hours or closed and when the regular session
+// next opens and closes. Worked out from the market calendar so it doesn't cost a polygon request
+func GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": calendar.Shared.Status(time.Now())})
+}
@@ -0,0 +1,192 @@
+package portfolio
+
+import (
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/calendar"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/processing"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GetPerformance works out the portfolio's returns and risk between from and to from its transactions and
+// each day's close. Holdings are valued with unadjusted closes as split transactions already adjust the
+// quantities, the benchmark uses adjusted closes. Tickers whose bars couldn't be fetched are listed in
+// FailedTickers, holdings are then valued at their last trade price and a missing benchmark leaves beta and
+// the benchmark return at 0
+func GetPerformance(c *gin.Context, portfolioDb PortfolioRepository, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var uri PortfolioIdDto
+	var params PortfolioPerformanceDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	//nothing after the last session to have closed has a bar yet
+	lastSession := calendar.Shared.LastSession(time.Now()).Date
+	if params.To.IsZero() || params.To.After(lastSession) {
+		params.To = lastSession
+	}
+	if !params.From.Before(params.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": "from must be before to"})
+		return
+	}
+
+	analytics := configuration.Current().Analytics
+	benchmark := strings.ToUpper(strings.TrimSpace(params.Benchmark))
+	if benchmark == "" {
+		benchmark = analytics.BenchmarkTicker
+	}
+
+	portfolio, ok := ownedPortfolio(c, portfolioDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	transactions, ok := portfolioTransactions(c, portfolioDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	//only what had happened by the end of the period counts
+	endOfPeriod := params.To.AddDate(0, 0, 1)
+	var inPeriod []Transaction
+	var tickers []string
+	seen := make(map[string]bool)
+	for _, transaction := range transactions {
+		if !transaction.ExecutedAt.Before(endOfPeriod) {
+			continue
+		}
+		inPeriod = append(inPeriod, transaction)
+		if !seen[transaction.Ticker] {
+			seen[transaction.Ticker] = true
+			tickers = append(tickers, transaction.Ticker)
+		}
+	}
+
+	processor := stockConcurrency.NewPolyDataProcessor(pa, 10)
+	holdingBars, errs := processor.FetchDailyBars(ctx, tickers, params.From, params.To, false)
+	benchmarkBars, benchmarkErrs := processor.FetchDailyBars(ctx, []string{benchmark}, params.From, params.To, true)
+	for ticker, err := range benchmarkErrs {
+		errs["benchmark "+ticker] = err
+	}
+
+	if ctx.Err() != nil {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+	if len(errs) > 0 {
+		failed := make(map[string]string, len(errs))
+		for ticker, err := range errs {
+			failed[ticker] = err.Error()
+		}
+
+		//with no bars at all there's nothing to work out, otherwise holdings without bars are valued at their
+		//last trade price and the tickers are listed in the response
+		if len(errs) == len(tickers)+1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily bars", "tickers": failed})
+			return
+		}
+		log.Warnf("portfolio %s performance is missing daily bars: %v", portfolio.Id, failed)
+	}
+
+	closes := closePrices(holdingBars)
+	benchmarkCloses := closePrices(benchmarkBars)[benchmark]
+
+	days := processing.TradingDays(closes, params.From, params.To)
+	if len(days) < 2 {
+		days = processing.TradingDays(map[string][]processing.ClosePrice{benchmark: benchmarkCloses}, params.From, params.To)
+	}
+	if len(days) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least two trading days are needed between from and to"})
+		return
+	}
+
+	values, flows := processing.ValueSeries(inPeriod, closes, days)
+	returns := processing.DailyReturns(values, flows)
+	benchmarkReturns := processing.PriceReturns(benchmarkCloses, days)
+
+	performance := PortfolioPerformance{
+		PortfolioId:          portfolio.Id,
+		From:                 days[0],
+		To:                   days[len(days)-1],
+		TradingDays:          len(days),
+		Benchmark:            benchmark,
+		RiskFreeRate:         analytics.RiskFreeRate,
+		StartValue:           values[0],
+		EndValue:             values[len(values)-1],
+		TimeWeightedReturn:   processing.TimeWeightedReturn(returns),
+		AnnualisedVolatility: processing.AnnualisedVolatility(returns),
+		SharpeRatio:          processing.SharpeRatio(returns, analytics.RiskFreeRate),
+		SortinoRatio:         processing.SortinoRatio(returns, analytics.RiskFreeRate),
+		MaxDrawdown:          processing.MaxDrawdown(returns),
+		Beta:                 processing.Beta(returns, benchmarkReturns),
+		BenchmarkReturn:      processing.TimeWeightedReturn(benchmarkReturns),
+		FailedTickers:        []string{},
+	}
+	for ticker := range errs {
+		performance.FailedTickers = append(performance.FailedTickers, ticker)
+	}
+	slices.Sort(performance.FailedTickers)
+	for _, flow := range flows {
+		performance.NetFlows += flow
+	}
+
+	if mwr, err := processing.MoneyWeightedReturn(cashFlows(days, values, flows)); err == nil {
+		performance.MoneyWeightedReturn = &mwr
+	} else {
+		log.Infof("no money-weighted return for portfolio %s: %s", portfolio.Id, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": performance})
+}
+
+// cashFlows the investor's view of the period: the starting value paid in, flows during the period and
+// the ending value taken out
+func cashFlows(days []time.Time, values []float64, flows []float64) []processing.CashFlow {
+	last := len(days) - 1
+	cashFlows := []processing.CashFlow{{Day: days[0], Amount: -values[0]}}
+
+	for i := 1; i <= last; i++ {
+		if flows[i] != 0 {
+			cashFlows = append(cashFlows, processing.CashFlow{Day: days[i], Amount: -flows[i]})
+		}
+	}
+
+	return append(cashFlows, processing.CashFlow{Day: days[last], Amount: values[last]})
+}
+
+// closePrices the daily closes keyed by ticker, bars are stamped at the start of the trading day in New
+// York which is still the same date in UTC
+func closePrices(bars map[string][]polyModels.Agg) map[string][]processing.ClosePrice {
+	closes := make(map[string][]processing.ClosePrice, len(bars))
+
+	for ticker, tickerBars := range bars {
+		prices := make([]processing.ClosePrice, 0, len(tickerBars))
+		for _, bar := range tickerBars {
+			stamp := time.Time(bar.Timestamp).UTC()
+			day := time.Date(stamp.Year(), stamp.Month(), stamp.Day(), 0, 0, 0, 0, time.UTC)
+			prices = append(prices, processing.ClosePrice{Day: day, Close: bar.Close})
+		}
+		closes[ticker] = prices
+	}
+
+	return closes
+}
@@ -0,0 +1,418 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/processing"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/respond"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errInvalidTransactions the portfolio's transactions can't be replayed, e.g. a sell of more than is held
+var errInvalidTransactions = errors.New("invalid transactions")
+
+// statuses the status each portfolio error is written with
+var statuses = respond.Statuses{
+	ErrPortfolioNotFound:   http.StatusNotFound,
+	ErrTransactionNotFound: http.StatusNotFound,
+	ErrPortfolioExists:     http.StatusConflict,
+	errInvalidTransactions: http.StatusBadRequest,
+}
+
+// ListPortfolios lists the caller's portfolios
+func ListPortfolios(c *gin.Context, portfolioDb PortfolioRepository) {
+	ctx := c.Request.Context()
+	var params ListPortfoliosDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respChan := make(chan *Response[[]Portfolio], 1)
+	go func() {
+		portfolios := portfolioDb.ListPortfolios(userId, ctx)
+		respChan <- &portfolios
+	}()
+
+	select {
+	case result := <-respChan:
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": result.Data})
+
+	case <-ctx.Done():
+		respond.Timeout(c)
+		return
+	}
+}
+
+// GetPortfolio gets a portfolio the caller owns with its positions
+func GetPortfolio(c *gin.Context, portfolioDb PortfolioRepository) {
+	var request PortfolioIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	portfolio, ok := ownedPortfolio(c, portfolioDb, request.Id)
+	if !ok {
+		return
+	}
+
+	positions, ok := portfolioPositions(c, portfolioDb, *portfolio)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"portfolio": portfolio, "positions": positions}})
+}
+
+// CreatePortfolio creates an empty portfolio for the caller
+func CreatePortfolio(c *gin.Context, portfolioDb PortfolioRepository) {
+	ctx := c.Request.Context()
+	var request CreatePortfolioDto
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, request.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.CostBasis == "" {
+		request.CostBasis = CostBasisFifo
+	}
+
+	portfolio := Portfolio{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Name:      strings.TrimSpace(request.Name),
+		CostBasis: request.CostBasis,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if !respond.Run(c, statuses, func() error { return portfolioDb.CreatePortfolio(portfolio, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"created": portfolio})
+}
+
+// UpdatePortfolio renames a portfolio or changes its cost basis method
+func UpdatePortfolio(c *gin.Context, portfolioDb PortfolioRepository) {
+	ctx := c.Request.Context()
+	var uri PortfolioIdDto
+	var request UpdatePortfolioDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	portfolio, ok := ownedPortfolio(c, portfolioDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	if name := strings.TrimSpace(request.Name); name != "" {
+		portfolio.Name = name
+	}
+	if request.CostBasis != "" {
+		portfolio.CostBasis = request.CostBasis
+	}
+
+	if !respond.Run(c, statuses, func() error { return portfolioDb.UpdatePortfolio(*portfolio, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": portfolio})
+}
+
+// DeletePortfolio deletes one of the caller's portfolios and its transactions
+func DeletePortfolio(c *gin.Context, portfolioDb PortfolioRepository) {
+	ctx := c.Request.Context()
+	var request PortfolioIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedPortfolio(c, portfolioDb, request.Id); !ok {
+		return
+	}
+
+	if !respond.Run(c, statuses, func() error { return portfolioDb.DeletePortfolio(request.Id, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": request.Id})
+}
+
+// ListTransactions lists a portfolio's transactions oldest first
+func ListTransactions(c *gin.Context, portfolioDb PortfolioRepository) {
+	var request PortfolioIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedPortfolio(c, portfolioDb, request.Id); !ok {
+		return
+	}
+
+	transactions, ok := portfolioTransactions(c, portfolioDb, request.Id)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": transactions})
+}
+
+// AddTransaction records a transaction, it's rejected if the portfolio's history no longer adds up
+// with it e.g. selling more than is held at the time
+func AddTransaction(c *gin.Context, portfolioDb PortfolioRepository) {
+	ctx := c.Request.Context()
+	var uri PortfolioIdDto
+	var request AddTransactionDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := validateTransaction(request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	portfolio, ok := ownedPortfolio(c, portfolioDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	transaction := Transaction{
+		Id:          uuid.NewString(),
+		PortfolioId: uri.Id,
+		Ticker:      strings.ToUpper(strings.TrimSpace(request.Ticker)),
+		Type:        request.Type,
+		Quantity:    request.Quantity,
+		Price:       request.Price,
+		Fees:        request.Fees,
+		Amount:      request.Amount,
+		Ratio:       request.Ratio,
+		ExecutedAt:  request.ExecutedAt.UTC(),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	existing, ok := portfolioTransactions(c, portfolioDb, uri.Id)
+	if !ok {
+		return
+	}
+	if _, err := processing.ComputePositions(append(existing, transaction), portfolio.CostBasis); err != nil {
+		statuses.Write(c, fmt.Errorf("%w: %w", errInvalidTransactions, err))
+		return
+	}
+
+	if !respond.Run(c, statuses, func() error { return portfolioDb.AddTransaction(transaction, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"created": transaction})
+}
+
+// DeleteTransaction removes a transaction, it's rejected if the remaining history no longer adds up
+func DeleteTransaction(c *gin.Context, portfolioDb PortfolioRepository) {
+	ctx := c.Request.Context()
+	var uri PortfolioTransactionUriDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	portfolio, ok := ownedPortfolio(c, portfolioDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	existing, ok := portfolioTransactions(c, portfolioDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	remaining := make([]Transaction, 0, len(existing))
+	for _, transaction := range existing {
+		if transaction.Id != uri.TransactionId {
+			remaining = append(remaining, transaction)
+		}
+	}
+	if len(remaining) == len(existing) {
+		statuses.Write(c, ErrTransactionNotFound)
+		return
+	}
+	if _, err := processing.ComputePositions(remaining, portfolio.CostBasis); err != nil {
+		statuses.Write(c, fmt.Errorf("%w: %w", errInvalidTransactions, err))
+		return
+	}
+
+	if !respond.Run(c, statuses, func() error { return portfolioDb.DeleteTransaction(uri.Id, uri.TransactionId, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": uri.TransactionId})
+}
+
+// GetValuation values every open position at the previous close, positions that can't be priced are
+// returned with a price_error and left out of the totals
+func GetValuation(c *gin.Context, portfolioDb PortfolioRepository, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var request PortfolioIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	portfolio, ok := ownedPortfolio(c, portfolioDb, request.Id)
+	if !ok {
+		return
+	}
+
+	positions, ok := portfolioPositions(c, portfolioDb, *portfolio)
+	if !ok {
+		return
+	}
+
+	var tickers []string
+	for _, position := range positions {
+		if position.Quantity > 0 {
+			tickers = append(tickers, position.Ticker)
+		}
+	}
+
+	processor := stockConcurrency.NewPolyDataProcessor(pa, 10)
+	bars, errs := processor.FetchPreviousCloses(ctx, tickers)
+
+	if ctx.Err() != nil {
+		respond.Timeout(c)
+		return
+	}
+
+	valuations := make([]PositionValuation, 0, len(positions))
+	for _, position := range positions {
+		if position.Quantity == 0 {
+			continue
+		}
+
+		if err, failed := errs[position.Ticker]; failed {
+			valuations = append(valuations, PositionValuation{Position: position, PriceError: err.Error()})
+			continue
+		}
+
+		bar := bars[position.Ticker]
+		valuations = append(valuations, processing.ValuePosition(position, bar.Open, bar.Close))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": PortfolioValuation{
+		PortfolioId: portfolio.Id,
+		CostBasis:   portfolio.CostBasis,
+		Positions:   valuations,
+		Totals:      processing.TotalValuation(valuations),
+	}})
+}
+
+// validateTransaction checks the fields the transaction's type needs were given
+func validateTransaction(request AddTransactionDto) error {
+	switch request.Type {
+	case TransactionBuy, TransactionSell:
+		if request.Quantity <= 0 {
+			return fmt.Errorf("%s needs a quantity greater than 0", request.Type)
+		}
+	case TransactionDividend:
+		if request.Amount <= 0 {
+			return errors.New("dividend needs an amount greater than 0")
+		}
+	case TransactionSplit:
+		if request.Ratio <= 0 {
+			return errors.New("split needs a ratio greater than 0")
+		}
+	}
+	return nil
+}
+
+// ownedPortfolio loads the portfolio and checks the caller owns it, admins can use anyone's. Someone
+// else's portfolio is reported as not found. The error response has been written when ok is false
+func ownedPortfolio(c *gin.Context, portfolioDb PortfolioRepository, id string) (*Portfolio, bool) {
+	return respond.Owned(c, statuses, ErrPortfolioNotFound, func(ctx context.Context) Response[*Portfolio] {
+		return portfolioDb.GetPortfolio(id, ctx)
+	}, func(portfolio *Portfolio) string { return portfolio.UserId })
+}
+
+func portfolioTransactions(c *gin.Context, portfolioDb PortfolioRepository, id string) ([]Transaction, bool) {
+	return respond.Fetch(c, statuses, func(ctx context.Context) Response[[]Transaction] {
+		return portfolioDb.ListTransactions(id, ctx)
+	})
+}
+
+// portfolioPositions replays the portfolio's transactions with its cost basis method
+func portfolioPositions(c *gin.Context, portfolioDb PortfolioRepository, portfolio Portfolio) ([]Position, bool) {
+	transactions, ok := portfolioTransactions(c, portfolioDb, portfolio.Id)
+	if !ok {
+		return nil, false
+	}
+
+	positions, err := processing.ComputePositions(transactions, portfolio.CostBasis)
+	if err != nil {
+		log.Errorf("portfolio %s has invalid transactions: %s", portfolio.Id, err)
+		statuses.Write(c, fmt.Errorf("%w: %w", errInvalidTransactions, err))
+		return nil, false
+	}
+
+	return positions, true
+}
@@ -0,0 +1,99 @@
+// Package respond the request plumbing shared by the service handlers: running work off the request
+// goroutine, loading a resource the caller has to own and writing errors with the right status
+package respond
+
+import (
+	"context"
+	"errors"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Statuses the status each of a package's errors is written with, an error matching none of them is a 500.
+// The errors are matched with errors.Is so each should be distinct
+type Statuses map[error]int
+
+// Write writes err with the status of the error in s that it wraps
+func (s Statuses) Write(c *gin.Context, err error) {
+	for target, status := range s {
+		if errors.Is(err, target) {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// Timeout writes the response for a request that was cancelled or ran out of time
+func Timeout(c *gin.Context) {
+	c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+}
+
+// Run calls fn off the request goroutine, the error response has been written when it returns false
+func Run(c *gin.Context, statuses Statuses, fn func() error) bool {
+	ctx := c.Request.Context()
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- fn()
+	}()
+
+	select {
+	case err := <-ch:
+		if err != nil {
+			statuses.Write(c, err)
+			return false
+		}
+		return true
+
+	case <-ctx.Done():
+		Timeout(c)
+		return false
+	}
+}
+
+// Fetch calls load off the request goroutine and returns what it loaded, the error response has been
+// written when ok is false
+func Fetch[T any](c *gin.Context, statuses Statuses, load func(ctx context.Context) Response[T]) (T, bool) {
+	ctx := c.Request.Context()
+
+	respChan := make(chan *Response[T], 1)
+	go func() {
+		result := load(ctx)
+		respChan <- &result
+	}()
+
+	select {
+	case result := <-respChan:
+		if result.Error != nil {
+			statuses.Write(c, result.Error)
+			var zero T
+			return zero, false
+		}
+		return result.Data, true
+
+	case <-ctx.Done():
+		Timeout(c)
+		var zero T
+		return zero, false
+	}
+}
+
+// Owned loads a resource and checks the caller owns it, owner gives the id of the user it belongs to and
+// admins can use anyone's. Someone else's resource is reported as notFound so callers can't tell it
+// exists. The error response has been written when ok is false
+func Owned[T any](c *gin.Context, statuses Statuses, notFound error, load func(ctx context.Context) Response[*T],
+	owner func(*T) string) (*T, bool) {
+	resource, ok := Fetch(c, statuses, load)
+	if !ok {
+		return nil, false
+	}
+
+	if _, err := auth.ResolveUserId(c.Request.Context(), owner(resource)); err != nil {
+		statuses.Write(c, notFound)
+		return nil, false
+	}
+	return resource, true
+}
@@ -2,9 +2,9 @@ package stockConcurrency
 
 import (
 	"context"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/calendar"
 	"github.com/RobsonDevCode/GoApi/cmd/api/models"
 	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
-	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
 	"github.com/labstack/gommon/log"
 	polyModels "github.com/polygon-io/client-go/rest/models"
 
@@ -12,10 +12,6 @@ import (
 	"time"
 )
 
-var polyConfig = Settings{
-	Yesterday: time.Now().AddDate(0, 0, -1),
-}
-
 // PolyDataProcessor handles concurrent processing of stock data from or to the polygon api
 type PolyDataProcessor struct {
 	api            *intergration.PolygonApi
@@ -45,6 +41,30 @@ func (p *PolyDataProcessor) ProcessTickersConcurrently(ctx context.Context, tick
 	return resultCh, nil
 }
 
+// FetchOpenClose processes the tickers concurrently and collects the responses, tickers that failed come
+// back as errors
+func (p *PolyDataProcessor) FetchOpenClose(ctx context.Context, tickers []string) ([]*polyModels.GetDailyOpenCloseAggResponse, []error) {
+	resultCh, err := p.ProcessTickersConcurrently(ctx, tickers)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var response []*polyModels.GetDailyOpenCloseAggResponse
+	var errs []error
+
+	for resp := range resultCh {
+		if resp.Error != nil {
+			errs = append(errs, resp.Error)
+			log.Error(resp.Error)
+			continue
+		}
+
+		response = append(response, resp.Data)
+	}
+
+	return response, errs
+}
+
 // spanWorkerPool creates the worker pool with rate limiting
 func (p *PolyDataProcessor) spanWorkerPool(ctx context.Context, wg *sync.WaitGroup,
 	resultCh chan<- models.Response[*polyModels.GetDailyOpenCloseAggResponse], tickers []string) {
@@ -69,7 +89,9 @@ func (p *PolyDataProcessor) processTickerWorker(ctx context.Context, ticker stri
 
 	log.Infof("Processing ticker %s", ticker)
 
-	response := p.api.FetchTickerOpenClose(ticker, polyConfig.Yesterday, ctx)
+	//the open/close of the latest session to have finished, not the calendar day before
+	lastSession := calendar.Shared.LastSession(time.Now())
+	response := p.api.FetchTickerOpenClose(ticker, lastSession.Date, ctx)
 
 	select {
 	case resultCh <- response:
@@ -0,0 +1,159 @@
+package stockConcurrency
+
+import (
+	"context"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/calendar"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"sync"
+	"time"
+)
+
+// FetchPreviousCloses gets the previous day's bar for each ticker concurrently, tickers that failed are
+// returned in errs instead
+func (p *PolyDataProcessor) FetchPreviousCloses(ctx context.Context, tickers []string) (map[string]polyModels.Agg, map[string]error) {
+	return fanOut(ctx, p.maxParallelism, tickers, func(ticker string) (polyModels.Agg, error) {
+		response := p.api.FetchPreviousClose(dtos.PreviousCloseRequestDto{Ticker: ticker, Adjusted: true}, ctx)
+		if response.Error != nil {
+			return polyModels.Agg{}, response.Error
+		}
+		if len(response.Data.Results) == 0 {
+			return polyModels.Agg{}, fmt.Errorf("no previous close for %s", ticker)
+		}
+
+		return response.Data.Results[0], nil
+	})
+}
+
+// FetchOpenCloses gets each ticker's open and close for the latest session to have finished concurrently,
+// tickers that failed are returned in errs instead
+func (p *PolyDataProcessor) FetchOpenCloses(ctx context.Context, tickers []string) (map[string]*polyModels.GetDailyOpenCloseAggResponse, map[string]error) {
+	lastSession := calendar.Shared.LastSession(time.Now())
+	return fanOut(ctx, p.maxParallelism, tickers, func(ticker string) (*polyModels.GetDailyOpenCloseAggResponse, error) {
+		response := p.api.FetchTickerOpenClose(ticker, lastSession.Date, ctx)
+		return response.Data, response.Error
+	})
+}
+
+// FetchDailyBars gets each ticker's daily bars between from and to concurrently, tickers that failed are
+// returned in errs instead
+func (p *PolyDataProcessor) FetchDailyBars(ctx context.Context, tickers []string, from time.Time, to time.Time,
+	adjusted bool) (map[string][]polyModels.Agg, map[string]error) {
+	return fanOut(ctx, p.maxParallelism, tickers, func(ticker string) ([]polyModels.Agg, error) {
+		response := p.api.FetchDailyBars(ticker, from, to, adjusted, ctx)
+		return response.Data, response.Error
+	})
+}
+
+// FetchSnapshots gets each ticker's latest snapshot concurrently, tickers that failed are returned in
+// errs instead
+func (p *PolyDataProcessor) FetchSnapshots(ctx context.Context, tickers []string) (map[string]polyModels.TickerSnapshot, map[string]error) {
+	return fanOut(ctx, p.maxParallelism, tickers, func(ticker string) (polyModels.TickerSnapshot, error) {
+		response := p.api.FetchTickerSnapshot(ticker, ctx)
+		if response.Error != nil {
+			return polyModels.TickerSnapshot{}, response.Error
+		}
+
+		return response.Data.Snapshot, nil
+	})
+}
+
+// FetchNews gets up to max of each ticker's news articles concurrently, newest first and published between
+// from and to inclusive. A zero from or to leaves that end open, tickers that failed are returned in errs
+// instead
+func (p *PolyDataProcessor) FetchNews(ctx context.Context, tickers []string, from time.Time, to time.Time,
+	max int) (map[string][]polyModels.TickerNews, map[string]error) {
+	return fanOut(ctx, p.maxParallelism, tickers, func(ticker string) ([]polyModels.TickerNews, error) {
+		sort := polyModels.Sort("published_utc")
+		order := polyModels.Desc
+		limit := min(max, 1000)
+		params := &polyModels.ListTickerNewsParams{TickerEQ: &ticker, Sort: &sort, Order: &order, Limit: &limit}
+		if !from.IsZero() {
+			params.PublishedUtcGTE = (*polyModels.Millis)(&from)
+		}
+		if !to.IsZero() {
+			params.PublishedUtcLTE = (*polyModels.Millis)(&to)
+		}
+
+		response := p.api.ListTickerNews(params, max, ctx)
+		return response.Data, response.Error
+	})
+}
+
+// FetchSplits gets each ticker's splits concurrently oldest first, tickers that failed are returned in errs
+// instead
+func (p *PolyDataProcessor) FetchSplits(ctx context.Context, tickers []string) (map[string][]polyModels.Split, map[string]error) {
+	return fanOut(ctx, p.maxParallelism, tickers, func(ticker string) ([]polyModels.Split, error) {
+		sort := polyModels.Sort("execution_date")
+		order := polyModels.Asc
+		limit := 1000
+
+		response := p.api.ListSplits(&polyModels.ListSplitsParams{TickerEQ: &ticker, Sort: &sort, Order: &order,
+			Limit: &limit}, 0, ctx)
+		return response.Data, response.Error
+	})
+}
+
+// SmaKey a ticker's simple moving average over Window days
+type SmaKey struct {
+	Ticker string
+	Window int
+}
+
+// FetchLatestSmas gets the most recent daily simple moving average for each key concurrently, keys that
+// failed are returned in errs instead
+func (p *PolyDataProcessor) FetchLatestSmas(ctx context.Context, keys []SmaKey) (map[SmaKey]float64, map[SmaKey]error) {
+	return fanOut(ctx, p.maxParallelism, keys, func(key SmaKey) (float64, error) {
+		//a week of sessions back so there's a value before the latest is published, results come back newest first
+		response := p.api.FetchSimpleMovingAverage(dtos.SimpleMovingAverageDto{
+			Ticker:    key.Ticker,
+			TimeStamp: calendar.Shared.SessionsBefore(time.Now(), 5).Date,
+			TimeSpan:  string(polyModels.Day),
+			Window:    key.Window,
+		}, ctx)
+		if response.Error != nil {
+			return 0, response.Error
+		}
+
+		return response.Data.Results.Values[0].Value, nil
+	})
+}
+
+// fanOut calls fetch for every key with at most maxParallelism running at once
+func fanOut[K comparable, T any](ctx context.Context, maxParallelism int8, keys []K,
+	fetch func(key K) (T, error)) (map[K]T, map[K]error) {
+	results := make(map[K]T, len(keys))
+	errs := make(map[K]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxParallelism)
+
+	for _, key := range keys {
+		wg.Add(1)
+
+		go func(key K) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			var result T
+			err := ctx.Err()
+			if err == nil {
+				result, err = fetch(key)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[key] = err
+				return
+			}
+			results[key] = result
+		}(key)
+	}
+
+	wg.Wait()
+	return results, errs
+}
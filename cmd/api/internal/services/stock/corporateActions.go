@@ -0,0 +1,202 @@
+package stock
+
+import (
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultCorporateActionsLimit dividends or splits returned when the caller doesn't set a limit
+const defaultCorporateActionsLimit = 100
+
+// GetDividends the ticker's cash dividends newest first, from and to bound the ex-dividend date
+func GetDividends(c *gin.Context, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	params, ok := bindCorporateActions(c)
+	if !ok {
+		return
+	}
+
+	key := corporateActionsKey("dividends", params)
+	if cacheResult, ok := cache.Get(key); ok {
+		c.JSON(http.StatusOK, gin.H{"data": cacheResult})
+		return
+	}
+
+	sort := polyModels.Sort("ex_dividend_date")
+	order := polyModels.Desc
+	request := &polyModels.ListDividendsParams{TickerEQ: &params.Ticker, Sort: &sort, Order: &order,
+		Limit: &params.Limit}
+	if !params.From.IsZero() {
+		request.ExDividendDateGTE = (*polyModels.Date)(&params.From)
+	}
+	if !params.To.IsZero() {
+		request.ExDividendDateLTE = (*polyModels.Date)(&params.To)
+	}
+
+	respChan := make(chan *Response[[]polyModels.Dividend], 1)
+
+	go func() {
+		dividends := pa.ListDividends(request, params.Limit, ctx)
+
+		respChan <- &dividends
+	}()
+
+	select {
+	case dividends := <-respChan:
+		if dividends.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": dividends.Error.Error()})
+			return
+		}
+
+		results := make([]Dividend, 0, len(dividends.Data))
+		for _, dividend := range dividends.Data {
+			results = append(results, toDividend(dividend))
+		}
+
+		cache.Set(key, results, time.Duration(configuration.Current().CorporateActions.CacheTtl))
+		c.JSON(http.StatusOK, gin.H{"data": results})
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+}
+
+// GetSplits the ticker's stock splits newest first, from and to bound the execution date
+func GetSplits(c *gin.Context, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	params, ok := bindCorporateActions(c)
+	if !ok {
+		return
+	}
+
+	key := corporateActionsKey("splits", params)
+	if cacheResult, ok := cache.Get(key); ok {
+		c.JSON(http.StatusOK, gin.H{"data": cacheResult})
+		return
+	}
+
+	sort := polyModels.Sort("execution_date")
+	order := polyModels.Desc
+	request := &polyModels.ListSplitsParams{TickerEQ: &params.Ticker, Sort: &sort, Order: &order,
+		Limit: &params.Limit}
+	if !params.From.IsZero() {
+		request.ExecutionDateGTE = (*polyModels.Date)(&params.From)
+	}
+	if !params.To.IsZero() {
+		request.ExecutionDateLTE = (*polyModels.Date)(&params.To)
+	}
+
+	respChan := make(chan *Response[[]polyModels.Split], 1)
+
+	go func() {
+		splits := pa.ListSplits(request, params.Limit, ctx)
+
+		respChan <- &splits
+	}()
+
+	select {
+	case splits := <-respChan:
+		if splits.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": splits.Error.Error()})
+			return
+		}
+
+		results := make([]Split, 0, len(splits.Data))
+		for _, split := range splits.Data {
+			if result, ok := toSplit(split); ok {
+				results = append(results, result)
+			}
+		}
+
+		cache.Set(key, results, time.Duration(configuration.Current().CorporateActions.CacheTtl))
+		c.JSON(http.StatusOK, gin.H{"data": results})
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+}
+
+// bindCorporateActions binds and normalises the query, the error response has been written when ok is false
+func bindCorporateActions(c *gin.Context) (CorporateActionsDto, bool) {
+	var params CorporateActionsDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return params, false
+	}
+	if !params.From.IsZero() && !params.To.IsZero() && params.To.Before(params.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": "to must not be before from"})
+		return params, false
+	}
+
+	params.Ticker = strings.ToUpper(strings.TrimSpace(params.Ticker))
+	if params.Limit == 0 {
+		params.Limit = defaultCorporateActionsLimit
+	}
+	return params, true
+}
+
+func corporateActionsKey(action string, params CorporateActionsDto) string {
+	return fmt.Sprintf("%s-%s-%s-%s-%d", action, params.Ticker, params.From.Format(time.DateOnly),
+		params.To.Format(time.DateOnly), params.Limit)
+}
+
+func toDividend(dividend polyModels.Dividend) Dividend {
+	result := Dividend{
+		Ticker:          dividend.Ticker,
+		CashAmount:      dividend.CashAmount,
+		DividendType:    dividend.DividendType,
+		Frequency:       dividend.Frequency,
+		DeclarationDate: optionalDate(dividend.DeclarationDate),
+		RecordDate:      optionalDate(dividend.RecordDate),
+		PayDate:         optionalDate(dividend.PayDate),
+	}
+
+	//polygon sends the ex-dividend date as a plain string unlike the others
+	exDividend, err := time.Parse(time.DateOnly, dividend.ExDividendDate)
+	if err != nil {
+		log.Warnf("invalid ex-dividend date %q for %s: %s", dividend.ExDividendDate, dividend.Ticker, err)
+	}
+	result.ExDividendDate = exDividend
+
+	return result
+}
+
+// toSplit false when polygon's split has no ratio to adjust by
+func toSplit(split polyModels.Split) (Split, bool) {
+	if split.SplitFrom <= 0 || split.SplitTo <= 0 {
+		log.Warnf("ignoring %s split on %s with ratio %g:%g", split.Ticker,
+			time.Time(split.ExecutionDate).Format(time.DateOnly), split.SplitTo, split.SplitFrom)
+		return Split{}, false
+	}
+
+	return Split{
+		Ticker:        split.Ticker,
+		ExecutionDate: time.Time(split.ExecutionDate).UTC(),
+		SplitFrom:     split.SplitFrom,
+		SplitTo:       split.SplitTo,
+		Ratio:         split.SplitTo / split.SplitFrom,
+		ReverseSplit:  split.SplitTo < split.SplitFrom,
+	}, true
+}
+
+func optionalDate(date polyModels.Date) *time.Time {
+	if time.Time(date).IsZero() {
+		return nil
+	}
+
+	result := time.Time(date).UTC()
+	return &result
+}
@@ -0,0 +1,291 @@
+package stock
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/features"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNewsLimit = 20
+	//extra articles fetched so ones published the same millisecond as the cursor aren't cut off
+	newsOverlap = 10
+)
+
+var errInvalidCursor = errors.New("cursor is invalid, use the next_cursor of a previous page")
+
+// newsCursor the last article of the previous page, the next page starts after it
+type newsCursor struct {
+	publishedAt time.Time
+	id          string
+}
+
+// GetTickerNews a page of news articles mentioning the ticker, newest first
+func GetTickerNews(c *gin.Context, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var params TickerNewsDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	cursor, err := decodeCursor(params.Cursor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultNewsLimit
+	}
+	ticker := strings.ToUpper(strings.TrimSpace(params.Ticker))
+
+	key := fmt.Sprintf("ticker-news-%s-%s-%s-%d", ticker, params.From.Format(time.DateOnly), params.Cursor, params.Limit)
+	if cacheResult, ok := cache.Get(key); ok {
+		respondNews(c, cacheResult.(NewsPage))
+		return
+	}
+
+	processor := stockConcurrency.NewPolyDataProcessor(pa, 1)
+	articles, errs := processor.FetchNews(ctx, []string{ticker}, params.From, cursor.publishedAt,
+		params.Limit+1+newsOverlap)
+
+	if ctx.Err() != nil {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+	if err := errs[ticker]; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := newsPage(articles, cursor, params.Limit)
+	cache.Set(key, page, time.Duration(configuration.Current().News.CacheTtl))
+	respondNews(c, page)
+}
+
+// GetFavouritesNews a page of news articles mentioning any of the caller's favourites, newest first.
+// Articles mentioning several favourites appear once
+func GetFavouritesNews(c *gin.Context, stockDb StockRepository, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var params FavouritesNewsDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	cursor, err := decodeCursor(params.Cursor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultNewsLimit
+	}
+
+	//the user comes from the caller's credentials, user_id is only for admins acting on behalf of someone
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := fmt.Sprintf("favourites-news-%s-%s-%s-%d", userId, params.From.Format(time.DateOnly), params.Cursor, params.Limit)
+	if cacheResult, ok := cache.Get(key); ok {
+		respondNews(c, cacheResult.(NewsPage))
+		return
+	}
+
+	respChan := make(chan *Response[[]string], 1)
+
+	go func() {
+		favouriteStocks := stockDb.GetFavouriteTickers(userId, ctx)
+
+		respChan <- &favouriteStocks
+	}()
+
+	var favourites []string
+	select {
+	case favouriteStocks := <-respChan:
+		if favouriteStocks.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": favouriteStocks.Error.Error()})
+			return
+		}
+		favourites = favouriteStocks.Data
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+
+	//every article on the page could come from one ticker so each needs a full page
+	processor := stockConcurrency.NewPolyDataProcessor(pa, 10)
+	articles, errs := processor.FetchNews(ctx, favourites, params.From, cursor.publishedAt, params.Limit+1+newsOverlap)
+
+	if ctx.Err() != nil {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+	if len(errs) > 0 && len(articles) == 0 {
+		failed := make(map[string]string, len(errs))
+		for ticker, err := range errs {
+			failed[ticker] = err.Error()
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get news", "tickers": failed})
+		return
+	}
+
+	page := newsPage(articles, cursor, params.Limit)
+
+	//only cache complete results so a failed ticker is retried on the next request
+	if len(errs) == 0 {
+		cache.Set(key, page, time.Duration(configuration.Current().News.CacheTtl))
+	}
+	respondNews(c, page)
+}
+
+// newsPage merges the tickers' articles dropping repeats, sorts them newest first and cuts the page after
+// the cursor at limit
+func newsPage(byTicker map[string][]polyModels.TickerNews, cursor newsCursor, limit int) NewsPage {
+	seen := make(map[string]bool)
+	var articles []NewsArticle
+
+	for _, tickerArticles := range byTicker {
+		for _, article := range tickerArticles {
+			if seen[article.ID] {
+				continue
+			}
+			seen[article.ID] = true
+			articles = append(articles, toArticle(article))
+		}
+	}
+
+	//newest first, ties broken by id so pages don't overlap
+	sort.Slice(articles, func(i, j int) bool {
+		if !articles[i].PublishedAt.Equal(articles[j].PublishedAt) {
+			return articles[i].PublishedAt.After(articles[j].PublishedAt)
+		}
+		return articles[i].Id > articles[j].Id
+	})
+
+	page := NewsPage{Articles: []NewsArticle{}}
+	for _, article := range articles {
+		if !cursor.before(article) {
+			continue
+		}
+		if len(page.Articles) == limit {
+			last := page.Articles[limit-1]
+			page.NextCursor = encodeCursor(newsCursor{publishedAt: last.PublishedAt, id: last.Id})
+			break
+		}
+		page.Articles = append(page.Articles, article)
+	}
+
+	return page
+}
+
+// respondNews writes the page, polygon's insights are left out for callers without the news insights flag.
+// Pages are cached with them so one cached page serves everyone
+func respondNews(c *gin.Context, page NewsPage) {
+	if !features.Enabled(c.Request.Context(), features.NewsInsights) {
+		articles := make([]NewsArticle, len(page.Articles))
+		for i, article := range page.Articles {
+			article.Insights = nil
+			articles[i] = article
+		}
+		page.Articles = articles
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": page})
+}
+
+func toArticle(article polyModels.TickerNews) NewsArticle {
+	result := NewsArticle{
+		Id:          article.ID,
+		Title:       article.Title,
+		Author:      article.Author,
+		Description: article.Description,
+		ArticleUrl:  article.ArticleURL,
+		ImageUrl:    article.ImageURL,
+		PublishedAt: time.Time(article.PublishedUTC).UTC(),
+		Publisher: NewsPublisher{
+			Name:        article.Publisher.Name,
+			HomepageUrl: article.Publisher.HomepageURL,
+			LogoUrl:     article.Publisher.LogoURL,
+		},
+		Tickers:  article.Tickers,
+		Keywords: article.Keywords,
+	}
+	if result.Tickers == nil {
+		result.Tickers = []string{}
+	}
+
+	for _, insight := range article.Insights {
+		result.Insights = append(result.Insights, NewsInsight{
+			Ticker:    insight.Ticker,
+			Sentiment: insight.Sentiment,
+			Reasoning: insight.SentimentReasoning,
+		})
+	}
+
+	return result
+}
+
+// before whether the article comes after the cursor in newest first order, everything does for the first page
+func (c newsCursor) before(article NewsArticle) bool {
+	if c.publishedAt.IsZero() {
+		return true
+	}
+	if !article.PublishedAt.Equal(c.publishedAt) {
+		return article.PublishedAt.Before(c.publishedAt)
+	}
+	return article.Id < c.id
+}
+
+// encodeCursor an opaque cursor of the article's publish time in milliseconds and its id
+func encodeCursor(cursor newsCursor) string {
+	raw := strconv.FormatInt(cursor.publishedAt.UnixMilli(), 10) + ":" + cursor.id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reads a cursor made by encodeCursor, an empty cursor is the first page
+func decodeCursor(encoded string) (newsCursor, error) {
+	if encoded == "" {
+		return newsCursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return newsCursor{}, errInvalidCursor
+	}
+
+	millis, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return newsCursor{}, errInvalidCursor
+	}
+	published, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil || published <= 0 {
+		return newsCursor{}, errInvalidCursor
+	}
+
+	return newsCursor{publishedAt: time.UnixMilli(published).UTC(), id: id}, nil
+}
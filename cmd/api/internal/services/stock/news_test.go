@@ -0,0 +1,140 @@
+package stock
+
+import (
+	"encoding/base64"
+	"errors"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewsPage(t *testing.T) {
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2024, time.March, 5, hour, minute, 0, 0, time.UTC)
+	}
+	article := func(id string, publishedAt time.Time) polyModels.TickerNews {
+		return polyModels.TickerNews{ID: id, PublishedUTC: polyModels.Time(publishedAt)}
+	}
+
+	//tie-a, tie-b and tie-c were published together, dup mentions both tickers
+	byTicker := map[string][]polyModels.TickerNews{
+		"AAPL": {article("a1", at(12, 0)), article("tie-a", at(11, 0)), article("tie-c", at(11, 0)),
+			article("dup", at(10, 0)), article("a5", at(9, 0))},
+		"MSFT": {article("m1", at(11, 30)), article("tie-b", at(11, 0)), article("dup", at(10, 0))},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  [][]string
+	}{
+		{"one a page", 1, [][]string{{"a1"}, {"m1"}, {"tie-c"}, {"tie-b"}, {"tie-a"}, {"dup"}, {"a5"}}},
+		{"ties split across pages", 2, [][]string{{"a1", "m1"}, {"tie-c", "tie-b"}, {"tie-a", "dup"}, {"a5"}}},
+		{"page ending inside ties", 3, [][]string{{"a1", "m1", "tie-c"}, {"tie-b", "tie-a", "dup"}, {"a5"}}},
+		{"exactly one page", 7, [][]string{{"a1", "m1", "tie-c", "tie-b", "tie-a", "dup", "a5"}}},
+		{"less than a page", 10, [][]string{{"a1", "m1", "tie-c", "tie-b", "tie-a", "dup", "a5"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got [][]string
+			cursor := newsCursor{}
+
+			for len(got) <= len(test.want) {
+				page := newsPage(byTicker, cursor, test.limit)
+
+				ids := []string{}
+				for _, article := range page.Articles {
+					ids = append(ids, article.Id)
+				}
+				got = append(got, ids)
+
+				if page.NextCursor == "" {
+					break
+				}
+				var err error
+				if cursor, err = decodeCursor(page.NextCursor); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !slices.EqualFunc(got, test.want, slices.Equal) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewsPageWithoutArticles(t *testing.T) {
+	page := newsPage(map[string][]polyModels.TickerNews{"AAPL": nil}, newsCursor{}, 10)
+	if page.Articles == nil || len(page.Articles) != 0 || page.NextCursor != "" {
+		t.Fatalf("got %+v, want an empty page without a cursor", page)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	published := time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC)
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    newsCursor
+		wantErr error
+	}{
+		{"first page", "", newsCursor{}, nil},
+		{"round trip", encodeCursor(newsCursor{publishedAt: published, id: "tie-b"}),
+			newsCursor{publishedAt: published, id: "tie-b"}, nil},
+		{"id with a colon", encode("1709636400000:a:b"), newsCursor{publishedAt: published, id: "a:b"}, nil},
+		{"not base64", "not a cursor!", newsCursor{}, errInvalidCursor},
+		{"padded", base64.URLEncoding.EncodeToString([]byte("1709636400000:ab")), newsCursor{}, errInvalidCursor},
+		{"no separator", encode("1709636400000"), newsCursor{}, errInvalidCursor},
+		{"no id", encode("1709636400000:"), newsCursor{}, errInvalidCursor},
+		{"time isn't a number", encode("yesterday:tie-b"), newsCursor{}, errInvalidCursor},
+		{"zero time", encode("0:tie-b"), newsCursor{}, errInvalidCursor},
+		{"negative time", encode("-1:tie-b"), newsCursor{}, errInvalidCursor},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeCursor(test.cursor)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if !got.publishedAt.Equal(test.want.publishedAt) || got.id != test.want.id {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewsCursorBefore(t *testing.T) {
+	published := time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC)
+	cursor := newsCursor{publishedAt: published, id: "tie-b"}
+
+	tests := []struct {
+		name    string
+		cursor  newsCursor
+		article NewsArticle
+		want    bool
+	}{
+		{"first page has everything", newsCursor{}, NewsArticle{Id: "a1", PublishedAt: published.Add(time.Hour)}, true},
+		{"older", cursor, NewsArticle{Id: "z", PublishedAt: published.Add(-time.Second)}, true},
+		{"newer", cursor, NewsArticle{Id: "a", PublishedAt: published.Add(time.Second)}, false},
+		{"same time, lower id", cursor, NewsArticle{Id: "tie-a", PublishedAt: published}, true},
+		{"same time, same id", cursor, NewsArticle{Id: "tie-b", PublishedAt: published}, false},
+		{"same time, higher id", cursor, NewsArticle{Id: "tie-c", PublishedAt: published}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.cursor.before(test.article); got != test.want {
+				t.Fatalf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package stock
+
+import (
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/tickers"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultSearchLimit results returned when the caller doesn't set a limit
+const defaultSearchLimit = 10
+
+// SearchTickers finds tickers by the start of their symbol or company name. Active stocks are answered from
+// the local ticker index, other markets, inactive tickers and searches before the index has loaded go to
+// polygon and are cached
+func SearchTickers(c *gin.Context, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var params SearchTickersDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	query := strings.TrimSpace(params.Query)
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": "q can't be blank"})
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultSearchLimit
+	}
+	active := params.Active == nil || *params.Active
+
+	if active && (params.Market == "" || params.Market == tickers.Market) && tickers.Shared.Ready() {
+		c.JSON(http.StatusOK, gin.H{"data": tickers.Shared.Search(query, params.Type, params.Limit)})
+		return
+	}
+
+	key := fmt.Sprintf("ticker-search-%s-%s-%s-%t-%d", strings.ToLower(query), params.Market,
+		strings.ToUpper(params.Type), active, params.Limit)
+	if cacheResult, ok := cache.Get(key); ok {
+		c.JSON(http.StatusOK, gin.H{"data": cacheResult})
+		return
+	}
+
+	request := &polyModels.ListTickersParams{Search: &query, Active: &active, Limit: &params.Limit}
+	if params.Market != "" {
+		market := polyModels.AssetClass(params.Market)
+		request.Market = &market
+	}
+	if params.Type != "" {
+		tickerType := strings.ToUpper(params.Type)
+		request.Type = &tickerType
+	}
+
+	respChan := make(chan *Response[[]polyModels.Ticker], 1)
+
+	go func() {
+		found := pa.ListTickers(request, params.Limit, ctx)
+
+		respChan <- &found
+	}()
+
+	select {
+	case found := <-respChan:
+		if found.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": found.Error.Error()})
+			return
+		}
+
+		results := make([]TickerSummary, 0, len(found.Data))
+		for _, ticker := range found.Data {
+			results = append(results, tickers.Summary(ticker))
+		}
+
+		cache.Set(key, results, time.Duration(configuration.Current().Tickers.SearchCacheTtl))
+		c.JSON(http.StatusOK, gin.H{"data": results})
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+}
@@ -0,0 +1,98 @@
+package stock
+
+import (
+	"context"
+	"errors"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/calendar"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/processing"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+// SplitAdjuster applies stock splits to the prices we store. Polygon's bars are split adjusted but
+// portfolio transactions, alert thresholds and alert triggers hold the prices of the day, so once a split
+// of a held ticker executes they're adjusted to match. Each split is applied once, a transaction
+// backdated to before a split that has already been applied gets its split row when it's added
+type SplitAdjuster struct {
+	splitDb   *SplitRepository
+	processor *stockConcurrency.PolyDataProcessor
+}
+
+func NewSplitAdjuster(splitDb *SplitRepository, pa *intergration.PolygonApi) *SplitAdjuster {
+	return &SplitAdjuster{
+		splitDb:   splitDb,
+		processor: stockConcurrency.NewPolyDataProcessor(pa, 10),
+	}
+}
+
+// Start applies new splits now and every corporateActions.checkInterval until ctx is done, the interval
+// is read before each wait so reloads are picked up
+func (s *SplitAdjuster) Start(ctx context.Context) {
+	schedule.NowAndEvery(ctx, func() time.Duration {
+		return time.Duration(configuration.Current().CorporateActions.CheckInterval)
+	}, func(ctx context.Context) {
+		if err := s.Apply(ctx); err != nil {
+			log.Errorf("applying splits failed: %s", err)
+		}
+	})
+}
+
+// Apply fetches the splits of every held ticker and applies those that have executed since the ticker
+// was first held and haven't been applied yet
+func (s *SplitAdjuster) Apply(ctx context.Context) error {
+	held := s.splitDb.ListHeldTickers(ctx)
+	if held.Error != nil {
+		return held.Error
+	}
+	if len(held.Data) == 0 {
+		return nil
+	}
+
+	tickers := make([]string, 0, len(held.Data))
+	since := make(map[string]time.Time, len(held.Data))
+	for _, ticker := range held.Data {
+		tickers = append(tickers, ticker.Ticker)
+		since[ticker.Ticker] = ticker.Since
+	}
+
+	splits, errs := s.processor.FetchSplits(ctx, tickers)
+	for ticker, err := range errs {
+		log.Errorf("skipping splits of %s, couldn't get them: %s", ticker, err)
+	}
+
+	//a split is applied from the morning it executes, when polygon's bars switch to the new share count
+	now := time.Now().In(calendar.Exchange)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	applied := 0
+	for ticker, tickerSplits := range splits {
+		for _, polySplit := range tickerSplits {
+			split, ok := toSplit(polySplit)
+			if !ok || !processing.SplitDue(split, since[ticker], today) {
+				continue
+			}
+
+			adjustment, err := s.splitDb.ApplySplit(split, ctx)
+			if errors.Is(err, ErrSplitAlreadyApplied) {
+				continue
+			}
+			if err != nil {
+				log.Errorf("error applying %s split on %s: %s", ticker, split.ExecutionDate.Format(time.DateOnly), err)
+				continue
+			}
+
+			applied++
+			log.Infof("applied %g-for-%g %s split on %s to %d portfolios, %d alerts and %d alert triggers",
+				split.SplitTo, split.SplitFrom, ticker, split.ExecutionDate.Format(time.DateOnly),
+				adjustment.Portfolios, adjustment.Alerts, adjustment.AlertTriggers)
+		}
+	}
+
+	log.Infof("checked splits of %d held tickers, %d applied", len(tickers), applied)
+	return nil
+}
@@ -3,11 +3,17 @@ package stock
 import (
 	"fmt"
 	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	appCache "github.com/RobsonDevCode/GoApi/cmd/api/internal/cache"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/calendar"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/events"
 	"github.com/RobsonDevCode/GoApi/cmd/api/internal/processing"
 	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
 	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/tickers"
 	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
 	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
 	"github.com/gin-gonic/gin"
 	"github.com/labstack/gommon/log"
 	polyModels "github.com/polygon-io/client-go/rest/models"
@@ -16,7 +22,7 @@ import (
 	"time"
 )
 
-var cache = &sync.Map{}
+var cache = appCache.Shared
 
 // GetTickerDetails gets stock information by ticker
 func GetTickerDetails(c *gin.Context, pa *intergration.PolygonApi) {
@@ -36,7 +42,7 @@ func GetTickerDetails(c *gin.Context, pa *intergration.PolygonApi) {
 	key := fmt.Sprintf("ticker-details-%s", request.Ticker)
 
 	if cacheResult, ok :=
-		cache.Load(key); ok {
+		cache.Get(key); ok {
 		//data has been cached already
 		c.JSON(http.StatusOK, cacheResult)
 		return
@@ -57,14 +63,9 @@ func GetTickerDetails(c *gin.Context, pa *intergration.PolygonApi) {
 			return
 		}
 
-		//cache timeout
-		go func() {
-			time.Sleep(4 * time.Minute)
-			cache.Delete(key)
-		}()
-
-		//store result in cache
-		cache.Store(key, result.Data)
+		//store result in cache, for longer while the market is closed
+		ttl := calendar.Shared.CacheTtl(time.Now(), time.Duration(configuration.Current().Cache.TickerDetailsTtl))
+		cache.Set(key, result.Data, ttl)
 
 		c.JSON(http.StatusOK, gin.H{"data": result.Data})
 
@@ -88,6 +89,7 @@ func GetSimpleMovingAverage(c *gin.Context, pa *intergration.PolygonApi) {
 	}
 
 	var wg sync.WaitGroup
+	wg.Add(2)
 	movingAvgCh := make(chan *Response[*polyModels.GetSMAResponse], 1)
 	lwTickerCh := make(chan *Response[*polyModels.GetDailyOpenCloseAggResponse], 1)
 
@@ -99,9 +101,9 @@ func GetSimpleMovingAverage(c *gin.Context, pa *intergration.PolygonApi) {
 	}()
 	go func() {
 		defer wg.Done()
-		//go get the price of the tickers close last week
-		weekAgo := params.TimeStamp.AddDate(0, 0, -7)
-		lwTickerPrice := pa.FetchTickerOpenClose(params.Ticker, weekAgo, ctx)
+		//go get the price of the tickers close last week, the session before when it was a holiday or weekend
+		weekAgo := calendar.Shared.SessionOnOrBefore(params.TimeStamp.AddDate(0, 0, -7))
+		lwTickerPrice := pa.FetchTickerOpenClose(params.Ticker, weekAgo.Date, ctx)
 
 		lwTickerCh <- &lwTickerPrice
 	}()
@@ -184,14 +186,22 @@ func GetFavouriteStocksOpenClose(c *gin.Context, stockDb StockRepository, pa *in
 
 	if err := c.ShouldBindQuery(&params); err != nil {
 		log.Error(err)
-		c.JSON(http.StatusBadRequest, gin.H{"validation error": "user_id cannot be null when requesting favourite stocks"})
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	//the user comes from the caller's credentials, user_id is only for admins acting on behalf of someone
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
+	params.UserId = userId
 
 	//check if result has been cached
-	key := fmt.Sprintf("get-fav-open-close-%s", params.UserId)
+	key := appCache.FavouritesOpenCloseKey(params.UserId)
 
-	if cacheResult, ok := cache.Load(key); ok {
+	if cacheResult, ok := cache.Get(key); ok {
 		c.JSON(http.StatusOK, cacheResult)
 		return
 	}
@@ -213,25 +223,7 @@ func GetFavouriteStocksOpenClose(c *gin.Context, stockDb StockRepository, pa *in
 		}
 
 		processor := stockConcurrency.NewPolyDataProcessor(pa, 10)
-
-		resultCh, err := processor.ProcessTickersConcurrently(ctx, favouriteStocks.Data)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-
-		var response []*polyModels.GetDailyOpenCloseAggResponse
-		var errs []error
-
-		for resp := range resultCh {
-			if resp.Error != nil {
-				errs = append(errs, resp.Error)
-				log.Error(resp.Error)
-				continue
-			}
-
-			response = append(response, resp.Data)
-		}
+		response, errs := processor.FetchOpenClose(ctx, favouriteStocks.Data)
 
 		if len(errs) > 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": errs})
@@ -241,13 +233,8 @@ func GetFavouriteStocksOpenClose(c *gin.Context, stockDb StockRepository, pa *in
 			return
 		}
 
-		//set timer for cache
-		go func() {
-			time.Sleep(3 * time.Minute)
-			cache.Delete(key)
-		}()
-		//cache result
-		cache.Store(key, response)
+		//favourites can change while the market is closed so they don't get the closed ttl
+		cache.Set(key, response, time.Duration(configuration.Current().Cache.OpenCloseTtl))
 		c.JSON(http.StatusOK, gin.H{"data": response})
 
 	case <-ctx.Done():
@@ -258,16 +245,13 @@ func GetFavouriteStocksOpenClose(c *gin.Context, stockDb StockRepository, pa *in
 }
 
 // FavouriteTicker sets stock as a favourite for the user
-func FavouriteTicker(c *gin.Context, stockDb StockRepository) {
+func FavouriteTicker(c *gin.Context, stockDb StockRepository, pa *intergration.PolygonApi) {
 	ctx := c.Request.Context()
 	var request FavouriteStock
 
 	if err := c.ShouldBindJSON(&request); err != nil {
 		log.Error(err)
-		if request.UserId == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id cannot be null when creating a favourite stock"})
-			return
-		} else if request.Ticker == "" {
+		if request.Ticker == "" {
 			c.JSON(http.StatusOK, gin.H{"error": "must provide a ticker"})
 			return
 		}
@@ -276,9 +260,25 @@ func FavouriteTicker(c *gin.Context, stockDb StockRepository) {
 		return
 	}
 
+	userId, err := auth.ResolveUserId(ctx, request.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	request.UserId = userId
+
+	//typos would otherwise only show up when the favourites' prices are fetched
+	ticker, ok := tickers.ValidTicker(c, pa, request.Ticker)
+	if !ok {
+		return
+	}
+	request.Ticker = ticker
+
+	var watchlistId string
 	ch := make(chan error)
 	go func() {
-		err := stockDb.AddToFavouriteTickers(request, ctx)
+		var err error
+		watchlistId, err = stockDb.AddToFavouriteTickers(request, ctx)
 		ch <- err
 	}()
 
@@ -293,6 +293,8 @@ func FavouriteTicker(c *gin.Context, stockDb StockRepository) {
 		return
 	}
 
+	clearFavouritesCache(userId, watchlistId)
+	events.Shared.Publish(events.New(events.FavouriteAdded, userId, request))
 	c.JSON(http.StatusCreated, gin.H{"created": "Stock added to favorites"})
 	return
 }
@@ -304,18 +306,24 @@ func UnFavouriteTicker(c *gin.Context, stockDb StockRepository) {
 
 	if err := c.ShouldBindQuery(&request); err != nil {
 		log.Error(err)
-		if request.UserId == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id cannot be null when removing a stock from favourites"})
-			return
-		} else if request.Ticker == "" {
+		if request.Ticker == "" {
 			c.JSON(http.StatusOK, gin.H{"error": "must provide a ticker"})
 			return
 		}
 	}
 
+	userId, err := auth.ResolveUserId(ctx, request.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	request.UserId = userId
+
+	var watchlistId string
 	ch := make(chan error)
 	go func() {
-		err := stockDb.DeleteStockFromFavouriteTickers(request, ctx)
+		var err error
+		watchlistId, err = stockDb.DeleteStockFromFavouriteTickers(request, ctx)
 		ch <- err
 	}()
 
@@ -331,6 +339,16 @@ func UnFavouriteTicker(c *gin.Context, stockDb StockRepository) {
 		return
 	}
 
+	clearFavouritesCache(userId, watchlistId)
 	c.JSON(http.StatusOK, gin.H{"deleted": "Stock removed from favorites"})
 	return
 }
+
+// clearFavouritesCache drops the cached open/close of the user's favourites and of their default watchlist,
+// which holds the same tickers
+func clearFavouritesCache(userId string, watchlistId string) {
+	cache.Delete(appCache.FavouritesOpenCloseKey(userId))
+	if watchlistId != "" {
+		cache.Delete(appCache.WatchlistOpenCloseKey(watchlistId))
+	}
+}
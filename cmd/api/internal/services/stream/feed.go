@@ -0,0 +1,272 @@
+package stream
+
+import (
+	"context"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"sync"
+	"time"
+)
+
+// Update a price update with the id it was published under, ids only ever increase
+type Update struct {
+	Id uint64
+	PriceUpdate
+}
+
+// Listener a client's view of the feed. Updates is closed if the client falls more than
+// stream.clientBuffer updates behind, it should reconnect with the last id it saw to resume
+type Listener struct {
+	Updates <-chan Update
+	updates chan Update
+	tickers map[string]bool
+	dropped bool
+}
+
+// Dropped reports whether Updates was closed because the client was too slow
+func (s *Listener) Dropped() bool {
+	return s.dropped
+}
+
+// Feed polls the tickers that have subscribers and publishes their changes. However many clients want a
+// ticker it's fetched once per poll, recent updates are kept so clients can resume where they left off
+type Feed struct {
+	processor *stockConcurrency.PolyDataProcessor
+
+	mu        sync.Mutex
+	listeners map[*Listener]struct{}
+	watching  map[string]int //subscribers per ticker
+	latest    map[string]Update
+	history   []Update
+	lastId    uint64
+}
+
+func NewFeed(pa *intergration.PolygonApi) *Feed {
+	return &Feed{
+		processor: stockConcurrency.NewPolyDataProcessor(pa, 10),
+		listeners: make(map[*Listener]struct{}),
+		watching:  make(map[string]int),
+		latest:    make(map[string]Update),
+		//seeded from the clock so ids from before a restart are older than any issued after it
+		lastId: uint64(time.Now().UnixMicro()),
+	}
+}
+
+// Start polls every stream.pollInterval until ctx is done, the interval is read before each wait so
+// reloads are picked up
+func (f *Feed) Start(ctx context.Context) {
+	schedule.Every(ctx, func() time.Duration {
+		return time.Duration(configuration.Current().Stream.PollInterval)
+	}, f.poll)
+}
+
+// Subscribe starts sending the tickers' updates. A client resuming from lastEventId is sent the updates it
+// missed, when those have been trimmed from the history or it isn't resuming it's sent the latest update
+// of each ticker instead
+func (f *Feed) Subscribe(tickers []string, lastEventId uint64, resuming bool) *Listener {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	listener := &Listener{tickers: make(map[string]bool, len(tickers))}
+	for _, ticker := range tickers {
+		listener.tickers[ticker] = true
+	}
+
+	var backlog []Update
+	oldestId := f.lastId + 1
+	if len(f.history) > 0 {
+		oldestId = f.history[0].Id
+	}
+
+	if resuming && lastEventId+1 >= oldestId && lastEventId <= f.lastId {
+		for _, update := range f.history {
+			if update.Id > lastEventId && listener.tickers[update.Ticker] {
+				backlog = append(backlog, update)
+			}
+		}
+	} else {
+		for _, ticker := range tickers {
+			if update, ok := f.latest[ticker]; ok {
+				backlog = append(backlog, update)
+			}
+		}
+	}
+
+	//room for the backlog on top of the usual buffer so a resume can't drop the client straight away
+	listener.updates = make(chan Update, configuration.Current().Stream.ClientBuffer+len(backlog))
+	listener.Updates = listener.updates
+	for _, update := range backlog {
+		listener.updates <- update
+	}
+
+	f.listeners[listener] = struct{}{}
+	for ticker := range listener.tickers {
+		f.watching[ticker]++
+	}
+
+	return listener
+}
+
+// Unsubscribe stops the listener's updates, it's safe to call after the listener was dropped
+func (f *Feed) Unsubscribe(listener *Listener) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.remove(listener)
+}
+
+// Watch adds tickers to a listener's subscription, it's sent the latest update of each new ticker
+func (f *Feed) Watch(listener *Listener, tickers ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.listeners[listener]; !ok {
+		return
+	}
+
+	for _, ticker := range tickers {
+		if listener.tickers[ticker] {
+			continue
+		}
+
+		listener.tickers[ticker] = true
+		f.watching[ticker]++
+		if update, ok := f.latest[ticker]; ok {
+			f.send(listener, update)
+			if listener.dropped {
+				return
+			}
+		}
+	}
+}
+
+// Unwatch removes tickers from a listener's subscription
+func (f *Feed) Unwatch(listener *Listener, tickers ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.listeners[listener]; !ok {
+		return
+	}
+
+	for _, ticker := range tickers {
+		if listener.tickers[ticker] {
+			delete(listener.tickers, ticker)
+			f.unwatch(ticker)
+		}
+	}
+}
+
+// poll fetches every watched ticker once and publishes the ones that changed
+func (f *Feed) poll(ctx context.Context) {
+	f.mu.Lock()
+	tickers := make([]string, 0, len(f.watching))
+	for ticker := range f.watching {
+		tickers = append(tickers, ticker)
+	}
+	f.mu.Unlock()
+
+	if len(tickers) == 0 {
+		return
+	}
+
+	snapshots, errs := f.processor.FetchSnapshots(ctx, tickers)
+	for ticker, err := range errs {
+		log.Errorf("couldn't get a snapshot of %s for the stream: %s", ticker, err)
+	}
+
+	for ticker, snapshot := range snapshots {
+		f.publish(ticker, priceUpdate(ticker, snapshot))
+	}
+}
+
+// publish sends the update to every subscriber of its ticker unless nothing has changed. Subscribers whose
+// buffer is full are dropped rather than holding up everyone else
+func (f *Feed) publish(ticker string, priceUpdate PriceUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.watching[ticker] == 0 {
+		return
+	}
+	if latest, ok := f.latest[ticker]; ok && latest.PriceUpdate == priceUpdate {
+		return
+	}
+
+	f.lastId++
+	update := Update{Id: f.lastId, PriceUpdate: priceUpdate}
+	f.latest[ticker] = update
+
+	f.history = append(f.history, update)
+	if excess := len(f.history) - configuration.Current().Stream.ReplayBuffer; excess > 0 {
+		f.history = append([]Update(nil), f.history[excess:]...)
+	}
+
+	for listener := range f.listeners {
+		if listener.tickers[ticker] {
+			f.send(listener, update)
+		}
+	}
+}
+
+// send queues the update for the listener, dropping the listener if its buffer is full. The caller holds mu
+func (f *Feed) send(listener *Listener, update Update) {
+	select {
+	case listener.updates <- update:
+	default:
+		log.Warnf("dropping stream subscriber of %d tickers, %d updates behind", len(listener.tickers),
+			len(listener.updates))
+		listener.dropped = true
+		f.remove(listener)
+	}
+}
+
+// remove unregisters the listener and closes its updates, the caller holds mu
+func (f *Feed) remove(listener *Listener) {
+	if _, ok := f.listeners[listener]; !ok {
+		return
+	}
+
+	delete(f.listeners, listener)
+	close(listener.updates)
+
+	for ticker := range listener.tickers {
+		f.unwatch(ticker)
+	}
+}
+
+// unwatch drops a subscriber of the ticker, forgetting it once nobody is left. The caller holds mu
+func (f *Feed) unwatch(ticker string) {
+	f.watching[ticker]--
+	if f.watching[ticker] <= 0 {
+		delete(f.watching, ticker)
+		delete(f.latest, ticker)
+	}
+}
+
+// priceUpdate the parts of the snapshot clients are sent. Outside market hours there's no bar for today so
+// the last trade is compared to the previous day's close
+func priceUpdate(ticker string, snapshot polyModels.TickerSnapshot) PriceUpdate {
+	update := PriceUpdate{
+		Ticker:        ticker,
+		Price:         snapshot.LastTrade.Price,
+		Open:          snapshot.Day.Open,
+		High:          snapshot.Day.High,
+		Low:           snapshot.Day.Low,
+		PreviousClose: snapshot.PrevDay.Close,
+		Change:        snapshot.TodaysChange,
+		ChangePercent: snapshot.TodaysChangePerc,
+		Volume:        snapshot.Day.Volume,
+		UpdatedAt:     time.Time(snapshot.Updated).UTC(),
+	}
+	if update.Price == 0 {
+		update.Price = snapshot.Day.Close
+	}
+
+	return update
+}
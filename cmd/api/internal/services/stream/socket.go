@@ -0,0 +1,380 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/events"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/realtime"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// actionUnsubscribe a SocketRequestDto action, anything else validated is a subscribe
+const actionUnsubscribe = "unsubscribe"
+
+// socketChannels every channel a websocket client can subscribe to
+var socketChannels = []string{ChannelPrices, ChannelTrades, ChannelQuotes, ChannelAggregates, ChannelAlerts}
+
+var (
+	errTooSlow          = errors.New("too far behind, reconnect and subscribe again")
+	errRealtimeDisabled = errors.New("the realtime feed isn't enabled, subscribe to prices instead")
+)
+
+// socket a websocket client. The handler's goroutine is the only writer, the reader goroutine handles what
+// the client sends and replies through outbox
+type socket struct {
+	ctx       context.Context
+	conn      *websocket.Conn
+	principal *auth.Principal
+	feed      *Feed
+	hub       *realtime.Hub
+
+	prices     *Listener
+	market     *realtime.Listener //nil when the realtime feed isn't enabled
+	stopAlerts func()
+
+	//only touched by the reader
+	subscriptions map[string]map[string]bool //tickers per channel, alerts has none
+	count         int
+
+	outbox   chan SocketFrame
+	tooSlow  chan struct{}
+	slowOnce sync.Once
+}
+
+// ServeSocket upgrades to a websocket that clients change their subscriptions over by sending
+// SocketRequestDto messages. Prices come from the same feed as the sse streams and trades, quotes and
+// aggregates from the realtime hub so a ticker is fetched once however many clients want it
+func ServeSocket(c *gin.Context, feed *Feed, hub *realtime.Hub) {
+	principal, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": auth.ErrNoCredentials.Error()})
+		return
+	}
+
+	settings := configuration.Current().Websocket
+	//a browser that passed its token as a subprotocol needs one picked that isn't the token
+	upgrader := websocket.Upgrader{CheckOrigin: allowedOrigin, Subprotocols: []string{auth.SocketProtocol}}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		//the upgrader has already replied with the error
+		log.Warnf("websocket upgrade failed: %s", err)
+		return
+	}
+	defer conn.Close()
+
+	s := &socket{
+		ctx:           c.Request.Context(),
+		conn:          conn,
+		principal:     principal,
+		feed:          feed,
+		hub:           hub,
+		subscriptions: make(map[string]map[string]bool),
+		outbox:        make(chan SocketFrame, settings.SendBuffer),
+		tooSlow:       make(chan struct{}),
+	}
+
+	s.prices = feed.Subscribe(nil, 0, false)
+	defer feed.Unsubscribe(s.prices)
+
+	var marketMessages <-chan MarketMessage
+	if configuration.Current().Realtime.Enabled {
+		s.market = hub.Listen()
+		defer hub.Close(s.market)
+		marketMessages = s.market.Messages
+	}
+
+	s.send(SocketFrame{Type: FrameConnected, Data: gin.H{
+		"channels":          socketChannels,
+		"max_subscriptions": settings.MaxSubscriptions,
+		"realtime":          s.market != nil,
+	}})
+
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		s.read()
+	}()
+
+	writeTimeout := time.Duration(settings.WriteTimeout)
+	ping := time.NewTicker(time.Duration(settings.PingInterval))
+	defer ping.Stop()
+
+	for {
+		var frame SocketFrame
+
+		select {
+		case update, ok := <-s.prices.Updates:
+			if !ok {
+				s.drop(writeTimeout)
+				return
+			}
+			frame = SocketFrame{Type: ChannelPrices, Ticker: update.Ticker, Data: update.PriceUpdate}
+
+		case message, ok := <-marketMessages:
+			if !ok {
+				s.drop(writeTimeout)
+				return
+			}
+			frame = SocketFrame{Type: message.Channel, Ticker: message.Ticker, Data: message.Data}
+
+		case frame = <-s.outbox:
+
+		case <-s.tooSlow:
+			s.drop(writeTimeout)
+			return
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+			continue
+
+		case <-readerDone:
+			return
+		}
+
+		if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			return
+		}
+		if err := conn.WriteJSON(frame); err != nil {
+			log.Infof("websocket write failed: %s", err)
+			return
+		}
+	}
+}
+
+// read handles the client's messages until the connection closes or a pong is overdue
+func (s *socket) read() {
+	settings := configuration.Current().Websocket
+	pongTimeout := time.Duration(settings.PongTimeout)
+
+	defer func() {
+		if s.stopAlerts != nil {
+			s.stopAlerts()
+		}
+	}()
+
+	s.conn.SetReadLimit(settings.MaxMessageSize)
+	_ = s.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Infof("websocket closed: %s", err)
+			}
+			return
+		}
+
+		var request SocketRequestDto
+		if err := json.Unmarshal(message, &request); err != nil {
+			s.send(SocketFrame{Type: FrameError, Error: "messages must be json: " + err.Error()})
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(&request); err != nil {
+			s.send(SocketFrame{Type: FrameError, Channel: request.Channel, Error: err.Error()})
+			continue
+		}
+
+		s.send(s.handle(request))
+	}
+}
+
+// handle applies a subscribe or unsubscribe and returns the reply
+func (s *socket) handle(request SocketRequestDto) SocketFrame {
+	if request.Channel == ChannelAlerts {
+		return s.handleAlerts(request.Action)
+	}
+
+	tickers := uniqueTickers(request.Tickers)
+	if len(tickers) == 0 {
+		return SocketFrame{Type: FrameError, Channel: request.Channel, Error: "at least one ticker is required"}
+	}
+
+	subscribed := s.subscriptions[request.Channel]
+	if subscribed == nil {
+		subscribed = make(map[string]bool)
+		s.subscriptions[request.Channel] = subscribed
+	}
+
+	if request.Action == actionUnsubscribe {
+		var removed []string
+		for _, ticker := range tickers {
+			if subscribed[ticker] {
+				delete(subscribed, ticker)
+				removed = append(removed, ticker)
+			}
+		}
+		s.count -= len(removed)
+
+		if err := s.unsubscribe(request.Channel, removed); err != nil {
+			return SocketFrame{Type: FrameError, Channel: request.Channel, Error: err.Error()}
+		}
+		return SocketFrame{Type: FrameUnsubscribed, Channel: request.Channel, Tickers: tickers}
+	}
+
+	var added []string
+	for _, ticker := range tickers {
+		if !subscribed[ticker] {
+			added = append(added, ticker)
+		}
+	}
+
+	maxSubscriptions := configuration.Current().Websocket.MaxSubscriptions
+	if s.count+len(added) > maxSubscriptions {
+		return SocketFrame{Type: FrameError, Channel: request.Channel, Tickers: tickers,
+			Error: fmt.Sprintf("at most %d subscriptions per connection, %d in use", maxSubscriptions, s.count)}
+	}
+
+	if err := s.subscribe(request.Channel, added); err != nil {
+		return SocketFrame{Type: FrameError, Channel: request.Channel, Tickers: tickers, Error: err.Error()}
+	}
+	for _, ticker := range added {
+		subscribed[ticker] = true
+	}
+	s.count += len(added)
+
+	return SocketFrame{Type: FrameSubscribed, Channel: request.Channel, Tickers: tickers}
+}
+
+// handleAlerts sends or stops sending the caller's alert triggers as they fire
+func (s *socket) handleAlerts(action string) SocketFrame {
+	if action == actionUnsubscribe {
+		if s.stopAlerts != nil {
+			s.stopAlerts()
+			s.stopAlerts = nil
+			s.count--
+		}
+		return SocketFrame{Type: FrameUnsubscribed, Channel: ChannelAlerts}
+	}
+
+	if s.stopAlerts != nil {
+		return SocketFrame{Type: FrameSubscribed, Channel: ChannelAlerts}
+	}
+	if !s.principal.HasScope(auth.ScopeAlerts) {
+		return SocketFrame{Type: FrameError, Channel: ChannelAlerts, Error: "missing required scope " + auth.ScopeAlerts}
+	}
+
+	userId, err := auth.ResolveUserId(s.ctx, "")
+	if err != nil {
+		return SocketFrame{Type: FrameError, Channel: ChannelAlerts, Error: err.Error()}
+	}
+
+	maxSubscriptions := configuration.Current().Websocket.MaxSubscriptions
+	if s.count+1 > maxSubscriptions {
+		return SocketFrame{Type: FrameError, Channel: ChannelAlerts,
+			Error: fmt.Sprintf("at most %d subscriptions per connection, %d in use", maxSubscriptions, s.count)}
+	}
+
+	//runs on the publisher's goroutine, send never blocks
+	s.stopAlerts = events.Shared.Subscribe(func(event events.Event) {
+		if event.Type == events.AlertTriggered && event.UserId == userId {
+			s.send(SocketFrame{Type: ChannelAlerts, Data: event})
+		}
+	})
+	s.count++
+
+	return SocketFrame{Type: FrameSubscribed, Channel: ChannelAlerts}
+}
+
+func (s *socket) subscribe(channel string, tickers []string) error {
+	if len(tickers) == 0 {
+		return nil
+	}
+
+	if channel == ChannelPrices {
+		s.feed.Watch(s.prices, tickers...)
+		return nil
+	}
+
+	if s.market == nil {
+		return errRealtimeDisabled
+	}
+	return s.hub.Subscribe(s.market, channel, tickers...)
+}
+
+func (s *socket) unsubscribe(channel string, tickers []string) error {
+	if len(tickers) == 0 {
+		return nil
+	}
+
+	if channel == ChannelPrices {
+		s.feed.Unwatch(s.prices, tickers...)
+		return nil
+	}
+
+	if s.market == nil {
+		return errRealtimeDisabled
+	}
+	return s.hub.Unsubscribe(s.market, channel, tickers...)
+}
+
+// send queues a frame for the writer, a client whose queue is full is dropped as too slow
+func (s *socket) send(frame SocketFrame) {
+	select {
+	case s.outbox <- frame:
+	default:
+		s.slowOnce.Do(func() {
+			close(s.tooSlow)
+		})
+	}
+}
+
+// drop closes the connection of a client that fell too far behind, telling it why
+func (s *socket) drop(writeTimeout time.Duration) {
+	log.Warnf("dropping websocket client %s, too far behind", s.principal.UserId)
+
+	message := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, errTooSlow.Error())
+	_ = s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeTimeout))
+}
+
+// allowedOrigin accepts clients that aren't browsers, pages served from the api itself and
+// websocket.allowedOrigins
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if slices.Contains(configuration.Current().Websocket.AllowedOrigins, origin) {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// uniqueTickers upper cases the tickers, skipping blanks and repeats
+func uniqueTickers(tickers []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+
+	for _, ticker := range tickers {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if ticker == "" || seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+		unique = append(unique, ticker)
+	}
+
+	return unique
+}
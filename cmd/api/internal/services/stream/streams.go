@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamTickers streams price updates for the requested tickers as server-sent events
+func StreamTickers(c *gin.Context, feed *Feed) {
+	var params StreamTickersDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	streamUpdates(c, feed, uniqueTickers(strings.Split(params.Tickers, ",")), params.LastEventId)
+}
+
+// StreamFavourites streams price updates for the caller's favourites as server-sent events, favourites
+// added after connecting are picked up on reconnect
+func StreamFavourites(c *gin.Context, stockDb StockRepository, feed *Feed) {
+	ctx := c.Request.Context()
+	var params StreamFavouritesDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respChan := make(chan *Response[[]string], 1)
+	go func() {
+		favourites := stockDb.GetFavouriteTickers(userId, ctx)
+		respChan <- &favourites
+	}()
+
+	select {
+	case favourites := <-respChan:
+		if favourites.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": favourites.Error.Error()})
+			return
+		}
+
+		streamUpdates(c, feed, favourites.Data, params.LastEventId)
+
+	case <-ctx.Done():
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+		return
+	}
+}
+
+// streamUpdates writes the feed's updates for the tickers until the client goes away or is dropped for
+// being too slow, with a heartbeat event every stream.heartbeatInterval to keep proxies from timing it out
+func streamUpdates(c *gin.Context, feed *Feed, tickers []string, lastEventIdParam string) {
+	ctx := c.Request.Context()
+	settings := configuration.Current().Stream
+
+	if len(tickers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": "at least one ticker is required"})
+		return
+	}
+	if len(tickers) > settings.MaxTickers {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": fmt.Sprintf("at most %d tickers can be streamed", settings.MaxTickers)})
+		return
+	}
+
+	//browsers send the header when they reconnect, the param is for clients that can't set it
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = lastEventIdParam
+	}
+	resumeFrom, err := strconv.ParseUint(lastEventId, 10, 64)
+	resuming := lastEventId != "" && err == nil
+
+	listener := feed.Subscribe(tickers, resumeFrom, resuming)
+	defer feed.Unsubscribe(listener)
+
+	heartbeat := time.NewTicker(time.Duration(settings.HeartbeatInterval))
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case update, ok := <-listener.Updates:
+			if !ok {
+				if listener.Dropped() {
+					c.Render(-1, sse.Event{Event: "dropped", Data: gin.H{
+						"error": "too far behind, reconnect with Last-Event-ID to resume",
+					}})
+				}
+				return false
+			}
+
+			c.Render(-1, sse.Event{Id: strconv.FormatUint(update.Id, 10), Event: "price", Data: update.PriceUpdate})
+			return true
+
+		case now := <-heartbeat.C:
+			c.Render(-1, sse.Event{Event: "heartbeat", Data: gin.H{"time": now.UTC()}})
+			return true
+
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
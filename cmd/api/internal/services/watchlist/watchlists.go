@@ -0,0 +1,399 @@
+package watchlist
+
+import (
+	"context"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	appCache "github.com/RobsonDevCode/GoApi/cmd/api/internal/cache"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/respond"
+	stockConcurrency "github.com/RobsonDevCode/GoApi/cmd/api/internal/services/stock/concurrency"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/tickers"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var cache = appCache.Shared
+
+// statuses the status each watchlist error is written with
+var statuses = respond.Statuses{
+	ErrWatchlistNotFound:       http.StatusNotFound,
+	ErrWatchlistTickerNotFound: http.StatusNotFound,
+	ErrWatchlistExists:         http.StatusConflict,
+	ErrWatchlistTickerExists:   http.StatusConflict,
+	ErrWatchlistOrderMismatch:  http.StatusBadRequest,
+}
+
+// ListWatchlists lists the caller's watchlists with their tickers in order
+func ListWatchlists(c *gin.Context, watchlistDb WatchlistRepository) {
+	ctx := c.Request.Context()
+	var params ListWatchlistsDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respChan := make(chan *Response[[]Watchlist], 1)
+	go func() {
+		watchlists := watchlistDb.ListWatchlists(userId, ctx)
+		respChan <- &watchlists
+	}()
+
+	select {
+	case result := <-respChan:
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": result.Data})
+
+	case <-ctx.Done():
+		respond.Timeout(c)
+		return
+	}
+}
+
+// GetWatchlist gets a single watchlist the caller owns
+func GetWatchlist(c *gin.Context, watchlistDb WatchlistRepository) {
+	var request WatchlistIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	watchlist, ok := ownedWatchlist(c, watchlistDb, request.Id)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": watchlist})
+}
+
+// CreateWatchlist creates an empty watchlist for the caller
+func CreateWatchlist(c *gin.Context, watchlistDb WatchlistRepository) {
+	ctx := c.Request.Context()
+	var request CreateWatchlistDto
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, request.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	watchlist := Watchlist{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Name:      strings.TrimSpace(request.Name),
+		CreatedAt: time.Now().UTC(),
+		Tickers:   []WatchlistTicker{},
+	}
+
+	if !respond.Run(c, statuses, func() error { return watchlistDb.CreateWatchlist(watchlist, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"created": watchlist})
+}
+
+// RenameWatchlist renames one of the caller's watchlists
+func RenameWatchlist(c *gin.Context, watchlistDb WatchlistRepository) {
+	ctx := c.Request.Context()
+	var uri WatchlistIdDto
+	var request RenameWatchlistDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedWatchlist(c, watchlistDb, uri.Id); !ok {
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if !respond.Run(c, statuses, func() error { return watchlistDb.RenameWatchlist(uri.Id, name, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": uri.Id})
+}
+
+// DeleteWatchlist deletes one of the caller's watchlists, the default watchlist can't be deleted
+func DeleteWatchlist(c *gin.Context, watchlistDb WatchlistRepository) {
+	ctx := c.Request.Context()
+	var request WatchlistIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	watchlist, ok := ownedWatchlist(c, watchlistDb, request.Id)
+	if !ok {
+		return
+	}
+	if watchlist.IsDefault {
+		c.JSON(http.StatusConflict, gin.H{"error": "the default watchlist can't be deleted"})
+		return
+	}
+
+	if !respond.Run(c, statuses, func() error { return watchlistDb.DeleteWatchlist(request.Id, ctx) }) {
+		return
+	}
+
+	cache.Delete(openCloseKey(request.Id))
+	c.JSON(http.StatusOK, gin.H{"deleted": request.Id})
+}
+
+// AddTicker adds a ticker to the end of one of the caller's watchlists
+func AddTicker(c *gin.Context, watchlistDb WatchlistRepository, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var uri WatchlistIdDto
+	var request AddWatchlistTickerDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	watchlist, ok := ownedWatchlist(c, watchlistDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	symbol, ok := tickers.ValidTicker(c, pa, request.Ticker)
+	if !ok {
+		return
+	}
+
+	ticker := WatchlistTicker{
+		Ticker:  symbol,
+		Note:    request.Note,
+		AddedAt: time.Now().UTC(),
+	}
+
+	if !respond.Run(c, statuses, func() error { return watchlistDb.AddWatchlistTicker(uri.Id, ticker, ctx) }) {
+		return
+	}
+
+	clearOpenClose(watchlist)
+	c.JSON(http.StatusCreated, gin.H{"created": ticker.Ticker})
+}
+
+// UpdateTickerNote sets the note on a ticker in one of the caller's watchlists
+func UpdateTickerNote(c *gin.Context, watchlistDb WatchlistRepository) {
+	ctx := c.Request.Context()
+	var uri WatchlistTickerUriDto
+	var request WatchlistTickerNoteDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedWatchlist(c, watchlistDb, uri.Id); !ok {
+		return
+	}
+
+	ticker := normaliseTicker(uri.Ticker)
+	if !respond.Run(c, statuses, func() error { return watchlistDb.UpdateWatchlistTickerNote(uri.Id, ticker, request.Note, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": ticker})
+}
+
+// RemoveTicker removes a ticker from one of the caller's watchlists
+func RemoveTicker(c *gin.Context, watchlistDb WatchlistRepository) {
+	ctx := c.Request.Context()
+	var uri WatchlistTickerUriDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	watchlist, ok := ownedWatchlist(c, watchlistDb, uri.Id)
+	if !ok {
+		return
+	}
+
+	ticker := normaliseTicker(uri.Ticker)
+	if !respond.Run(c, statuses, func() error { return watchlistDb.RemoveWatchlistTicker(uri.Id, ticker, ctx) }) {
+		return
+	}
+
+	clearOpenClose(watchlist)
+	c.JSON(http.StatusOK, gin.H{"deleted": ticker})
+}
+
+// ReorderTickers sets the order of the tickers in one of the caller's watchlists
+func ReorderTickers(c *gin.Context, watchlistDb WatchlistRepository) {
+	ctx := c.Request.Context()
+	var uri WatchlistIdDto
+	var request ReorderWatchlistDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedWatchlist(c, watchlistDb, uri.Id); !ok {
+		return
+	}
+
+	tickers := make([]string, len(request.Tickers))
+	for i, ticker := range request.Tickers {
+		tickers[i] = normaliseTicker(ticker)
+	}
+
+	if !respond.Run(c, statuses, func() error { return watchlistDb.ReorderWatchlist(uri.Id, tickers, ctx) }) {
+		return
+	}
+
+	cache.Delete(openCloseKey(uri.Id))
+	c.JSON(http.StatusOK, gin.H{"updated": tickers})
+}
+
+// GetWatchlistOpenClose gets yesterday's open and close for every ticker in the watchlist concurrently,
+// results keep the watchlist's order and tickers that couldn't be fetched are listed under errors
+func GetWatchlistOpenClose(c *gin.Context, watchlistDb WatchlistRepository, pa *intergration.PolygonApi) {
+	ctx := c.Request.Context()
+	var request WatchlistIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	watchlist, ok := ownedWatchlist(c, watchlistDb, request.Id)
+	if !ok {
+		return
+	}
+
+	//check if result has been cached
+	key := openCloseKey(request.Id)
+	if cacheResult, ok := cache.Get(key); ok {
+		c.JSON(http.StatusOK, gin.H{"data": cacheResult})
+		return
+	}
+
+	tickers := make([]string, len(watchlist.Tickers))
+	for i, ticker := range watchlist.Tickers {
+		tickers[i] = ticker.Ticker
+	}
+	if len(tickers) == 0 {
+		c.JSON(http.StatusOK, gin.H{"data": []*polyModels.GetDailyOpenCloseAggResponse{}})
+		return
+	}
+
+	processor := stockConcurrency.NewPolyDataProcessor(pa, 10)
+	bySymbol, errs := processor.FetchOpenCloses(ctx, tickers)
+
+	if ctx.Err() != nil {
+		respond.Timeout(c)
+		return
+	}
+
+	failed := make(map[string]string, len(errs))
+	for ticker, err := range errs {
+		log.Errorf("open/close for %s in watchlist %s: %s", ticker, request.Id, err)
+		failed[ticker] = err.Error()
+	}
+	if len(bySymbol) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid responses received", "errors": failed})
+		return
+	}
+
+	response := make([]*polyModels.GetDailyOpenCloseAggResponse, 0, len(bySymbol))
+	for _, ticker := range tickers {
+		if result, ok := bySymbol[ticker]; ok {
+			response = append(response, result)
+		}
+	}
+
+	//only cache complete results so a failed ticker is retried on the next request, the tickers can change
+	//while the market is closed so they don't get the closed ttl
+	if len(errs) > 0 {
+		c.JSON(http.StatusOK, gin.H{"data": response, "errors": failed})
+		return
+	}
+	cache.Set(key, response, time.Duration(configuration.Current().Cache.OpenCloseTtl))
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// ownedWatchlist loads the watchlist and checks the caller owns it, admins can use anyone's. Someone
+// else's watchlist is reported as not found. The error response has been written when ok is false
+func ownedWatchlist(c *gin.Context, watchlistDb WatchlistRepository, id string) (*Watchlist, bool) {
+	return respond.Owned(c, statuses, ErrWatchlistNotFound, func(ctx context.Context) Response[*Watchlist] {
+		return watchlistDb.GetWatchlist(id, ctx)
+	}, func(watchlist *Watchlist) string { return watchlist.UserId })
+}
+
+func normaliseTicker(ticker string) string {
+	return strings.ToUpper(strings.TrimSpace(ticker))
+}
+
+func openCloseKey(id string) string {
+	return appCache.WatchlistOpenCloseKey(id)
+}
+
+// clearOpenClose drops the watchlist's cached open/close, the default watchlist's tickers are the user's
+// favourites so theirs is dropped too
+func clearOpenClose(watchlist *Watchlist) {
+	cache.Delete(openCloseKey(watchlist.Id))
+	if watchlist.IsDefault {
+		cache.Delete(appCache.FavouritesOpenCloseKey(watchlist.UserId))
+	}
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+var errPrivateAddress = errors.New("webhooks can't be sent to private, loopback or link-local addresses")
+
+// reservedPrefixes ranges that aren't public but that netip doesn't class as private or local
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      //this network
+	netip.MustParsePrefix("100.64.0.0/10"),  //carrier grade nat
+	netip.MustParsePrefix("192.0.0.0/24"),   //ietf protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  //benchmarking
+	netip.MustParsePrefix("64:ff9b:1::/48"), //local use nat64
+}
+
+// publicAddress false for addresses that would let a webhook reach the api's own network, e.g. the cloud
+// metadata endpoint on 169.254.169.254 or a database on 10.0.0.0/8
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() || addr.IsMulticast() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkHost rejects a webhook host that resolves to an address that isn't public, unless
+// webhooks.allowPrivateNetworks is set
+func checkHost(ctx context.Context, host string) error {
+	if configuration.Current().Webhooks.AllowPrivateNetworks {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("url host %s couldn't be resolved: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// dialControl checks the address a delivery is actually sent to, the host was checked when the webhook was
+// registered but its dns could since have been pointed somewhere private
+func dialControl(network string, address string, conn syscall.RawConn) error {
+	if configuration.Current().Webhooks.AllowPrivateNetworks {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(addrPort.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/events"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+// writeTimeout how long writing an event to the outbox can take
+const writeTimeout = 5 * time.Second
+
+// queueSize events waiting to be written before publishers have to wait
+const queueSize = 1024
+
+// Outbox writes events to the webhook_deliveries table for the Worker to send, once written a delivery
+// isn't lost if an endpoint is down or the api restarts. Events are queued and written on the outbox's own
+// goroutine so publishers, usually handling a request, don't wait on the database, anything still queued
+// when the api stops is lost
+type Outbox struct {
+	webhookDb *WebhookRepository
+	queue     chan events.Event
+}
+
+func NewOutbox(webhookDb *WebhookRepository) *Outbox {
+	return &Outbox{webhookDb: webhookDb, queue: make(chan events.Event, queueSize)}
+}
+
+// Start writes queued events to the outbox until ctx is done
+func (o *Outbox) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case event := <-o.queue:
+				o.write(event)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Enqueue queues the event to be written to the outbox, subscribe it to the event bus. It only blocks
+// the publisher while the queue is full, an event still waiting after writeTimeout is dropped
+func (o *Outbox) Enqueue(event events.Event) {
+	select {
+	case o.queue <- event:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(writeTimeout)
+	defer timer.Stop()
+
+	select {
+	case o.queue <- event:
+	case <-timer.C:
+		log.Errorf("webhook outbox is full, dropped %s event %s", event.Type, event.Id)
+	}
+}
+
+// write adds a delivery of the event for each of the user's webhooks that want it
+func (o *Outbox) write(event events.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	webhooks := o.webhookDb.ListSubscribedWebhooks(event.UserId, event.Type, ctx)
+	if webhooks.Error != nil {
+		log.Errorf("couldn't find webhooks for %s event %s: %s", event.Type, event.Id, webhooks.Error)
+		return
+	}
+	if len(webhooks.Data) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("couldn't serialise %s event %s: %s", event.Type, event.Id, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]WebhookDelivery, 0, len(webhooks.Data))
+	for _, webhook := range webhooks.Data {
+		deliveries = append(deliveries, WebhookDelivery{
+			Id:            uuid.NewString(),
+			WebhookId:     webhook.Id,
+			EventId:       event.Id,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	if err := o.webhookDb.EnqueueDeliveries(deliveries, ctx); err != nil {
+		log.Errorf("couldn't enqueue %s event %s: %s", event.Type, event.Id, err)
+	}
+}
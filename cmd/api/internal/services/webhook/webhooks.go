@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/dtos"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/auth"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/services/respond"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const defaultDeliveriesLimit = 50
+
+// statuses the status each webhook error is written with
+var statuses = respond.Statuses{
+	ErrWebhookNotFound:  http.StatusNotFound,
+	ErrDeliveryNotFound: http.StatusNotFound,
+	ErrTooManyWebhooks:  http.StatusConflict,
+}
+
+// ListWebhooks lists the caller's webhooks, secrets aren't returned
+func ListWebhooks(c *gin.Context, webhookDb WebhookRepository) {
+	ctx := c.Request.Context()
+	var params ListWebhooksDto
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, params.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhooks, ok := respond.Fetch(c, statuses, func(ctx context.Context) Response[[]Webhook] {
+		return webhookDb.ListWebhooks(userId, ctx)
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhooks})
+}
+
+// CreateWebhook registers an endpoint for the caller. The response is the only time the signing secret
+// is returned
+func CreateWebhook(c *gin.Context, webhookDb WebhookRepository) {
+	ctx := c.Request.Context()
+	var request CreateWebhookDto
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	parsed, err := url.Parse(request.Url)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": "url must be http or https"})
+		return
+	}
+	if err := checkHost(ctx, parsed.Hostname()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	userId, err := auth.ResolveUserId(ctx, request.UserId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		log.Errorf("error generating webhook secret: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	eventTypes := slices.Clone(request.Events)
+	slices.Sort(eventTypes)
+
+	webhook := Webhook{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Url:       request.Url,
+		Secret:    secret,
+		Events:    slices.Compact(eventTypes),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	maxPerUser := configuration.Current().Webhooks.MaxPerUser
+	if !respond.Run(c, statuses, func() error { return webhookDb.CreateWebhook(webhook, maxPerUser, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"created": webhook})
+}
+
+// DeleteWebhook deletes one of the caller's webhooks, undelivered events for it are dropped
+func DeleteWebhook(c *gin.Context, webhookDb WebhookRepository) {
+	ctx := c.Request.Context()
+	var request WebhookIdDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedWebhook(c, webhookDb, request.Id); !ok {
+		return
+	}
+
+	if !respond.Run(c, statuses, func() error { return webhookDb.DeleteWebhook(request.Id, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": request.Id})
+}
+
+// ListDeliveries lists a webhook's deliveries newest first, filter on status=dead to find what needs replaying
+func ListDeliveries(c *gin.Context, webhookDb WebhookRepository) {
+	var uri WebhookIdDto
+	var params ListDeliveriesDto
+
+	if err := c.ShouldBindUri(&uri); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultDeliveriesLimit
+	}
+
+	if _, ok := ownedWebhook(c, webhookDb, uri.Id); !ok {
+		return
+	}
+
+	deliveries, ok := respond.Fetch(c, statuses, func(ctx context.Context) Response[[]WebhookDelivery] {
+		return webhookDb.ListDeliveries(uri.Id, params.Status, params.Limit, ctx)
+	})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+// ReplayDelivery queues a delivery to be sent again straight away with a fresh set of attempts, usually
+// one that was dead lettered
+func ReplayDelivery(c *gin.Context, webhookDb WebhookRepository) {
+	ctx := c.Request.Context()
+	var request WebhookDeliveryUriDto
+
+	if err := c.ShouldBindUri(&request); err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+		return
+	}
+
+	if _, ok := ownedWebhook(c, webhookDb, request.Id); !ok {
+		return
+	}
+
+	delivery, ok := respond.Fetch(c, statuses, func(ctx context.Context) Response[*WebhookDelivery] {
+		return webhookDb.GetDelivery(request.Id, request.DeliveryId, ctx)
+	})
+	if !ok {
+		return
+	}
+
+	replayed := replay(*delivery)
+	if !respond.Run(c, statuses, func() error { return webhookDb.UpdateDelivery(replayed, ctx) }) {
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"updated": replayed})
+}
+
+// ownedWebhook loads the webhook and checks the caller owns it, admins can use anyone's. Someone else's
+// webhook is reported as not found. The error response has been written when ok is false
+func ownedWebhook(c *gin.Context, webhookDb WebhookRepository, id string) (*Webhook, bool) {
+	return respond.Owned(c, statuses, ErrWebhookNotFound, func(ctx context.Context) Response[*Webhook] {
+		return webhookDb.GetWebhook(id, ctx)
+	}, func(webhook *Webhook) string { return webhook.UserId })
+}
+
+// newSecret a random signing secret
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
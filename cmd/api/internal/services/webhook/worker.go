@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/labstack/gommon/log"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// headers sent with every delivery. The signature is "sha256=" then the hex HMAC-SHA256 of
+// "{timestamp}.{body}" keyed with the webhook's secret, receivers should recompute it and reject
+// timestamps that are too old
+const (
+	SignatureHeader = "X-GoApi-Signature"
+	TimestampHeader = "X-GoApi-Timestamp"
+	EventHeader     = "X-GoApi-Event"
+	DeliveryHeader  = "X-GoApi-Delivery"
+)
+
+// Worker sends due deliveries from the outbox. Failures are retried with exponential backoff until
+// webhooks.maxAttempts is reached, then the delivery is dead lettered until it's replayed
+type Worker struct {
+	webhookDb DeliveryStore
+	client    *http.Client
+}
+
+// DeliveryStore the outbox the Worker claims deliveries from and records attempts in, a WebhookRepository
+type DeliveryStore interface {
+	ClaimDueDeliveries(limit int, lease time.Duration, ctx context.Context) Response[[]DueDelivery]
+	UpdateDelivery(delivery WebhookDelivery, ctx context.Context) error
+}
+
+func NewWorker(webhookDb DeliveryStore) *Worker {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialControl}
+
+	return &Worker{
+		webhookDb: webhookDb,
+		client: &http.Client{
+			//no proxy so the dialer sees the endpoint's address rather than the proxy's
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+			//a redirect could send the signed payload somewhere the user didn't register
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Start sends due deliveries every webhooks.pollInterval until ctx is done, a full batch is followed
+// straight away by the next
+func (w *Worker) Start(ctx context.Context) {
+	schedule.Loop(ctx, func(ctx context.Context) time.Duration {
+		sent, err := w.DeliverDue(ctx)
+		if err != nil {
+			log.Errorf("webhook delivery failed: %s", err)
+		}
+		if sent >= configuration.Current().Webhooks.BatchSize {
+			return 0
+		}
+		return time.Duration(configuration.Current().Webhooks.PollInterval)
+	})
+}
+
+// DeliverDue claims a batch of due deliveries and sends them concurrently, returning how many were attempted
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	settings := configuration.Current().Webhooks
+	timeout := time.Duration(settings.Timeout)
+
+	//the lease outlasts the request so a slow endpoint isn't sent the delivery twice
+	due := w.webhookDb.ClaimDueDeliveries(settings.BatchSize, 2*timeout, ctx)
+	if due.Error != nil {
+		return 0, due.Error
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range due.Data {
+		wg.Add(1)
+		go func(delivery DueDelivery) {
+			defer wg.Done()
+
+			attempted := w.deliver(ctx, delivery, timeout)
+			attempted = afterAttempt(attempted, settings.MaxAttempts, time.Duration(settings.InitialBackoff),
+				time.Duration(settings.MaxBackoff))
+
+			if attempted.Status == DeliveryDead {
+				log.Warnf("webhook delivery %s dead lettered after %d attempts: %s", attempted.Id, attempted.Attempts,
+					attempted.LastError)
+			}
+			if err := w.webhookDb.UpdateDelivery(attempted, ctx); err != nil {
+				log.Errorf("error saving attempt of delivery %s: %s", attempted.Id, err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(due.Data), nil
+}
+
+// deliver posts the payload, the returned delivery has the attempt's outcome but not its next status
+func (w *Worker) deliver(ctx context.Context, due DueDelivery, timeout time.Duration) WebhookDelivery {
+	delivery := due.WebhookDelivery
+	delivery.Attempts++
+	delivery.LastError = ""
+	delivery.LastStatusCode = nil
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, due.Url, bytes.NewReader(body))
+	if err != nil {
+		delivery.LastError = err.Error()
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoApi-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.Id)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(due.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		delivery.LastError = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+
+	statusCode := resp.StatusCode
+	delivery.LastStatusCode = &statusCode
+	if statusCode < 200 || statusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		delivery.LastError = fmt.Sprintf("endpoint responded %d: %s", statusCode, snippet)
+	}
+
+	return delivery
+}
+
+// Sign the signature sent in SignatureHeader for a body sent at timestamp
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// afterAttempt works out the delivery's status after an attempt: delivered, dead lettered once it's out
+// of attempts, or pending with the next attempt backed off
+func afterAttempt(delivery WebhookDelivery, maxAttempts int, initialBackoff time.Duration, maxBackoff time.Duration) WebhookDelivery {
+	now := time.Now().UTC()
+
+	switch {
+	case delivery.LastError == "":
+		delivery.Status = DeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= maxAttempts:
+		delivery.Status = DeliveryDead
+	default:
+		delivery.Status = DeliveryPending
+		delivery.NextAttemptAt = now.Add(schedule.Backoff(delivery.Attempts, initialBackoff, maxBackoff))
+	}
+
+	return delivery
+}
+
+// replay puts the delivery back in the outbox to be sent straight away with a fresh set of attempts
+func replay(delivery WebhookDelivery) WebhookDelivery {
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	delivery.LastError = ""
+	delivery.LastStatusCode = nil
+	delivery.DeliveredAt = nil
+	return delivery
+}
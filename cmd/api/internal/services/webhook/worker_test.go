@@ -0,0 +1,292 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSettings = `{
+	"connectionStrings": {"stocksDb": "test"},
+	"apiSettings": {"key": "test"},
+	"webhooks": {
+		"timeout": "5s",
+		"batchSize": 10,
+		"maxAttempts": 3,
+		"initialBackoff": "1m",
+		"maxBackoff": "90s",
+		"allowPrivateNetworks": %s
+	}
+}`
+
+// useSettings makes the test configuration current, private networks have to be allowed to reach httptest
+func useSettings(t *testing.T, allowPrivateNetworks bool) {
+	t.Helper()
+
+	dir := t.TempDir()
+	allow := "false"
+	if allowPrivateNetworks {
+		allow = "true"
+	}
+	settings := []byte(fmt.Sprintf(testSettings, allow))
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), settings, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// memoryStore an outbox in memory, claiming mirrors the repository: pending deliveries that are due
+type memoryStore struct {
+	mu         sync.Mutex
+	url        string
+	secret     string
+	deliveries map[string]WebhookDelivery
+}
+
+func newMemoryStore(url string, secret string, deliveries ...WebhookDelivery) *memoryStore {
+	store := &memoryStore{url: url, secret: secret, deliveries: make(map[string]WebhookDelivery)}
+	for _, delivery := range deliveries {
+		store.deliveries[delivery.Id] = delivery
+	}
+	return store
+}
+
+func (m *memoryStore) ClaimDueDeliveries(limit int, lease time.Duration, ctx context.Context) Response[[]DueDelivery] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []DueDelivery
+	for id, delivery := range m.deliveries {
+		if len(due) == limit || delivery.Status != DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		due = append(due, DueDelivery{WebhookDelivery: delivery, Url: m.url, Secret: m.secret})
+		delivery.NextAttemptAt = now.Add(lease)
+		m.deliveries[id] = delivery
+	}
+	return Response[[]DueDelivery]{Data: due, Error: nil}
+}
+
+func (m *memoryStore) UpdateDelivery(delivery WebhookDelivery, ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deliveries[delivery.Id] = delivery
+	return nil
+}
+
+func (m *memoryStore) get(id string) WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deliveries[id]
+}
+
+// makeDue brings the delivery's next attempt forward to now, as if its backoff had passed
+func (m *memoryStore) makeDue(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery := m.deliveries[id]
+	delivery.NextAttemptAt = time.Now().UTC()
+	m.deliveries[id] = delivery
+}
+
+func pendingDelivery(id string) WebhookDelivery {
+	now := time.Now().UTC()
+	return WebhookDelivery{
+		Id:            id,
+		WebhookId:     "webhook-1",
+		EventId:       "event-" + id,
+		EventType:     "favourite.added",
+		Payload:       `{"id":"event-` + id + `","type":"favourite.added"}`,
+		Status:        DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+func deliverDue(t *testing.T, worker *Worker) {
+	t.Helper()
+
+	if _, err := worker.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliveryIsSigned(t *testing.T) {
+	useSettings(t, true)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+	}))
+	defer server.Close()
+
+	delivery := pendingDelivery("1")
+	store := newMemoryStore(server.URL, "whsec_test", delivery)
+	deliverDue(t, NewWorker(store))
+
+	request := <-requests
+	if string(request.body) != delivery.Payload {
+		t.Fatalf("body %q, want the payload %q", request.body, delivery.Payload)
+	}
+
+	timestamp := request.header.Get(TimestampHeader)
+	if want := Sign("whsec_test", timestamp, request.body); request.header.Get(SignatureHeader) != want {
+		t.Fatalf("signature %q, want %q", request.header.Get(SignatureHeader), want)
+	}
+	if request.header.Get(EventHeader) != delivery.EventType || request.header.Get(DeliveryHeader) != delivery.Id {
+		t.Fatalf("event %q and delivery %q headers, want %q and %q", request.header.Get(EventHeader),
+			request.header.Get(DeliveryHeader), delivery.EventType, delivery.Id)
+	}
+
+	if sent := store.get(delivery.Id); sent.Status != DeliveryDelivered || sent.Attempts != 1 || sent.DeliveredAt == nil {
+		t.Fatalf("delivery %+v, want delivered on the first attempt", sent)
+	}
+}
+
+func TestFailedDeliveriesBackOffThenDie(t *testing.T) {
+	useSettings(t, true)
+
+	var mu sync.Mutex
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	delivery := pendingDelivery("1")
+	store := newMemoryStore(server.URL, "whsec_test", delivery)
+	worker := NewWorker(store)
+
+	//initialBackoff is 1m doubling after each failure, capped by maxBackoff at 90s
+	for attempt, wantBackoff := range []time.Duration{time.Minute, 90 * time.Second} {
+		before := time.Now().UTC()
+		deliverDue(t, worker)
+
+		failed := store.get(delivery.Id)
+		if failed.Status != DeliveryPending || failed.Attempts != attempt+1 {
+			t.Fatalf("after attempt %d got %s with %d attempts, want pending", attempt+1, failed.Status, failed.Attempts)
+		}
+		if failed.LastStatusCode == nil || *failed.LastStatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("after attempt %d last status code %v, want 503", attempt+1, failed.LastStatusCode)
+		}
+		if backoff := failed.NextAttemptAt.Sub(before); backoff < wantBackoff || backoff > wantBackoff+time.Second {
+			t.Fatalf("after attempt %d next attempt in %s, want %s", attempt+1, backoff, wantBackoff)
+		}
+
+		//not due until the backoff has passed
+		deliverDue(t, worker)
+		if store.get(delivery.Id).Attempts != attempt+1 {
+			t.Fatalf("delivery was sent again before its backoff passed")
+		}
+		store.makeDue(delivery.Id)
+	}
+
+	deliverDue(t, worker)
+	dead := store.get(delivery.Id)
+	if dead.Status != DeliveryDead || dead.Attempts != 3 {
+		t.Fatalf("after maxAttempts got %s with %d attempts, want dead", dead.Status, dead.Attempts)
+	}
+
+	//dead letters stay put until they're replayed
+	deliverDue(t, worker)
+	if store.get(delivery.Id).Attempts != 3 {
+		t.Fatal("dead delivery was sent again")
+	}
+
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+
+	replayed := replay(dead)
+	if replayed.Status != DeliveryPending || replayed.Attempts != 0 || replayed.LastError != "" ||
+		replayed.LastStatusCode != nil {
+		t.Fatalf("replayed delivery %+v, want pending with a fresh set of attempts", replayed)
+	}
+	if err := store.UpdateDelivery(replayed, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	deliverDue(t, worker)
+	if sent := store.get(delivery.Id); sent.Status != DeliveryDelivered || sent.Attempts != 1 {
+		t.Fatalf("replayed delivery %+v, want delivered on its first new attempt", sent)
+	}
+}
+
+func TestDeliveriesToPrivateAddressesAreRefused(t *testing.T) {
+	useSettings(t, false)
+
+	var sent atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent.Store(true)
+	}))
+	defer server.Close()
+
+	delivery := pendingDelivery("1")
+	store := newMemoryStore(server.URL, "whsec_test", delivery)
+	deliverDue(t, NewWorker(store))
+
+	if sent.Load() {
+		t.Fatal("delivery reached a loopback address")
+	}
+	if failed := store.get(delivery.Id); failed.Status != DeliveryPending || failed.LastError == "" {
+		t.Fatalf("delivery %+v, want a failed attempt", failed)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			if got := publicAddress(netip.MustParseAddr(test.address)); got != test.want {
+				t.Fatalf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
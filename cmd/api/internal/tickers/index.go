@@ -0,0 +1,213 @@
+package tickers
+
+import (
+	"context"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/schedule"
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/labstack/gommon/log"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Market the market the index holds, searches of anything else go to polygon
+const Market = "stocks"
+
+// entry a key searched by prefix and the ticker it leads to
+type entry struct {
+	key    string
+	ticker int
+	start  bool //key is the whole name rather than from a later word
+}
+
+// universe one load of every active ticker, swapped as a whole so searches never see half a refresh
+type universe struct {
+	tickers  []models.TickerSummary
+	bySymbol map[string]int
+	symbols  []entry //ticker symbols, sorted
+	names    []entry //the company name from the start of each word lower cased, sorted
+}
+
+// Index every active stock ticker held in memory for prefix search, loaded from polygon on start and
+// refreshed daily at tickers.refreshAt so autocomplete doesn't make a request per keystroke
+type Index struct {
+	current atomic.Pointer[universe]
+}
+
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// Shared the process wide index
+var Shared = NewIndex()
+
+// Start loads the index now and again every day at tickers.refreshAt until ctx is done, the time is read
+// before each wait so reloads are picked up
+func (i *Index) Start(ctx context.Context, pa *intergration.PolygonApi) {
+	schedule.Daily(ctx, func() string {
+		return configuration.Current().Tickers.RefreshAt
+	}, func(ctx context.Context, _ time.Time) {
+		if err := i.Refresh(ctx, pa); err != nil {
+			log.Errorf("ticker index refresh failed, searches use what it has: %s", err)
+		}
+	})
+}
+
+// Refresh reads every active stock ticker from polygon and swaps them in
+func (i *Index) Refresh(ctx context.Context, pa *intergration.PolygonApi) error {
+	market := polyModels.AssetStocks
+	active := true
+	limit := 1000
+
+	response := pa.ListTickers(&polyModels.ListTickersParams{Market: &market, Active: &active, Limit: &limit}, 0, ctx)
+	if response.Error != nil {
+		return response.Error
+	}
+
+	summaries := make([]models.TickerSummary, 0, len(response.Data))
+	for _, ticker := range response.Data {
+		summaries = append(summaries, Summary(ticker))
+	}
+
+	i.current.Store(build(summaries))
+	log.Infof("ticker index loaded %d tickers", len(summaries))
+	return nil
+}
+
+// Ready whether the index has loaded
+func (i *Index) Ready() bool {
+	return i.current.Load() != nil
+}
+
+// Lookup the ticker's summary, false when it isn't an active stock or the index hasn't loaded
+func (i *Index) Lookup(ticker string) (models.TickerSummary, bool) {
+	u := i.current.Load()
+	if u == nil {
+		return models.TickerSummary{}, false
+	}
+
+	position, ok := u.bySymbol[strings.ToUpper(strings.TrimSpace(ticker))]
+	if !ok {
+		return models.TickerSummary{}, false
+	}
+	return u.tickers[position], true
+}
+
+// Search up to limit tickers whose symbol or a word of whose name starts with query, filtered to
+// tickerType when it's set. An exact symbol comes first, then symbol matches shortest first, then names
+// starting with query, then names with a later word starting with it, shortest first
+func (i *Index) Search(query string, tickerType string, limit int) []models.TickerSummary {
+	results := []models.TickerSummary{}
+
+	u := i.current.Load()
+	query = strings.TrimSpace(query)
+	if u == nil || query == "" {
+		return results
+	}
+
+	seen := make(map[int]bool)
+	add := func(position int) {
+		if seen[position] || len(results) >= limit {
+			return
+		}
+		if tickerType != "" && !strings.EqualFold(u.tickers[position].Type, tickerType) {
+			return
+		}
+		seen[position] = true
+		results = append(results, u.tickers[position])
+	}
+
+	symbol := strings.ToUpper(query)
+	if position, ok := u.bySymbol[symbol]; ok {
+		add(position)
+	}
+
+	symbolMatches := withPrefix(u.symbols, symbol)
+	sort.SliceStable(symbolMatches, func(a, b int) bool {
+		return len(symbolMatches[a].key) < len(symbolMatches[b].key)
+	})
+	for _, match := range symbolMatches {
+		add(match.ticker)
+	}
+
+	nameMatches := withPrefix(u.names, strings.ToLower(query))
+	sort.SliceStable(nameMatches, func(a, b int) bool {
+		if nameMatches[a].start != nameMatches[b].start {
+			return nameMatches[a].start
+		}
+		return len(nameMatches[a].key) < len(nameMatches[b].key)
+	})
+	for _, match := range nameMatches {
+		add(match.ticker)
+	}
+
+	return results
+}
+
+// Summary the parts of polygon's ticker that search returns
+func Summary(ticker polyModels.Ticker) models.TickerSummary {
+	return models.TickerSummary{
+		Ticker:          ticker.Ticker,
+		Name:            ticker.Name,
+		Market:          ticker.Market,
+		Type:            ticker.Type,
+		PrimaryExchange: ticker.PrimaryExchange,
+		Currency:        strings.ToUpper(ticker.CurrencyName),
+		Active:          ticker.Active,
+	}
+}
+
+// build sorts the tickers' symbols and names for prefix search
+func build(tickers []models.TickerSummary) *universe {
+	u := &universe{
+		tickers:  tickers,
+		bySymbol: make(map[string]int, len(tickers)),
+		symbols:  make([]entry, 0, len(tickers)),
+	}
+
+	for position, ticker := range tickers {
+		u.bySymbol[ticker.Ticker] = position
+		u.symbols = append(u.symbols, entry{key: ticker.Ticker, ticker: position})
+
+		name := strings.ToLower(ticker.Name)
+		for start := 0; start < len(name); {
+			u.names = append(u.names, entry{key: name[start:], ticker: position, start: start == 0})
+
+			next := strings.IndexByte(name[start:], ' ')
+			if next < 0 {
+				break
+			}
+			start += next + 1
+		}
+	}
+
+	byKey := func(entries []entry) func(a, b int) bool {
+		return func(a, b int) bool {
+			return entries[a].key < entries[b].key
+		}
+	}
+	sort.Slice(u.symbols, byKey(u.symbols))
+	sort.Slice(u.names, byKey(u.names))
+
+	return u
+}
+
+// withPrefix the entries whose key starts with prefix, entries is sorted by key
+func withPrefix(entries []entry, prefix string) []entry {
+	start := sort.Search(len(entries), func(i int) bool {
+		return entries[i].key >= prefix
+	})
+
+	end := start
+	for end < len(entries) && strings.HasPrefix(entries[end].key, prefix) {
+		end++
+	}
+
+	matches := make([]entry, end-start)
+	copy(matches, entries[start:end])
+	return matches
+}
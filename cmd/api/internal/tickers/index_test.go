@@ -0,0 +1,91 @@
+package tickers
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"slices"
+	"testing"
+)
+
+func testIndex() *Index {
+	index := NewIndex()
+	index.current.Store(build([]models.TickerSummary{
+		{Ticker: "APPS", Name: "Digital Turbine Inc", Type: "CS"},
+		{Ticker: "AAPL", Name: "Apple Inc", Type: "CS"},
+		{Ticker: "APLE", Name: "Apple Hospitality REIT Inc", Type: "CS"},
+		{Ticker: "AP", Name: "Ampco-Pittsburgh Corp", Type: "CS"},
+		{Ticker: "APPF", Name: "AppFolio Inc", Type: "CS"},
+		{Ticker: "APPLW", Name: "Appleseed Acquisition Warrants", Type: "WARRANT"},
+		{Ticker: "PAPL", Name: "Pineapple Energy Inc", Type: "CS"},
+		{Ticker: "GAPL", Name: "Green Apple Group", Type: "CS"},
+		{Ticker: "BTAPL", Name: "Big Tree Apple Farms Holdings Corporation", Type: "CS"},
+		{Ticker: "MSFT", Name: "Microsoft Corp", Type: "CS"},
+	}))
+	return index
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		tickerType string
+		limit      int
+		want       []string
+	}{
+		{"exact symbol first", "ap", "", 10, []string{"AP", "APLE", "APPF", "APPS", "APPLW", "AAPL", "GAPL", "BTAPL"}},
+		{"shorter symbols first", "app", "", 10, []string{"APPF", "APPS", "APPLW", "AAPL", "APLE", "GAPL", "BTAPL"}},
+		{"names starting with query shortest first, then later words shortest first", "apple", "", 10,
+			[]string{"AAPL", "APLE", "APPLW", "GAPL", "BTAPL"}},
+		{"ticker matching its symbol and name only once", "appf", "", 10, []string{"APPF"}},
+		{"case and spaces ignored", "  MiCro ", "", 10, []string{"MSFT"}},
+		{"limit", "app", "", 3, []string{"APPF", "APPS", "APPLW"}},
+		{"type filter", "app", "CS", 10, []string{"APPF", "APPS", "AAPL", "APLE", "GAPL", "BTAPL"}},
+		{"type filter ignores case", "app", "warrant", 10, []string{"APPLW"}},
+		{"type filter before the limit", "ap", "warrant", 1, []string{"APPLW"}},
+		{"word in the middle of a word isn't a match", "pple", "", 10, []string{}},
+		{"blank query", " ", "", 10, []string{}},
+		{"no match", "zzz", "", 10, []string{}},
+	}
+
+	index := testIndex()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, summary := range index.Search(test.query, test.tickerType, test.limit) {
+				got = append(got, summary.Ticker)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchBeforeLoading(t *testing.T) {
+	if got := NewIndex().Search("aapl", "", 10); len(got) != 0 {
+		t.Fatalf("got %v, want nothing", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		ticker string
+		want   string
+		wantOk bool
+	}{
+		{"symbol", "AAPL", "Apple Inc", true},
+		{"lower case and spaces", " aapl ", "Apple Inc", true},
+		{"prefix isn't a match", "AAP", "", false},
+		{"unknown", "ZZZZ", "", false},
+	}
+
+	index := testIndex()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := index.Lookup(test.ticker)
+			if ok != test.wantOk || got.Name != test.want {
+				t.Fatalf("got %q %t, want %q %t", got.Name, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
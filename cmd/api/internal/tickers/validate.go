@@ -0,0 +1,173 @@
+package tickers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	intergration "github.com/RobsonDevCode/GoApi/cmd/api/polygonApi"
+	"github.com/gin-gonic/gin"
+	polyModels "github.com/polygon-io/client-go/rest/models"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions the most "did you mean" suggestions given for an unknown ticker
+const maxSuggestions = 3
+
+var (
+	ErrUnknownTicker  = errors.New("unknown ticker")
+	ErrInactiveTicker = errors.New("ticker is no longer traded")
+)
+
+// TickerError why a ticker was rejected, with the closest known tickers when it doesn't exist
+type TickerError struct {
+	Ticker      string
+	Err         error
+	Suggestions []string
+}
+
+func (e *TickerError) Error() string {
+	message := fmt.Sprintf("%s %s", e.Err, e.Ticker)
+	switch n := len(e.Suggestions); {
+	case n == 1:
+		message += ", did you mean " + e.Suggestions[0] + "?"
+	case n > 1:
+		message += ", did you mean " + strings.Join(e.Suggestions[:n-1], ", ") + " or " + e.Suggestions[n-1] + "?"
+	}
+	return message
+}
+
+func (e *TickerError) Unwrap() error {
+	return e.Err
+}
+
+// Validate normalises the ticker and checks it's actively traded. Tickers in the index are accepted
+// straight away, anything else is looked up on polygon to catch listings since the last refresh and tell
+// delisted tickers from typos. A rejected ticker comes back as a *TickerError, other errors mean polygon
+// couldn't be asked
+func (i *Index) Validate(ticker string, pa *intergration.PolygonApi, ctx context.Context) (string, error) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if ticker == "" {
+		return ticker, &TickerError{Ticker: ticker, Err: ErrUnknownTicker}
+	}
+	if _, ok := i.Lookup(ticker); ok {
+		return ticker, nil
+	}
+
+	details := pa.FetchTickerDetails(ticker, ctx)
+	if details.Error != nil {
+		var response *polyModels.ErrorResponse
+		if errors.As(details.Error, &response) && response.StatusCode == http.StatusNotFound {
+			return ticker, &TickerError{Ticker: ticker, Err: ErrUnknownTicker, Suggestions: i.Suggest(ticker)}
+		}
+		return ticker, details.Error
+	}
+
+	if !details.Data.Results.Active {
+		return ticker, &TickerError{Ticker: ticker, Err: ErrInactiveTicker}
+	}
+	return ticker, nil
+}
+
+// ValidTicker validates the ticker for a handler about to store it, returning it normalised. The error
+// response has been written when ok is false
+func ValidTicker(c *gin.Context, pa *intergration.PolygonApi, ticker string) (string, bool) {
+	ticker, err := Shared.Validate(ticker, pa, c.Request.Context())
+	if err == nil {
+		return ticker, true
+	}
+
+	var tickerErr *TickerError
+	switch {
+	case errors.As(err, &tickerErr) && len(tickerErr.Suggestions) > 0:
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error(), "suggestions": tickerErr.Suggestions})
+	case errors.As(err, &tickerErr):
+		c.JSON(http.StatusBadRequest, gin.H{"validation error": err.Error()})
+	case c.Request.Context().Err() != nil:
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request cancelled or timed out!"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't validate ticker: " + err.Error()})
+	}
+	return ticker, false
+}
+
+// Suggest the indexed tickers closest to ticker by edit distance, a swapped pair of letters counting as
+// one edit. Only the closest are suggested and short tickers only get suggestions one edit away so "A"
+// doesn't suggest half the universe
+func (i *Index) Suggest(ticker string) []string {
+	u := i.current.Load()
+	if u == nil {
+		return nil
+	}
+
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if ticker == "" {
+		return nil
+	}
+	maxDistance := 2
+	if len(ticker) <= 3 {
+		maxDistance = 1
+	}
+
+	var suggestions []string
+	for _, symbol := range u.symbols {
+		if abs(len(symbol.key)-len(ticker)) > maxDistance {
+			continue
+		}
+		distance := editDistance(ticker, symbol.key)
+		if distance > maxDistance {
+			continue
+		}
+
+		//anything further than the closest so far is noise
+		if distance < maxDistance {
+			maxDistance = distance
+			suggestions = suggestions[:0]
+		}
+		suggestions = append(suggestions, symbol.key)
+	}
+
+	//those sharing the first letter first as that's rarely the typo, symbols are already sorted
+	sort.SliceStable(suggestions, func(a, b int) bool {
+		return suggestions[a][0] == ticker[0] && suggestions[b][0] != ticker[0]
+	})
+
+	return suggestions[:min(len(suggestions), maxSuggestions)]
+}
+
+// editDistance the optimal string alignment distance: insertions, deletions, substitutions and swaps of
+// adjacent characters each count as one edit
+func editDistance(a string, b string) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(a)][len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
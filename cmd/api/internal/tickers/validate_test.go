@@ -0,0 +1,80 @@
+package tickers
+
+import (
+	"github.com/RobsonDevCode/GoApi/cmd/api/models"
+	"slices"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want int
+	}{
+		{"same", "AAPL", "AAPL", 0},
+		{"both empty", "", "", 0},
+		{"from empty", "", "AMD", 3},
+		{"to empty", "AMD", "", 3},
+		{"substitution", "AMD", "AMZ", 1},
+		{"insertion", "AAL", "AAPL", 1},
+		{"deletion", "GOOGL", "GOOG", 1},
+		{"swapped pair is one edit", "MSTF", "MSFT", 1},
+		{"swapped pair at the start", "PAPL", "APPL", 1},
+		{"two swapped pairs", "ABCD", "BADC", 2},
+		{"a swapped pair isn't edited again", "CA", "ABC", 3},
+		{"nothing in common", "AAPL", "MSFT", 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := editDistance(test.a, test.b); got != test.want {
+				t.Fatalf("got %d, want %d", got, test.want)
+			}
+			if got := editDistance(test.b, test.a); got != test.want {
+				t.Fatalf("got %d the other way round, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	var summaries []models.TickerSummary
+	symbols := []string{"AA", "AAL", "AAN", "AAPL", "AAT", "AMD", "AMZN", "APLE", "BMY", "GOOG", "GOOGL", "MSFT"}
+	for _, ticker := range symbols {
+		summaries = append(summaries, models.TickerSummary{Ticker: ticker})
+	}
+	index := NewIndex()
+	index.current.Store(build(summaries))
+
+	tests := []struct {
+		name   string
+		ticker string
+		want   []string
+	}{
+		{"APPL is AAPL", "APPL", []string{"AAPL"}},
+		{"swapped letters", "MSTF", []string{"MSFT"}},
+		{"lower case and spaces", " mstf ", []string{"MSFT"}},
+		{"two edits away", "GXXG", []string{"GOOG"}},
+		{"three letters or fewer only one edit away", "GOX", nil},
+		{"three letters or fewer one edit away", "AMX", []string{"AMD"}},
+		{"only the closest", "AMZM", []string{"AMZN"}},
+		{"same first letter first", "BMD", []string{"BMY", "AMD"}},
+		{"at most three", "AAX", []string{"AA", "AAL", "AAN"}},
+		{"nothing close", "QQQQ", nil},
+		{"blank", " ", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := index.Suggest(test.ticker); !slices.Equal(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if got := NewIndex().Suggest("APPL"); got != nil {
+		t.Fatalf("got %v before loading, want nothing", got)
+	}
+}
@@ -1,26 +1,90 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	repository "github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/dataAccess"
+	"github.com/RobsonDevCode/GoApi/cmd/api/internal/repository/migrations"
 	"github.com/RobsonDevCode/GoApi/cmd/api/internal/routing"
 	"github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/secrets"
 	"log"
+	"os"
+	"strings"
 	"time"
 )
 
-func run() error {
-	if err := configuration.SetEnvironmentSettings("development"); err != nil {
-		log.Fatalf("Failed to set environment variables: %v", err)
+const usage = `usage: api [command] [flags]
+
+commands:
+  (none)        run the api
+  config print  print the effective configuration with secrets redacted
+  secrets encrypt --in secrets.json --out secrets.enc --key-file secrets.key
+                encrypt a json object of secret name to value for the encrypted secrets provider
+
+configuration precedence, highest first:
+  flags > GOAPI_* environment variables > config.{env}.json > config.json
+  files are deep merged, config.{env}.json only needs the fields it changes
+  the files are watched and reloaded on change or SIGHUP, db settings need a restart
+
+flags:
+`
+
+const configReloadInterval = 5 * time.Second
+
+func run(args []string) error {
+	command, flagArgs := splitCommand(args)
+
+	//encrypting secrets doesn't need the configuration loaded
+	if command == "secrets encrypt" {
+		return encryptSecrets(flagArgs)
+	}
+
+	fs := flag.NewFlagSet("api", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	env := fs.String("env", "", "environment config to load, defaults to $APP_ENV then development")
+	configDir := fs.String("config-dir", "", "directory holding config.json, defaults to $APP_CONFIG_DIR then settings/configuration")
+	overrides := configuration.RegisterFlags(fs)
+
+	if err := fs.Parse(flagArgs); err != nil {
 		return err
 	}
 
+	if err := configuration.SetEnvironmentSettings(configuration.LoadOptions{
+		Dir:           *configDir,
+		Env:           configuration.ResolveEnvironment(*env),
+		FlagOverrides: overrides,
+	}); err != nil {
+		return fmt.Errorf("failed to load configuration:\n%w", err)
+	}
+
+	switch command {
+	case "":
+		return serve()
+	case "config print":
+		return configuration.Print(os.Stdout)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func serve() error {
 	//Add Connections and there configs
+	cfg := configuration.Current()
+	pool := cfg.Database
 	stocksDB, err := configuration.NewDB(configuration.DbConfig{
-		DSN:          configuration.Configuration.ConnectionStrings.Stocks,
-		MaxOpenConns: 25,
-		MaxIdelConns: 25,
-		MaxLifeTime:  15 * time.Minute,
-		MaxIdelTime:  5 * time.Minute,
+		DSN:          cfg.ConnectionStrings.Stocks,
+		MaxOpenConns: pool.MaxOpenConns,
+		MaxIdelConns: pool.MaxIdleConns,
+		MaxLifeTime:  time.Duration(pool.ConnMaxLifetime),
+		MaxIdelTime:  time.Duration(pool.ConnMaxIdleTime),
 	})
 
 	if err != nil {
@@ -28,9 +92,25 @@ func run() error {
 	}
 	defer stocksDB.Close()
 
-	stockRepo := repository.NewStockRepository(&repository.StocksDataBase{DB: stocksDB})
+	if err := migrations.Apply(context.Background(), stocksDB); err != nil {
+		log.Fatalf("error migrating stocks database: %s", err)
+	}
+
+	//pick up config and secret changes without a restart
+	configuration.WatchForChanges(context.Background(), configReloadInterval)
 
-	routerErr := routing.NewRouter(stockRepo)
+	stocksDataBase := &repository.StocksDataBase{DB: stocksDB}
+
+	routerErr := routing.NewRouter(routing.Repositories{
+		Stocks:     repository.NewStockRepository(stocksDataBase),
+		ApiKeys:    repository.NewApiKeyRepository(stocksDataBase),
+		Watchlists: repository.NewWatchlistRepository(stocksDataBase),
+		Portfolios: repository.NewPortfolioRepository(stocksDataBase),
+		Alerts:     repository.NewAlertRepository(stocksDataBase),
+		Webhooks:   repository.NewWebhookRepository(stocksDataBase),
+		Digests:    repository.NewDigestRepository(stocksDataBase),
+		Splits:     repository.NewSplitRepository(stocksDataBase),
+	})
 	if routerErr != nil {
 		log.Fatal(err)
 		return err
@@ -39,8 +119,55 @@ func run() error {
 	return nil
 }
 
+// encryptSecrets seals a plain json secrets file for the encrypted secrets provider
+func encryptSecrets(args []string) error {
+	fs := flag.NewFlagSet("secrets encrypt", flag.ExitOnError)
+	in := fs.String("in", "", "plain json object of secret name to value")
+	out := fs.String("out", "", "encrypted file to write")
+	keyFile := fs.String("key-file", "", "file holding the 32 byte AES key")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *out == "" || *keyFile == "" {
+		fs.Usage()
+		return errors.New("--in, --out and --key-file are required")
+	}
+
+	key, err := secrets.LoadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(plaintext) {
+		return fmt.Errorf("%s isn't valid json", *in)
+	}
+
+	sealed, err := secrets.Encrypt(plaintext, key)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(*out, sealed, 0600)
+}
+
+// splitCommand separates the leading command words from the flags that follow them
+func splitCommand(args []string) (string, []string) {
+	var words []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		words = append(words, args[0])
+		args = args[1:]
+	}
+
+	return strings.Join(words, " "), args
+}
+
 func main() {
-	if err := run(); err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// alert conditions, price and percent conditions compare the latest daily bar with the Threshold,
+// sma crosses compare its close with the Window day simple moving average
+const (
+	AlertPriceAbove    = "price_above"
+	AlertPriceBelow    = "price_below"
+	AlertPercentMove   = "percent_move"
+	AlertSmaCrossAbove = "sma_cross_above"
+	AlertSmaCrossBelow = "sma_cross_below"
+)
+
+// Alert a condition on a ticker checked by the background evaluator. LastState is which side of the
+// sma the close was on at the last evaluation, so a cross can be spotted
+type Alert struct {
+	Id              string     `json:"id"`
+	UserId          string     `json:"user_id"`
+	Ticker          string     `json:"ticker"`
+	Condition       string     `json:"condition"`
+	Threshold       float64    `json:"threshold,omitempty"`
+	Window          int        `json:"window,omitempty"`
+	CooldownMinutes int        `json:"cooldown_minutes"`
+	Active          bool       `json:"active"`
+	LastState       string     `json:"-"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// AlertTrigger a time an alert fired, an alert fires at most once per daily bar. Value is what was
+// compared, the percentage move or the sma
+type AlertTrigger struct {
+	Id          string    `json:"id"`
+	AlertId     string    `json:"alert_id"`
+	BarDate     time.Time `json:"bar_date"`
+	Price       float64   `json:"price"`
+	Value       float64   `json:"value"`
+	Message     string    `json:"message"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
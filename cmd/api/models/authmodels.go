@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ApiKey an issued api key, the raw key is only ever returned once on creation so only its hash is stored
+type ApiKey struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	OwnerId    string     `json:"owner_id"`
+	Scopes     []string   `json:"scopes"`
+	DailyQuota int        `json:"daily_quota"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
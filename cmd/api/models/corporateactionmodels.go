@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// dividend types, regular cash dividends and one-off special ones
+const (
+	DividendRegular = "CD"
+	DividendSpecial = "SC"
+)
+
+// Dividend a cash dividend. Frequency is payments per year, 0 for a one-off. Dates polygon doesn't have
+// are left out
+type Dividend struct {
+	Ticker          string     `json:"ticker"`
+	CashAmount      float64    `json:"cash_amount"`
+	DividendType    string     `json:"dividend_type"`
+	Frequency       int64      `json:"frequency"`
+	ExDividendDate  time.Time  `json:"ex_dividend_date"`
+	DeclarationDate *time.Time `json:"declaration_date,omitempty"`
+	RecordDate      *time.Time `json:"record_date,omitempty"`
+	PayDate         *time.Time `json:"pay_date,omitempty"`
+}
+
+// Split a stock split, SplitTo new shares for every SplitFrom old ones. Ratio is new shares per old share
+// e.g. 4 for a 4-for-1 split and 0.1 for a 1-for-10 reverse split
+type Split struct {
+	Ticker        string    `json:"ticker"`
+	ExecutionDate time.Time `json:"execution_date"`
+	SplitFrom     float64   `json:"split_from"`
+	SplitTo       float64   `json:"split_to"`
+	Ratio         float64   `json:"ratio"`
+	ReverseSplit  bool      `json:"reverse_split"`
+}
+
+// SplitAdjustment what applying a split changed in the data we hold
+type SplitAdjustment struct {
+	Split         Split `json:"split"`
+	Portfolios    int64 `json:"portfolios"`     //portfolios given a split transaction
+	Alerts        int64 `json:"alerts"`         //price alerts whose threshold was rescaled
+	AlertTriggers int64 `json:"alert_triggers"` //past triggers whose prices were rescaled
+}
@@ -5,7 +5,8 @@ type Subscription struct {
 	Ticker string `json:"ticker"`
 }
 
+// FavouriteStock UserId defaults to the authenticated caller, admins can set it to act for another user
 type FavouriteStock struct {
-	UserId string `form:"user_id" json:"user_id" binding:"required"`
+	UserId string `form:"user_id" json:"user_id"`
 	Ticker string `form:"ticker" json:"ticker" binding:"required"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DigestSubscription a user's opt in to the daily email digest. UnsubscribeToken goes in the email's
+// unsubscribe link so the user can opt out without signing in
+type DigestSubscription struct {
+	UserId           string     `json:"user_id"`
+	Email            string     `json:"email"`
+	Subscribed       bool       `json:"subscribed"`
+	UnsubscribeToken string     `json:"-"`
+	LastSentAt       *time.Time `json:"last_sent_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// DigestTicker one of the user's favourites in the digest, Error is set when its prices couldn't be fetched
+type DigestTicker struct {
+	Ticker        string
+	Open          float64
+	Close         float64
+	Change        float64
+	ChangePercent float64
+	Error         string
+}
+
+// Digest everything that goes in one user's daily email
+type Digest struct {
+	Email          string
+	Date           time.Time
+	Tickers        []DigestTicker
+	Alerts         []AlertTrigger
+	UnsubscribeUrl string
+}
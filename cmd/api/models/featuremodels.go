@@ -0,0 +1,8 @@
+package models
+
+// FeatureEvaluation whether a feature flag is on for a user and why
+type FeatureEvaluation struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
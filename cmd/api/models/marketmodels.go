@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// states the equities market can be in
+const (
+	MarketOpen       = "open"
+	MarketPreMarket  = "pre-market"
+	MarketAfterHours = "after-hours"
+	MarketClosed     = "closed"
+)
+
+// MarketStatus whether US equities are trading right now and when the regular session next opens and closes,
+// times are in the exchange's timezone
+type MarketStatus struct {
+	Market     string    `json:"market"` //open, pre-market, after-hours or closed
+	ServerTime time.Time `json:"server_time"`
+	NextOpen   time.Time `json:"next_open"`
+	NextClose  time.Time `json:"next_close"`
+	EarlyClose bool      `json:"early_close"`       //the session next_close belongs to closes early
+	Holiday    string    `json:"holiday,omitempty"` //today's holiday or early close
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// NewsArticle a news article mentioning one or more tickers
+type NewsArticle struct {
+	Id          string        `json:"id"`
+	Title       string        `json:"title"`
+	Author      string        `json:"author,omitempty"`
+	Description string        `json:"description,omitempty"`
+	ArticleUrl  string        `json:"article_url"`
+	ImageUrl    string        `json:"image_url,omitempty"`
+	PublishedAt time.Time     `json:"published_at"`
+	Publisher   NewsPublisher `json:"publisher"`
+	Tickers     []string      `json:"tickers"`
+	Keywords    []string      `json:"keywords,omitempty"`
+	Insights    []NewsInsight `json:"insights,omitempty"` //only when polygon has analysed the article
+}
+
+// NewsPublisher who published a news article
+type NewsPublisher struct {
+	Name        string `json:"name"`
+	HomepageUrl string `json:"homepage_url,omitempty"`
+	LogoUrl     string `json:"logo_url,omitempty"`
+}
+
+// NewsInsight the sentiment of an article towards one of the tickers it mentions
+type NewsInsight struct {
+	Ticker    string `json:"ticker"`
+	Sentiment string `json:"sentiment"` //positive, neutral or negative
+	Reasoning string `json:"reasoning"`
+}
+
+// NewsPage a page of articles newest first, pass NextCursor back as cursor for the next page. It's empty on
+// the last page
+type NewsPage struct {
+	Articles   []NewsArticle `json:"articles"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,115 @@
+package models
+
+import "time"
+
+// cost basis methods a portfolio's positions can be computed with
+const (
+	CostBasisFifo    = "fifo"
+	CostBasisAverage = "average"
+)
+
+// transaction types
+const (
+	TransactionBuy      = "buy"
+	TransactionSell     = "sell"
+	TransactionDividend = "dividend"
+	TransactionSplit    = "split"
+)
+
+// Portfolio a user's holdings, positions are computed from its transactions using CostBasis
+type Portfolio struct {
+	Id        string    `json:"id"`
+	UserId    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	CostBasis string    `json:"cost_basis"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Transaction a change to a portfolio. Buys and sells use Quantity, Price and Fees, dividends the cash
+// Amount received and splits the Ratio of new shares per old share e.g. 4 for a 4-for-1 split
+type Transaction struct {
+	Id          string    `json:"id"`
+	PortfolioId string    `json:"portfolio_id"`
+	Ticker      string    `json:"ticker"`
+	Type        string    `json:"type"`
+	Quantity    float64   `json:"quantity,omitempty"`
+	Price       float64   `json:"price,omitempty"`
+	Fees        float64   `json:"fees,omitempty"`
+	Amount      float64   `json:"amount,omitempty"`
+	Ratio       float64   `json:"ratio,omitempty"`
+	ExecutedAt  time.Time `json:"executed_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Lot shares bought together, with average cost a position holds a single lot
+type Lot struct {
+	Quantity     float64   `json:"quantity"`
+	CostPerShare float64   `json:"cost_per_share"`
+	AcquiredAt   time.Time `json:"acquired_at"`
+}
+
+// Position what's held of a ticker after replaying the portfolio's transactions
+type Position struct {
+	Ticker      string  `json:"ticker"`
+	Quantity    float64 `json:"quantity"`
+	CostBasis   float64 `json:"cost_basis"`
+	AverageCost float64 `json:"average_cost"`
+	RealisedPnl float64 `json:"realised_pnl"`
+	Dividends   float64 `json:"dividends"`
+	Lots        []Lot   `json:"lots"`
+}
+
+// PositionValuation a position valued at the previous close, DayChange is the move over that session
+type PositionValuation struct {
+	Position
+	Price                float64 `json:"price"`
+	MarketValue          float64 `json:"market_value"`
+	UnrealisedPnl        float64 `json:"unrealised_pnl"`
+	UnrealisedPnlPercent float64 `json:"unrealised_pnl_percent"`
+	DayChange            float64 `json:"day_change"`
+	DayChangePercent     float64 `json:"day_change_percent"`
+	PriceError           string  `json:"price_error,omitempty"`
+}
+
+// ValuationTotals the sum over every position that could be priced
+type ValuationTotals struct {
+	MarketValue          float64 `json:"market_value"`
+	CostBasis            float64 `json:"cost_basis"`
+	UnrealisedPnl        float64 `json:"unrealised_pnl"`
+	UnrealisedPnlPercent float64 `json:"unrealised_pnl_percent"`
+	DayChange            float64 `json:"day_change"`
+	DayChangePercent     float64 `json:"day_change_percent"`
+	RealisedPnl          float64 `json:"realised_pnl"`
+	Dividends            float64 `json:"dividends"`
+}
+
+// PortfolioValuation every open position in a portfolio valued at the previous close
+type PortfolioValuation struct {
+	PortfolioId string              `json:"portfolio_id"`
+	CostBasis   string              `json:"cost_basis"`
+	Positions   []PositionValuation `json:"positions"`
+	Totals      ValuationTotals     `json:"totals"`
+}
+
+// PortfolioPerformance returns and risk over a period, returns and ratios are fractions e.g. 0.05 for 5%.
+// MoneyWeightedReturn and AnnualisedVolatility are annualised, TimeWeightedReturn is for the whole period
+type PortfolioPerformance struct {
+	PortfolioId          string    `json:"portfolio_id"`
+	From                 time.Time `json:"from"`
+	To                   time.Time `json:"to"`
+	TradingDays          int       `json:"trading_days"`
+	Benchmark            string    `json:"benchmark"`
+	RiskFreeRate         float64   `json:"risk_free_rate"`
+	StartValue           float64   `json:"start_value"`
+	EndValue             float64   `json:"end_value"`
+	NetFlows             float64   `json:"net_flows"`
+	TimeWeightedReturn   float64   `json:"time_weighted_return"`
+	MoneyWeightedReturn  *float64  `json:"money_weighted_return"`
+	AnnualisedVolatility float64   `json:"annualised_volatility"`
+	SharpeRatio          float64   `json:"sharpe_ratio"`
+	SortinoRatio         float64   `json:"sortino_ratio"`
+	MaxDrawdown          float64   `json:"max_drawdown"`
+	Beta                 float64   `json:"beta"`
+	BenchmarkReturn      float64   `json:"benchmark_return"`
+	FailedTickers        []string  `json:"failed_tickers"` //tickers whose daily bars couldn't be fetched
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// PriceUpdate a ticker's latest trade and today's session so far, sent to stream subscribers when it changes
+type PriceUpdate struct {
+	Ticker        string    `json:"ticker"`
+	Price         float64   `json:"price"`
+	Open          float64   `json:"open"`
+	High          float64   `json:"high"`
+	Low           float64   `json:"low"`
+	PreviousClose float64   `json:"previous_close"`
+	Change        float64   `json:"change"`
+	ChangePercent float64   `json:"change_percent"`
+	Volume        float64   `json:"volume"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// realtime channels a client can subscribe a ticker to
+const (
+	ChannelTrades     = "trades"
+	ChannelQuotes     = "quotes"
+	ChannelAggregates = "aggregates" //minute bars
+)
+
+// MarketMessage a message from the realtime feed, Data is a Trade, Quote or MinuteAggregate depending on Channel
+type MarketMessage struct {
+	Channel string `json:"channel"`
+	Ticker  string `json:"ticker"`
+	Data    any    `json:"data"`
+}
+
+// Trade a single trade of a ticker
+type Trade struct {
+	Price     float64   `json:"price"`
+	Size      int64     `json:"size"`
+	Exchange  int32     `json:"exchange"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Quote the best bid and ask for a ticker
+type Quote struct {
+	BidPrice  float64   `json:"bid_price"`
+	BidSize   int32     `json:"bid_size"`
+	AskPrice  float64   `json:"ask_price"`
+	AskSize   int32     `json:"ask_size"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MinuteAggregate a ticker's trading over one minute
+type MinuteAggregate struct {
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+	Vwap   float64   `json:"vwap"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// websocket channels besides the realtime feed's
+const (
+	ChannelPrices = "prices" //the same price updates as the sse streams
+	ChannelAlerts = "alerts" //the caller's alert triggers, it takes no tickers
+)
+
+// websocket control frame types, data frames are typed by their channel
+const (
+	FrameConnected    = "connected"
+	FrameSubscribed   = "subscribed"
+	FrameUnsubscribed = "unsubscribed"
+	FrameError        = "error"
+)
+
+// SocketFrame a message sent to a websocket client, either data from a channel it's subscribed to or a
+// control frame answering what it sent
+type SocketFrame struct {
+	Type    string   `json:"type"`
+	Channel string   `json:"channel,omitempty"`
+	Ticker  string   `json:"ticker,omitempty"`
+	Tickers []string `json:"tickers,omitempty"`
+	Data    any      `json:"data,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// TickerSummary what ticker search returns for each match
+type TickerSummary struct {
+	Ticker          string `json:"ticker"`
+	Name            string `json:"name"`
+	Market          string `json:"market"`
+	Type            string `json:"type"`
+	PrimaryExchange string `json:"primary_exchange"`
+	Currency        string `json:"currency"`
+	Active          bool   `json:"active"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Watchlist a named, ordered group of tickers belonging to a user. The default watchlist holds the user's
+// favourites, adding or removing a ticker from either changes both
+type Watchlist struct {
+	Id        string            `json:"id"`
+	UserId    string            `json:"user_id"`
+	Name      string            `json:"name"`
+	IsDefault bool              `json:"is_default"`
+	CreatedAt time.Time         `json:"created_at"`
+	Tickers   []WatchlistTicker `json:"tickers"`
+}
+
+// WatchlistTicker a ticker in a watchlist, lower positions come first
+type WatchlistTicker struct {
+	Ticker   string    `json:"ticker"`
+	Position int       `json:"position"`
+	Note     string    `json:"note"`
+	AddedAt  time.Time `json:"added_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// webhook delivery statuses, a pending delivery is retried until it's delivered or runs out of attempts
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook an endpoint a user registered to be sent events. Secret signs every delivery and is only
+// returned when the webhook is created
+type Webhook struct {
+	Id        string    `json:"id"`
+	UserId    string    `json:"user_id"`
+	Url       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery an event waiting in, or sent from, the outbox. Payload is the exact body posted
+type WebhookDelivery struct {
+	Id             string     `json:"id"`
+	WebhookId      string     `json:"webhook_id"`
+	EventId        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// DueDelivery a claimed delivery with where to send it
+type DueDelivery struct {
+	WebhookDelivery
+	Url    string
+	Secret string
+}
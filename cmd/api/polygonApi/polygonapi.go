@@ -6,6 +6,8 @@ import (
 	"github.com/RobsonDevCode/GoApi/cmd/api/dtos"
 	. "github.com/RobsonDevCode/GoApi/cmd/api/models"
 	. "github.com/RobsonDevCode/GoApi/cmd/api/settings/configuration"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/secrets"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/gommon/log"
@@ -14,13 +16,23 @@ import (
 )
 
 type PolygonApi struct {
-	client *polygon.Client
+	client atomic.Pointer[polygon.Client]
 }
 
 func ConnectToPolygonApi() *PolygonApi {
-	return &PolygonApi{
-		client: polygon.New(Configuration.ApiSettings.Key),
-	}
+	api := &PolygonApi{}
+	api.client.Store(polygon.New(Current().ApiSettings.Key))
+
+	//rebuild the client when the key is rotated, in flight requests finish on the old one
+	Subscribe(func(previous AppConfig, next AppConfig) {
+		if previous.ApiSettings.Key != next.ApiSettings.Key {
+			api.client.Store(polygon.New(next.ApiSettings.Key))
+			log.Infof("polygon api key changed from %s to %s, client rebuilt", secrets.Redact(previous.ApiSettings.Key),
+				secrets.Redact(next.ApiSettings.Key))
+		}
+	})
+
+	return api
 }
 
 func (p *PolygonApi) FetchTickerDetails(ticker string, ctx context.Context) Response[*polyModels.GetTickerDetailsResponse] {
@@ -30,7 +42,7 @@ func (p *PolygonApi) FetchTickerDetails(ticker string, ctx context.Context) Resp
 	}
 
 	//make request to get ticker details https://polygon.io/docs/stocks/get_v3_reference_tickers__ticker
-	if response, err := p.client.GetTickerDetails(ctx, params); err == nil {
+	if response, err := p.client.Load().GetTickerDetails(ctx, params); err == nil {
 		return Response[*polyModels.GetTickerDetailsResponse]{
 			Data:  response,
 			Error: nil,
@@ -50,7 +62,7 @@ func (p *PolygonApi) FetchPreviousClose(dto dtos.PreviousCloseRequestDto, ctx co
 		Ticker:   dto.Ticker,
 		Adjusted: &dto.Adjusted,
 	}
-	if response, err := p.client.GetPreviousCloseAgg(ctx, params); err == nil {
+	if response, err := p.client.Load().GetPreviousCloseAgg(ctx, params); err == nil {
 		return Response[*polyModels.GetPreviousCloseAggResponse]{
 			Data:  response,
 			Error: nil,
@@ -79,7 +91,7 @@ func (p *PolygonApi) FetchTickerOpenClose(ticker string, dateFrom time.Time, ctx
 	}
 
 	//make request to Daily open and close https://polygon.io/docs/stocks/get_v1_open-close__stocksticker___date
-	if response, err := p.client.GetDailyOpenCloseAgg(ctx, params); err == nil {
+	if response, err := p.client.Load().GetDailyOpenCloseAgg(ctx, params); err == nil {
 		return Response[*polyModels.GetDailyOpenCloseAggResponse]{
 			Data:  response,
 			Error: nil,
@@ -94,6 +106,63 @@ func (p *PolygonApi) FetchTickerOpenClose(ticker string, dateFrom time.Time, ctx
 
 }
 
+// FetchDailyBars gets a ticker's daily bars between from and to inclusive, oldest first
+func (p *PolygonApi) FetchDailyBars(ticker string, from time.Time, to time.Time, adjusted bool, ctx context.Context) Response[[]polyModels.Agg] {
+	order := polyModels.Asc
+	params := &polyModels.ListAggsParams{
+		Ticker:     ticker,
+		Multiplier: 1,
+		Timespan:   polyModels.Day,
+		From:       polyModels.Millis(from),
+		To:         polyModels.Millis(to),
+		Adjusted:   &adjusted,
+		Order:      &order,
+	}
+
+	//make request to aggregates https://polygon.io/docs/stocks/get_v2_aggs_ticker__stocksticker__range__multiplier___timespan___from___to
+	iter := p.client.Load().ListAggs(ctx, params)
+
+	var bars []polyModels.Agg
+	for iter.Next() {
+		bars = append(bars, iter.Item())
+	}
+	if err := iter.Err(); err != nil {
+		log.Errorf("Error calling aggregates: %s", err)
+		return Response[[]polyModels.Agg]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]polyModels.Agg]{
+		Data:  bars,
+		Error: nil,
+	}
+}
+
+// FetchTickerSnapshot gets the ticker's latest trade and today's bar so far
+func (p *PolygonApi) FetchTickerSnapshot(ticker string, ctx context.Context) Response[*polyModels.GetTickerSnapshotResponse] {
+	params := &polyModels.GetTickerSnapshotParams{
+		Locale:     polyModels.US,
+		MarketType: polyModels.Stocks,
+		Ticker:     ticker,
+	}
+
+	//make request to snapshot https://polygon.io/docs/stocks/get_v2_snapshot_locale_us_markets_stocks_tickers__stocksticker
+	if response, err := p.client.Load().GetTickerSnapshot(ctx, params); err == nil {
+		return Response[*polyModels.GetTickerSnapshotResponse]{
+			Data:  response,
+			Error: nil,
+		}
+	} else {
+		log.Errorf("Error calling ticker snapshot: %s", err)
+		return Response[*polyModels.GetTickerSnapshotResponse]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+}
+
 func (p *PolygonApi) FetchSimpleMovingAverage(request dtos.SimpleMovingAverageDto, ctx context.Context) Response[*polyModels.GetSMAResponse] {
 
 	params := &polyModels.GetSMAParams{
@@ -104,7 +173,7 @@ func (p *PolygonApi) FetchSimpleMovingAverage(request dtos.SimpleMovingAverageDt
 		ExpandUnderlying: &request.MoreDetails,
 	}
 
-	if response, err := p.client.GetSMA(ctx, params); err == nil {
+	if response, err := p.client.Load().GetSMA(ctx, params); err == nil {
 		result := Response[*polyModels.GetSMAResponse]{
 			Data:  response,
 			Error: nil,
@@ -124,3 +193,130 @@ func (p *PolygonApi) FetchSimpleMovingAverage(request dtos.SimpleMovingAverageDt
 		}
 	}
 }
+
+// FetchMarketHolidays gets the upcoming exchange holidays and early closes
+func (p *PolygonApi) FetchMarketHolidays(ctx context.Context) Response[[]polyModels.MarketHoliday] {
+	//make request to market holidays https://polygon.io/docs/stocks/get_v1_marketstatus_upcoming
+	response, err := p.client.Load().GetMarketHolidays(ctx)
+	if err != nil {
+		log.Errorf("Error calling market holidays: %s", err)
+		return Response[[]polyModels.MarketHoliday]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]polyModels.MarketHoliday]{
+		Data:  *response,
+		Error: nil,
+	}
+}
+
+// FetchMarketStatus gets whether each exchange is open right now
+func (p *PolygonApi) FetchMarketStatus(ctx context.Context) Response[*polyModels.GetMarketStatusResponse] {
+	//make request to market status https://polygon.io/docs/stocks/get_v1_marketstatus_now
+	if response, err := p.client.Load().GetMarketStatus(ctx); err == nil {
+		return Response[*polyModels.GetMarketStatusResponse]{
+			Data:  response,
+			Error: nil,
+		}
+	} else {
+		log.Errorf("Error calling market status: %s", err)
+		return Response[*polyModels.GetMarketStatusResponse]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+}
+
+// ListTickers gets the tickers matching params following every page, stopping once max have been read.
+// A max of 0 reads them all
+func (p *PolygonApi) ListTickers(params *polyModels.ListTickersParams, max int, ctx context.Context) Response[[]polyModels.Ticker] {
+	//make request to tickers https://polygon.io/docs/stocks/get_v3_reference_tickers
+	iter := p.client.Load().ListTickers(ctx, params)
+
+	var tickers []polyModels.Ticker
+	for (max == 0 || len(tickers) < max) && iter.Next() {
+		tickers = append(tickers, iter.Item())
+	}
+	if err := iter.Err(); err != nil {
+		log.Errorf("Error calling tickers: %s", err)
+		return Response[[]polyModels.Ticker]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]polyModels.Ticker]{
+		Data:  tickers,
+		Error: nil,
+	}
+}
+
+// ListTickerNews gets the news articles matching params following every page, stopping once max have been
+// read. A max of 0 reads them all
+func (p *PolygonApi) ListTickerNews(params *polyModels.ListTickerNewsParams, max int, ctx context.Context) Response[[]polyModels.TickerNews] {
+	//make request to ticker news https://polygon.io/docs/stocks/get_v2_reference_news
+	iter := p.client.Load().ListTickerNews(ctx, params)
+
+	var articles []polyModels.TickerNews
+	for (max == 0 || len(articles) < max) && iter.Next() {
+		articles = append(articles, iter.Item())
+	}
+	if err := iter.Err(); err != nil {
+		log.Errorf("Error calling ticker news: %s", err)
+		return Response[[]polyModels.TickerNews]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]polyModels.TickerNews]{
+		Data:  articles,
+		Error: nil,
+	}
+}
+
+func (p *PolygonApi) ListDividends(params *polyModels.ListDividendsParams, max int, ctx context.Context) Response[[]polyModels.Dividend] {
+	//make request to dividends https://polygon.io/docs/stocks/get_v3_reference_dividends
+	iter := p.client.Load().ListDividends(ctx, params)
+
+	var dividends []polyModels.Dividend
+	for (max == 0 || len(dividends) < max) && iter.Next() {
+		dividends = append(dividends, iter.Item())
+	}
+	if err := iter.Err(); err != nil {
+		log.Errorf("Error calling dividends: %s", err)
+		return Response[[]polyModels.Dividend]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]polyModels.Dividend]{
+		Data:  dividends,
+		Error: nil,
+	}
+}
+
+func (p *PolygonApi) ListSplits(params *polyModels.ListSplitsParams, max int, ctx context.Context) Response[[]polyModels.Split] {
+	//make request to splits https://polygon.io/docs/stocks/get_v3_reference_splits
+	iter := p.client.Load().ListSplits(ctx, params)
+
+	var splits []polyModels.Split
+	for (max == 0 || len(splits) < max) && iter.Next() {
+		splits = append(splits, iter.Item())
+	}
+	if err := iter.Err(); err != nil {
+		log.Errorf("Error calling splits: %s", err)
+		return Response[[]polyModels.Split]{
+			Data:  nil,
+			Error: err,
+		}
+	}
+
+	return Response[[]polyModels.Split]{
+		Data:  splits,
+		Error: nil,
+	}
+}
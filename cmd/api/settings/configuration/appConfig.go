@@ -1,89 +1,244 @@
 package configuration
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/labstack/gommon/log"
-	"os"
-	"path/filepath"
+	"sync/atomic"
 	"time"
 )
 
 type AppConfig struct {
 	ConnectionStrings struct {
-		Stocks string `json:"stocksDb"`
-	}
+		Stocks string `json:"stocksDb" secret:"true"`
+	} `reload:"restart"`
+	Database struct {
+		MaxOpenConns    int      `json:"maxOpenConns"`
+		MaxIdleConns    int      `json:"maxIdleConns"`
+		ConnMaxLifetime Duration `json:"connMaxLifetime"`
+		ConnMaxIdleTime Duration `json:"connMaxIdleTime"`
+	} `reload:"restart"`
 	ApiSettings struct {
-		Key string `json:"key"`
+		Key            string   `json:"key" secret:"true"`
+		TrustedProxies []string `json:"trustedProxies" reload:"restart"` //ips or cidrs whose X-Forwarded-For is believed, none by default
+	}
+	Auth struct {
+		ApiKeyHeader      string `json:"apiKeyHeader" reload:"restart"`
+		BootstrapAdminKey string `json:"bootstrapAdminKey" secret:"true"`
+		DefaultDailyQuota int    `json:"defaultDailyQuota"`
+		Jwt               struct {
+			Issuer           string `json:"issuer"`
+			Audience         string `json:"audience"`
+			HmacSecret       string `json:"hmacSecret" secret:"true"`
+			Jwks             string `json:"jwks"` //file path or url
+			JwksCacheMinutes int    `json:"jwksCacheMinutes"`
+			AdminRole        string `json:"adminRole"`
+		} `reload:"restart"`
+	}
+	RateLimit struct { //counted per instance
+		Rules map[string]RateLimitRule `json:"rules"` //keyed by route group
+	}
+	Cache struct {
+		TickerDetailsTtl Duration `json:"tickerDetailsTtl"` //while the market is open, pre-market and after-hours included
+		OpenCloseTtl     Duration `json:"openCloseTtl"`
+		ClosedTtl        Duration `json:"closedTtl"` //market data while the market is closed, never past the next pre-market open
+	}
+	Analytics struct {
+		BenchmarkTicker string  `json:"benchmarkTicker"` //compared against for beta, e.g. SPY
+		RiskFreeRate    float64 `json:"riskFreeRate"`    //annual, e.g. 0.04 for 4%
+	}
+	Alerts struct {
+		EvaluationInterval Duration `json:"evaluationInterval"`
+		DefaultCooldown    Duration `json:"defaultCooldown"` //used when an alert doesn't set its own
+		MaxPerUser         int      `json:"maxPerUser"`
+	}
+	Webhooks struct {
+		PollInterval   Duration `json:"pollInterval"` //how often the outbox is checked for due deliveries
+		Timeout        Duration `json:"timeout"`
+		BatchSize      int      `json:"batchSize"`
+		MaxAttempts    int      `json:"maxAttempts"`    //a delivery is dead lettered after this many failures
+		InitialBackoff Duration `json:"initialBackoff"` //doubled after each failure up to maxBackoff
+		MaxBackoff     Duration `json:"maxBackoff"`
+		MaxPerUser     int      `json:"maxPerUser"`
+		//lets webhooks be sent to loopback and private addresses, only for local development
+		AllowPrivateNetworks bool `json:"allowPrivateNetworks"`
+	}
+	Stream struct {
+		PollInterval      Duration `json:"pollInterval"` //how often subscribed tickers are fetched, once each however many clients
+		HeartbeatInterval Duration `json:"heartbeatInterval"`
+		ReplayBuffer      int      `json:"replayBuffer"` //updates kept for clients resuming with Last-Event-ID
+		ClientBuffer      int      `json:"clientBuffer"` //updates queued for a client before it's dropped as too slow
+		MaxTickers        int      `json:"maxTickers"`   //per stream
+	}
+	Realtime struct {
+		Enabled        bool     `json:"enabled" reload:"restart"` //needs a polygon plan with websocket access
+		Url            string   `json:"url"`                      //polygon websocket feed, e.g. wss://delayed.polygon.io
+		InitialBackoff Duration `json:"initialBackoff"`           //between reconnects, doubled after each failure up to maxBackoff
+		MaxBackoff     Duration `json:"maxBackoff"`
+		ClientBuffer   int      `json:"clientBuffer"` //messages queued for a listener before it's dropped as too slow
+	}
+	Websocket struct {
+		MaxSubscriptions int      `json:"maxSubscriptions"` //per connection, each ticker on each channel counts once
+		SendBuffer       int      `json:"sendBuffer"`       //replies and alerts queued for a client before it's dropped as too slow
+		PingInterval     Duration `json:"pingInterval"`
+		PongTimeout      Duration `json:"pongTimeout"` //a client that hasn't answered a ping in this long is disconnected
+		WriteTimeout     Duration `json:"writeTimeout"`
+		MaxMessageSize   int64    `json:"maxMessageSize"` //bytes a client message can be
+		AllowedOrigins   []string `json:"allowedOrigins"` //browser origins besides the api's own that can connect
 	}
+	Tickers struct {
+		RefreshAt      string   `json:"refreshAt"`      //hh:mm UTC the ticker index is reloaded each day
+		SearchCacheTtl Duration `json:"searchCacheTtl"` //for searches the index can't answer
+	}
+	News struct {
+		CacheTtl Duration `json:"cacheTtl"`
+	}
+	Calendar struct {
+		RefreshInterval Duration `json:"refreshInterval"` //how often holidays and market status are fetched from polygon
+	}
+	CorporateActions struct {
+		CacheTtl      Duration `json:"cacheTtl"`      //for dividends and splits, they rarely change
+		CheckInterval Duration `json:"checkInterval"` //how often held tickers are checked for new splits to apply
+	}
+	Smtp struct {
+		Host     string `json:"host"` //when empty emails are logged instead of sent
+		Port     int    `json:"port"`
+		Username string `json:"username"`
+		Password string `json:"password" secret:"true"`
+		From     string `json:"from"`
+	}
+	Digest struct {
+		SendAt  string `json:"sendAt"`  //hh:mm UTC the daily digest goes out
+		BaseUrl string `json:"baseUrl"` //public url of the api, used for unsubscribe links
+	}
+	Features map[string]FeatureFlag `json:"features"` //keyed by flag name
+	Secrets  struct {
+		Provider string `json:"provider"` //env, file or encrypted, any string field can then be set to secret://name
+		Dir      string `json:"dir"`      //mounted secret files for the file provider
+		File     string `json:"file"`     //secrets file for the encrypted provider
+		KeyFile  string `json:"keyFile"`  //AES-256 key for the encrypted provider
+	}
+	Versioning struct {
+		DeprecatedSince string `json:"deprecatedSince"` //yyyy-mm-dd the unversioned routes were deprecated
+		Sunset          string `json:"sunset"`          //yyyy-mm-dd the unversioned routes will be removed
+	} `reload:"restart"`
 }
 
-// Settings generic app settings so we don't have "magic" values
-type Settings struct {
-	Yesterday time.Time
+// RateLimitRule how many requests a caller can make to a route group per window
+type RateLimitRule struct {
+	Requests      int `json:"requests"`
+	WindowSeconds int `json:"windowSeconds"`
 }
 
-var Configuration AppConfig
+// FeatureFlag gates a feature, Enabled is the master switch. With RolloutPercent set only that percentage
+// of users get it, chosen by a stable hash of their user id. Users always get it while Enabled
+type FeatureFlag struct {
+	Enabled        bool     `json:"enabled"`
+	RolloutPercent *int     `json:"rolloutPercent"`
+	Users          []string `json:"users"`
+}
 
-// set up base settings to prep configuration
+// Duration a time.Duration written in config as a string like "15m"
+type Duration time.Duration
 
-// SetEnvironmentSettings map json configuration to our connection string struct
-func SetEnvironmentSettings(env string) error {
-	//get base config settings
-	configBasePath, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	configBasePath = filepath.Join(configBasePath, "settings", "configuration")
-	errConf := configureApp(configBasePath, env)
-	if errConf != nil {
-		log.Fatalf("Error setting environment settings: %s", errConf)
-	}
-	return nil
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
 }
 
-// configureApp open correct configuration based on environment
-func configureApp(filePath string, env string) error {
-
-	//open base configuration settings unless a specific environment is specified
-	baseFile, err := os.Open(filePath + "\\config.json")
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
 	if err != nil {
-		log.Fatalf("Error opening config file: %v", err)
 		return err
 	}
-	defer baseFile.Close()
+	*d = Duration(parsed)
+	return nil
+}
 
-	decoder := json.NewDecoder(baseFile)
+// snapshot a loaded configuration, swapped as a whole so readers never see a half applied reload
+type snapshot struct {
+	config          AppConfig
+	resolvedSecrets map[string]bool //fields whose values came from the secrets provider
+}
 
-	baseConfig := AppConfig{}
+var current atomic.Pointer[snapshot]
 
-	if err = decoder.Decode(&baseConfig); err != nil {
-		log.Fatalf("Error Decoding Configuration File: %v", err)
-		return err
+// Current the configuration in effect, read it each time it's needed rather than holding on to it so
+// reloads are picked up
+func Current() AppConfig {
+	if s := current.Load(); s != nil {
+		return s.config
 	}
+	return AppConfig{}
+}
 
-	currentEnv := fmt.Sprintf("\\config.%s.json", env)
-	envFilePath := filepath.Join(filePath, currentEnv)
+// defaults values used when neither config file sets them
+func defaults() AppConfig {
+	var cfg AppConfig
+	cfg.Database.MaxOpenConns = 25
+	cfg.Database.MaxIdleConns = 25
+	cfg.Database.ConnMaxLifetime = Duration(15 * time.Minute)
+	cfg.Database.ConnMaxIdleTime = Duration(5 * time.Minute)
+	cfg.Cache.TickerDetailsTtl = Duration(4 * time.Minute)
+	cfg.Cache.OpenCloseTtl = Duration(3 * time.Minute)
+	cfg.Cache.ClosedTtl = Duration(12 * time.Hour)
+	cfg.Analytics.BenchmarkTicker = "SPY"
+	cfg.Alerts.EvaluationInterval = Duration(5 * time.Minute)
+	cfg.Alerts.DefaultCooldown = Duration(24 * time.Hour)
+	cfg.Alerts.MaxPerUser = 50
+	cfg.Webhooks.PollInterval = Duration(5 * time.Second)
+	cfg.Webhooks.Timeout = Duration(10 * time.Second)
+	cfg.Webhooks.BatchSize = 50
+	cfg.Webhooks.MaxAttempts = 8
+	cfg.Webhooks.InitialBackoff = Duration(30 * time.Second)
+	cfg.Webhooks.MaxBackoff = Duration(6 * time.Hour)
+	cfg.Webhooks.MaxPerUser = 10
+	cfg.Stream.PollInterval = Duration(5 * time.Second)
+	cfg.Stream.HeartbeatInterval = Duration(15 * time.Second)
+	cfg.Stream.ReplayBuffer = 1000
+	cfg.Stream.ClientBuffer = 64
+	cfg.Stream.MaxTickers = 50
+	cfg.Realtime.Url = "wss://delayed.polygon.io"
+	cfg.Realtime.InitialBackoff = Duration(time.Second)
+	cfg.Realtime.MaxBackoff = Duration(time.Minute)
+	cfg.Realtime.ClientBuffer = 256
+	cfg.Websocket.MaxSubscriptions = 100
+	cfg.Websocket.SendBuffer = 64
+	cfg.Websocket.PingInterval = Duration(30 * time.Second)
+	cfg.Websocket.PongTimeout = Duration(60 * time.Second)
+	cfg.Websocket.WriteTimeout = Duration(10 * time.Second)
+	cfg.Websocket.MaxMessageSize = 4096
+	cfg.Tickers.RefreshAt = "09:00"
+	cfg.Tickers.SearchCacheTtl = Duration(time.Hour)
+	cfg.News.CacheTtl = Duration(5 * time.Minute)
+	cfg.Calendar.RefreshInterval = Duration(time.Hour)
+	cfg.CorporateActions.CacheTtl = Duration(12 * time.Hour)
+	cfg.CorporateActions.CheckInterval = Duration(6 * time.Hour)
+	cfg.Smtp.Port = 587
+	cfg.Digest.SendAt = "07:00"
+	cfg.Digest.BaseUrl = "http://localhost:8080"
+	//the flags in internal/features, a flag set in config replaces its default as a whole
+	cfg.Features = map[string]FeatureFlag{
+		"indicators":        {Enabled: true},
+		"news":              {Enabled: true},
+		"news-insights":     {Enabled: true},
+		"corporate-actions": {Enabled: true},
+	}
+	return cfg
+}
 
-	envFile, err := os.Open(envFilePath)
+// SetEnvironmentSettings loads and validates the configuration then makes it the Current configuration.
+// Values are layered with the highest precedence last:
+// config.json < config.{env}.json < GOAPI_* environment variables < cli flags
+func SetEnvironmentSettings(options LoadOptions) error {
+	dir, err := ResolveConfigDir(options.Dir)
 	if err != nil {
-		log.Fatalf("Error opening %s config file: %v", env, err)
 		return err
 	}
-	defer envFile.Close()
-
-	decoder = json.NewDecoder(envFile)
-	envConfig := AppConfig{}
+	options.Dir = dir
 
-	if err = decoder.Decode(&envConfig); err != nil {
-		log.Fatalf("Error Decoding Environment Configuration File: %v", err)
+	cfg, resolved, err := load(options)
+	if err != nil {
+		return err
 	}
 
-	baseConfig.ConnectionStrings = envConfig.ConnectionStrings
-	baseConfig.ApiSettings = envConfig.ApiSettings
-
-	Configuration = baseConfig
-
+	current.Store(&snapshot{config: cfg, resolvedSecrets: resolved})
+	loadedOptions = options
 	return nil
 }
@@ -3,7 +3,7 @@ package configuration
 import (
 	"context"
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/labstack/gommon/log"
 	"time"
 )
@@ -18,7 +18,16 @@ type DbConfig struct {
 
 func NewDB(cfg DbConfig) (*sql.DB, error) {
 
-	db, err := sql.Open("mysql", cfg.DSN)
+	//repositories scan DATETIME columns straight into time.Time so parseTime must always be on
+	dsn, err := mysql.ParseDSN(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	dsn.ParseTime = true
+	//rows affected has to count changed rows, not matched ones, for the api key quota and update checks
+	dsn.ClientFoundRows = false
+
+	db, err := sql.Open("mysql", dsn.FormatDSN())
 	if err != nil {
 		return nil, err
 	}
@@ -32,10 +41,10 @@ func NewDB(cfg DbConfig) (*sql.DB, error) {
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
-		log.Errorf("failed to ping db %s: %s", cfg.DSN, err)
+		log.Errorf("failed to ping db %s: %s", RedactDSN(cfg.DSN), err)
 		return nil, err
 	}
 
-	log.Infof("connected to db %s", cfg.DSN)
+	log.Infof("connected to db %s", RedactDSN(cfg.DSN))
 	return db, nil
 }
@@ -0,0 +1,98 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	configDirVar      = "APP_CONFIG_DIR"
+	relativeConfigDir = "settings/configuration"
+	baseConfigFile    = "config.json"
+)
+
+// LoadOptions where the configuration is read from and what overrides it
+type LoadOptions struct {
+	Dir           string
+	Env           string
+	FlagOverrides map[string]string
+}
+
+// Load reads config.json then deep merges config.{env}.json over it field by field, applies env and
+// flag overrides, resolves secret:// references and validates the result. Every problem found is
+// returned together
+func Load(options LoadOptions) (AppConfig, error) {
+	cfg, _, err := load(options)
+	return cfg, err
+}
+
+func load(options LoadOptions) (AppConfig, map[string]bool, error) {
+	cfg := defaults()
+
+	if err := mergeFile(&cfg, filepath.Join(options.Dir, baseConfigFile)); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	if options.Env != "" {
+		envFile := filepath.Join(options.Dir, fmt.Sprintf("config.%s.json", options.Env))
+		if err := mergeFile(&cfg, envFile); err != nil {
+			return AppConfig{}, nil, err
+		}
+	}
+
+	overrideErr := applyOverrides(&cfg, options.FlagOverrides)
+	resolved, secretsErr := resolveSecrets(&cfg)
+
+	//report every problem together so they can all be fixed in one go
+	if err := errors.Join(overrideErr, secretsErr, Validate(cfg)); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	return cfg, resolved, nil
+}
+
+// mergeFile decodes the file on top of cfg, encoding/json only sets the keys present in the file
+// and merges into nested structs and maps, so anything the file doesn't mention is kept
+func mergeFile(cfg *AppConfig, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening config file: %w", err)
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(cfg); err != nil {
+		return fmt.Errorf("error decoding config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// ResolveConfigDir picks the config directory: the explicit dir, then $APP_CONFIG_DIR, then
+// settings/configuration next to the executable, then under the working directory
+func ResolveConfigDir(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	if dir := os.Getenv(configDirVar); dir != "" {
+		return dir, nil
+	}
+
+	var candidates []string
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), filepath.FromSlash(relativeConfigDir)))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		candidates = append(candidates, filepath.Join(wd, filepath.FromSlash(relativeConfigDir)))
+	}
+
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, baseConfigFile)); err == nil {
+			return dir, nil
+		}
+	}
+
+	return "", errors.New("no " + baseConfigFile + " found, set $" + configDirVar + " or --config-dir")
+}
@@ -0,0 +1,192 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// required the settings every config needs to validate
+const required = `"connectionStrings": {"stocksDb": "user:pass@tcp(db:3306)/stocks"}, "apiSettings": {"key": "polygon-key"}`
+
+func writeConfig(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMergesTheEnvironmentFileFieldByField(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "config.json", `{`+required+`,
+		"database": {"maxOpenConns": 10, "maxIdleConns": 5},
+		"cache": {"openCloseTtl": "1m"},
+		"auth": {"jwt": {"issuer": "https://base.test", "audience": "goapi"}},
+		"smtp": {"host": "smtp.base.test", "port": 2525, "from": "digest@goapi.test"},
+		"webhooks": {"allowPrivateNetworks": true},
+		"rateLimit": {"rules": {"stocks": {"requests": 60, "windowSeconds": 60}}}
+	}`)
+	writeConfig(t, dir, "config.staging.json", `{
+		"database": {"maxIdleConns": 0},
+		"auth": {"jwt": {"issuer": "https://staging.test"}},
+		"smtp": {"port": 25},
+		"webhooks": {"allowPrivateNetworks": false},
+		"rateLimit": {"rules": {"admin": {"requests": 5, "windowSeconds": 60}}}
+	}`)
+
+	cfg, err := Load(LoadOptions{Dir: dir, Env: "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"base value the environment doesn't set", cfg.Database.MaxOpenConns, 10},
+		{"zero value set by the environment", cfg.Database.MaxIdleConns, 0},
+		{"false set by the environment", cfg.Webhooks.AllowPrivateNetworks, false},
+		{"nested value set by the environment", cfg.Auth.Jwt.Issuer, "https://staging.test"},
+		{"nested sibling only the base sets", cfg.Auth.Jwt.Audience, "goapi"},
+		{"section sibling only the base sets", cfg.Smtp.Host, "smtp.base.test"},
+		{"section value set by the environment", cfg.Smtp.Port, 25},
+		{"base value over a default", cfg.Cache.OpenCloseTtl, Duration(time.Minute)},
+		{"default neither file sets", cfg.Cache.TickerDetailsTtl, Duration(4 * time.Minute)},
+		{"map entry only the base sets", cfg.RateLimit.Rules["stocks"], RateLimitRule{Requests: 60, WindowSeconds: 60}},
+		{"map entry added by the environment", cfg.RateLimit.Rules["admin"], RateLimitRule{Requests: 5, WindowSeconds: 60}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.want {
+				t.Fatalf("got %v, want %v", test.got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadWithoutAnEnvironmentFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "config.json", `{`+required+`}`)
+
+	if _, err := Load(LoadOptions{Dir: dir}); err != nil {
+		t.Fatalf("no environment: %s", err)
+	}
+	if _, err := Load(LoadOptions{Dir: dir, Env: "production"}); err == nil {
+		t.Fatal("a missing config.production.json should fail")
+	}
+}
+
+func TestLoadReportsEveryProblemTogether(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "config.json", `{
+		"database": {"maxOpenConns": 0},
+		"tickers": {"refreshAt": "9am"},
+		"realtime": {"url": "https://delayed.polygon.io"},
+		"features": {"news": {"enabled": true, "rolloutPercent": 150}}
+	}`)
+	t.Setenv("GOAPI_ALERTS_MAXPERUSER", "lots")
+
+	_, err := Load(LoadOptions{Dir: dir})
+	if err == nil {
+		t.Fatal("want an error")
+	}
+
+	for _, want := range []string{
+		"connectionstrings.stocks is required",
+		"apisettings.key is required",
+		"database.maxopenconns must be at least 1",
+		"tickers.refreshat must be hh:mm",
+		"realtime.url must be a ws:// or wss:// url",
+		"features.news.rolloutpercent must be between 0 and 100",
+		"invalid GOAPI_ALERTS_MAXPERUSER",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't report %q:\n%s", want, err)
+		}
+	}
+}
+
+func TestResolveConfigDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	//a working directory with settings/configuration/config.json under it, as when run from cmd/api
+	root := t.TempDir()
+	writeConfig(t, filepath.Join(root, filepath.FromSlash(relativeConfigDir)), baseConfigFile, `{`+required+`}`)
+	empty := t.TempDir()
+
+	tests := []struct {
+		name     string
+		explicit string
+		envDir   string
+		wd       string
+		want     string
+		wantErr  bool
+	}{
+		{"explicit dir", "/etc/goapi", "/srv/goapi", root, "/etc/goapi", false},
+		{"environment variable", "", "/srv/goapi", root, "/srv/goapi", false},
+		{"relative to the working directory", "", "", root,
+			filepath.Join(root, filepath.FromSlash(relativeConfigDir)), false},
+		{"nowhere to be found", "", "", empty, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(configDirVar, test.envDir)
+			if test.envDir == "" {
+				os.Unsetenv(configDirVar)
+			}
+			if err := os.Chdir(test.wd); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ResolveConfigDir(test.explicit)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadRelativeDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	root := t.TempDir()
+	writeConfig(t, filepath.Join(root, "config"), "config.json", `{`+required+`}`)
+	writeConfig(t, filepath.Join(root, "config"), "config.test.json", `{"analytics": {"benchmarkTicker": "QQQ"}}`)
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(LoadOptions{Dir: "config", Env: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Analytics.BenchmarkTicker != "QQQ" {
+		t.Fatalf("benchmark ticker %q, want QQQ from the relative dir's environment file", cfg.Analytics.BenchmarkTicker)
+	}
+}
@@ -0,0 +1,173 @@
+package configuration
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/secrets"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	envPrefix      = "GOAPI_"
+	environmentVar = "APP_ENV"
+	defaultEnv     = "development"
+)
+
+// field a leaf of AppConfig, Path is the dotted lower case go field path e.g. connectionstrings.stocks.
+// Restart fields can't be hot reloaded
+type field struct {
+	Path    string
+	Secret  bool
+	Restart bool
+	value   reflect.Value
+}
+
+// EnvName the environment variable that overrides the field e.g. GOAPI_CONNECTIONSTRINGS_STOCKS
+func (f field) EnvName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(f.Path, ".", "_"))
+}
+
+// set parses raw into the field, anything that isn't a string, number or bool is given as json
+func (f field) set(raw string) error {
+	if unmarshaler, ok := f.value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		f.value.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		f.value.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		f.value.SetBool(b)
+	default:
+		if err := json.Unmarshal([]byte(raw), f.value.Addr().Interface()); err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// fields walks the config struct down to its leaves
+func fields(cfg *AppConfig) []field {
+	var result []field
+	walkFields(reflect.ValueOf(cfg).Elem(), "", false, false, &result)
+	return result
+}
+
+func walkFields(v reflect.Value, prefix string, secret bool, restart bool, result *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		path := strings.ToLower(structField.Name)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		isSecret := secret || structField.Tag.Get("secret") == "true"
+		isRestart := restart || structField.Tag.Get("reload") == "restart"
+
+		if structField.Type.Kind() == reflect.Struct {
+			walkFields(v.Field(i), path, isSecret, isRestart, result)
+			continue
+		}
+
+		*result = append(*result, field{Path: path, Secret: isSecret, Restart: isRestart, value: v.Field(i)})
+	}
+}
+
+// ResolveEnvironment picks the environment from the --env flag, then APP_ENV, then development
+func ResolveEnvironment(flagEnv string) string {
+	if flagEnv != "" {
+		return flagEnv
+	}
+	if env := os.Getenv(environmentVar); env != "" {
+		return env
+	}
+	return defaultEnv
+}
+
+// RegisterFlags adds a flag per config field, e.g. --connectionstrings.stocks, and returns the
+// overrides collected once the flag set is parsed
+func RegisterFlags(fs *flag.FlagSet) map[string]string {
+	overrides := make(map[string]string)
+
+	for _, f := range fields(&AppConfig{}) {
+		path := f.Path
+		usage := fmt.Sprintf("override %s (env %s)", path, f.EnvName())
+		fs.Func(path, usage, func(value string) error {
+			overrides[path] = value
+			return nil
+		})
+	}
+
+	return overrides
+}
+
+// applyOverrides layers environment variables then flag overrides onto the config
+func applyOverrides(cfg *AppConfig, flagOverrides map[string]string) error {
+	var errs []error
+
+	for _, f := range fields(cfg) {
+		if value, ok := os.LookupEnv(f.EnvName()); ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", f.EnvName(), err))
+			}
+		}
+		if value, ok := flagOverrides[f.Path]; ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid --%s: %w", f.Path, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Print writes the effective configuration as json with secrets redacted, enough of each is kept to tell
+// which one is loaded
+func Print(w io.Writer) error {
+	loaded := current.Load()
+	if loaded == nil {
+		return errors.New("configuration hasn't been loaded")
+	}
+
+	redacted := loaded.config
+	for _, f := range fields(&redacted) {
+		if (f.Secret || loaded.resolvedSecrets[f.Path]) && !f.value.IsZero() {
+			f.value.SetString(secrets.Redact(f.value.String()))
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(redacted)
+}
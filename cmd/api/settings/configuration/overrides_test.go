@@ -0,0 +1,147 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOverridePrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		envFile bool
+		envVar  bool
+		flag    bool
+		want    string
+	}{
+		{"base file", false, false, false, "BASE"},
+		{"environment file over the base file", true, false, false, "ENVFILE"},
+		{"environment variable over the environment file", true, true, false, "ENVVAR"},
+		{"flag over everything", true, true, true, "FLAG"},
+		{"flag over the base file", false, false, true, "FLAG"},
+		{"environment variable over the base file", false, true, false, "ENVVAR"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, "config.json", `{`+required+`, "analytics": {"benchmarkTicker": "BASE"}}`)
+			options := LoadOptions{Dir: dir}
+
+			if test.envFile {
+				writeConfig(t, dir, "config.staging.json", `{"analytics": {"benchmarkTicker": "ENVFILE"}}`)
+				options.Env = "staging"
+			}
+			if test.envVar {
+				t.Setenv("GOAPI_ANALYTICS_BENCHMARKTICKER", "ENVVAR")
+			}
+
+			//flags go through the same flag set main parses
+			fs := flag.NewFlagSet("api", flag.ContinueOnError)
+			options.FlagOverrides = RegisterFlags(fs)
+			var args []string
+			if test.flag {
+				args = append(args, "--analytics.benchmarkticker=FLAG")
+			}
+			if err := fs.Parse(args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Analytics.BenchmarkTicker != test.want {
+				t.Fatalf("got %s, want %s", cfg.Analytics.BenchmarkTicker, test.want)
+			}
+		})
+	}
+}
+
+func TestOverridesParseEachKind(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "config.json", `{`+required+`}`)
+	t.Setenv("GOAPI_CACHE_OPENCLOSETTL", "90s")
+	t.Setenv("GOAPI_ANALYTICS_RISKFREERATE", "0.05")
+	t.Setenv("GOAPI_WEBHOOKS_ALLOWPRIVATENETWORKS", "true")
+	t.Setenv("GOAPI_APISETTINGS_TRUSTEDPROXIES", `["10.0.0.0/8"]`)
+
+	cfg, err := Load(LoadOptions{Dir: dir, FlagOverrides: map[string]string{"alerts.maxperuser": "7"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Cache.OpenCloseTtl != Duration(90*time.Second) || cfg.Analytics.RiskFreeRate != 0.05 ||
+		!cfg.Webhooks.AllowPrivateNetworks || len(cfg.ApiSettings.TrustedProxies) != 1 || cfg.Alerts.MaxPerUser != 7 {
+		t.Fatalf("overrides weren't all applied: ttl %s, rate %g, private %t, proxies %v, max %d",
+			time.Duration(cfg.Cache.OpenCloseTtl), cfg.Analytics.RiskFreeRate, cfg.Webhooks.AllowPrivateNetworks,
+			cfg.ApiSettings.TrustedProxies, cfg.Alerts.MaxPerUser)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	const (
+		dsnPassword  = "db-password-123"
+		polygonKey   = "pk_live_abcdefghijkl"
+		smtpPassword = "hunter2!" //short enough to be hidden completely
+		smtpUsername = "mailer-account"
+	)
+
+	dir := t.TempDir()
+	writeConfig(t, dir, "config.json", `{
+		"connectionStrings": {"stocksDb": "goapi:`+dsnPassword+`@tcp(db:3306)/stocks"},
+		"apiSettings": {"key": "`+polygonKey+`"},
+		"smtp": {"host": "smtp.goapi.test", "from": "digest@goapi.test", "username": "secret://smtp/username",
+			"password": "`+smtpPassword+`"}
+	}`)
+	t.Setenv("GOAPI_SECRET_SMTP_USERNAME", smtpUsername)
+
+	if err := SetEnvironmentSettings(LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	printed := out.String()
+
+	for _, secret := range []string{dsnPassword, polygonKey, smtpPassword, smtpUsername} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("config print shows %q:\n%s", secret, printed)
+		}
+	}
+
+	var cfg AppConfig
+	if err := json.Unmarshal(out.Bytes(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"secret field", cfg.ApiSettings.Key, "pk_l****"},
+		{"short secret field", cfg.Smtp.Password, "****"},
+		{"secret connection string", cfg.ConnectionStrings.Stocks, "goap****"},
+		{"field resolved from a secret reference", cfg.Smtp.Username, "mail****"},
+		{"ordinary field", cfg.Smtp.Host, "smtp.goapi.test"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.want {
+				t.Fatalf("got %q, want %q", test.got, test.want)
+			}
+		})
+	}
+
+	//the running config still has the real values
+	if Current().ApiSettings.Key != polygonKey {
+		t.Fatal("printing redacted the running configuration")
+	}
+}
@@ -0,0 +1,155 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	loadedOptions LoadOptions
+	reloadMu      sync.Mutex
+	subscribersMu sync.RWMutex
+	subscribers   []func(previous AppConfig, next AppConfig)
+)
+
+// Subscribe registers fn to run after every successful reload with the old and new configuration,
+// components use it to rebuild whatever they built from the config
+func Subscribe(fn func(previous AppConfig, next AppConfig)) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	subscribers = append(subscribers, fn)
+}
+
+// Reload re-reads and re-validates the configuration with the options it was first loaded with and swaps
+// it in. An invalid config is rejected and the running one kept. Fields that need a restart keep their
+// running values and are logged
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	loaded := current.Load()
+	if loaded == nil {
+		return errors.New("configuration hasn't been loaded")
+	}
+
+	next, resolved, err := load(loadedOptions)
+	if err != nil {
+		return err
+	}
+
+	previous := loaded.config
+	keepRestartFields(&previous, &next)
+
+	current.Store(&snapshot{config: next, resolvedSecrets: resolved})
+
+	if reflect.DeepEqual(previous, next) {
+		log.Info("configuration reloaded, nothing changed")
+		return nil
+	}
+	log.Info("configuration reloaded")
+
+	subscribersMu.RLock()
+	defer subscribersMu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(previous, next)
+	}
+
+	return nil
+}
+
+// keepRestartFields copies the running value back over any restart only field that changed
+func keepRestartFields(previous *AppConfig, next *AppConfig) {
+	previousFields := fields(previous)
+
+	for i, f := range fields(next) {
+		if !f.Restart || reflect.DeepEqual(f.value.Interface(), previousFields[i].value.Interface()) {
+			continue
+		}
+
+		log.Warnf("restart required: %s changed, the running value is kept until the api restarts", f.Path)
+		f.value.Set(previousFields[i].value)
+	}
+}
+
+// WatchForChanges reloads the configuration when SIGHUP is received or any of the config or secrets
+// files change, files are polled every interval. It stops when ctx is done
+func WatchForChanges(ctx context.Context, interval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hangup)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		stamps := fileStamps(watchedFiles())
+
+		for {
+			select {
+			case <-hangup:
+				log.Info("SIGHUP received, reloading configuration")
+			case <-ticker.C:
+				latest := fileStamps(watchedFiles())
+				if reflect.DeepEqual(stamps, latest) {
+					continue
+				}
+				stamps = latest
+				log.Info("configuration files changed, reloading configuration")
+			case <-ctx.Done():
+				return
+			}
+
+			if err := Reload(); err != nil {
+				log.Errorf("configuration reload rejected, keeping the running configuration:\n%s", err)
+			}
+			stamps = fileStamps(watchedFiles())
+		}
+	}()
+}
+
+// watchedFiles the config files plus any secrets files the running config reads
+func watchedFiles() []string {
+	files := []string{filepath.Join(loadedOptions.Dir, baseConfigFile)}
+	if loadedOptions.Env != "" {
+		files = append(files, filepath.Join(loadedOptions.Dir, fmt.Sprintf("config.%s.json", loadedOptions.Env)))
+	}
+
+	cfg := Current()
+	if cfg.Secrets.File != "" {
+		files = append(files, cfg.Secrets.File, cfg.Secrets.KeyFile)
+	}
+
+	return files
+}
+
+type fileStamp struct {
+	modified time.Time
+	size     int64
+}
+
+func fileStamps(files []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(files))
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			stamps[file] = fileStamp{}
+			continue
+		}
+		stamps[file] = fileStamp{modified: info.ModTime(), size: info.Size()}
+	}
+
+	return stamps
+}
@@ -0,0 +1,127 @@
+package configuration
+
+import (
+	"reflect"
+	"testing"
+)
+
+// reloaded the (previous, next) pairs a subscriber was called with
+type reloaded struct {
+	previous AppConfig
+	next     AppConfig
+}
+
+func subscribe(t *testing.T) *[]reloaded {
+	t.Helper()
+
+	//subscribers can't be removed, each test reads only the calls made to its own
+	var calls []reloaded
+	Subscribe(func(previous AppConfig, next AppConfig) {
+		calls = append(calls, reloaded{previous: previous, next: next})
+	})
+	return &calls
+}
+
+func TestReloadKeepsRestartFields(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "config.json", `{
+		"connectionStrings": {"stocksDb": "user:pass@tcp(db:3306)/stocks"},
+		"database": {"maxOpenConns": 10, "maxIdleConns": 5},
+		"apiSettings": {"key": "polygon-key", "trustedProxies": ["10.0.0.0/8"]},
+		"auth": {"defaultDailyQuota": 100, "jwt": {"issuer": "https://old.test"}},
+		"analytics": {"benchmarkTicker": "SPY"}
+	}`)
+	if err := SetEnvironmentSettings(LoadOptions{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	calls := subscribe(t)
+
+	writeConfig(t, dir, "config.json", `{
+		"connectionStrings": {"stocksDb": "user:pass@tcp(other-db:3306)/stocks"},
+		"database": {"maxOpenConns": 50, "maxIdleConns": 5},
+		"apiSettings": {"key": "rotated-key", "trustedProxies": ["192.168.0.0/16"]},
+		"auth": {"defaultDailyQuota": 500, "jwt": {"issuer": "https://new.test"}},
+		"analytics": {"benchmarkTicker": "QQQ"}
+	}`)
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Current()
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"restart section", cfg.ConnectionStrings.Stocks, "user:pass@tcp(db:3306)/stocks"},
+		{"field in a restart section", cfg.Database.MaxOpenConns, 10},
+		{"restart field", cfg.ApiSettings.TrustedProxies, []string{"10.0.0.0/8"}},
+		{"nested restart section", cfg.Auth.Jwt.Issuer, "https://old.test"},
+		{"hot field beside a restart field", cfg.ApiSettings.Key, "rotated-key"},
+		{"hot field beside a restart section", cfg.Auth.DefaultDailyQuota, 500},
+		{"hot section", cfg.Analytics.BenchmarkTicker, "QQQ"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.got, test.want) {
+				t.Fatalf("got %v, want %v", test.got, test.want)
+			}
+		})
+	}
+
+	if len(*calls) != 1 {
+		t.Fatalf("subscriber called %d times, want once", len(*calls))
+	}
+	call := (*calls)[0]
+	if call.previous.Analytics.BenchmarkTicker != "SPY" || call.next.Analytics.BenchmarkTicker != "QQQ" {
+		t.Fatalf("subscriber got %s then %s, want SPY then QQQ", call.previous.Analytics.BenchmarkTicker,
+			call.next.Analytics.BenchmarkTicker)
+	}
+	if call.next.Database.MaxOpenConns != 10 {
+		t.Fatalf("subscriber's next config has max open conns %d, want the running 10", call.next.Database.MaxOpenConns)
+	}
+	if !reflect.DeepEqual(call.next, cfg) {
+		t.Fatal("subscriber's next config isn't the one swapped in")
+	}
+}
+
+func TestReloadOnlyNotifiesOnAChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		wantErr    bool
+		wantCalled bool
+		wantTicker string
+	}{
+		{"nothing changed", `{` + required + `, "analytics": {"benchmarkTicker": "SPY"}}`, false, false, "SPY"},
+		{"only a restart field changed", `{` + required + `, "analytics": {"benchmarkTicker": "SPY"},
+			"database": {"maxOpenConns": 99}}`, false, false, "SPY"},
+		{"invalid config is rejected", `{` + required + `, "analytics": {"benchmarkTicker": "QQQ"},
+			"tickers": {"refreshAt": "9am"}}`, true, false, "SPY"},
+		{"hot field changed", `{` + required + `, "analytics": {"benchmarkTicker": "QQQ"}}`, false, true, "QQQ"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, "config.json", `{`+required+`, "analytics": {"benchmarkTicker": "SPY"}}`)
+			if err := SetEnvironmentSettings(LoadOptions{Dir: dir}); err != nil {
+				t.Fatal(err)
+			}
+			calls := subscribe(t)
+
+			writeConfig(t, dir, "config.json", test.config)
+			if err := Reload(); (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %t", err, test.wantErr)
+			}
+
+			if called := len(*calls) > 0; called != test.wantCalled {
+				t.Fatalf("subscriber called %t, want %t", called, test.wantCalled)
+			}
+			if got := Current().Analytics.BenchmarkTicker; got != test.wantTicker {
+				t.Fatalf("running benchmark ticker %s, want %s", got, test.wantTicker)
+			}
+		})
+	}
+}
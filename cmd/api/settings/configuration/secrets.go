@@ -0,0 +1,84 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/secrets"
+	"github.com/go-sql-driver/mysql"
+	"reflect"
+	"time"
+)
+
+const secretEnvPrefix = "GOAPI_SECRET_"
+
+// newSecretsProvider builds the provider named in the Secrets section, env is the default
+func newSecretsProvider(cfg *AppConfig) (secrets.Provider, error) {
+	switch cfg.Secrets.Provider {
+	case "", "env":
+		return secrets.NewEnvProvider(secretEnvPrefix), nil
+	case "file":
+		if cfg.Secrets.Dir == "" {
+			return nil, errors.New("secrets.dir is required for the file provider")
+		}
+		return secrets.NewFileProvider(cfg.Secrets.Dir), nil
+	case "encrypted":
+		if cfg.Secrets.File == "" || cfg.Secrets.KeyFile == "" {
+			return nil, errors.New("secrets.file and secrets.keyfile are required for the encrypted provider")
+		}
+		return secrets.NewEncryptedFileProvider(cfg.Secrets.File, cfg.Secrets.KeyFile)
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", cfg.Secrets.Provider)
+	}
+}
+
+// resolveSecrets replaces every secret:// reference in the config with the secret's value and
+// returns the paths of the fields it resolved
+func resolveSecrets(cfg *AppConfig) (map[string]bool, error) {
+	resolved := make(map[string]bool)
+
+	var references []field
+	for _, f := range fields(cfg) {
+		if f.value.Kind() == reflect.String && secrets.IsReference(f.value.String()) {
+			references = append(references, f)
+		}
+	}
+	if len(references) == 0 {
+		return resolved, nil
+	}
+
+	provider, err := newSecretsProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var errs []error
+	for _, f := range references {
+		value, err := secrets.Resolve(ctx, provider, f.value.String())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
+			continue
+		}
+
+		f.value.SetString(value)
+		resolved[f.Path] = true
+	}
+
+	return resolved, errors.Join(errs...)
+}
+
+// RedactDSN strips the password from a mysql dsn so it's safe to log
+func RedactDSN(dsn string) string {
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "[unparseable dsn]"
+	}
+
+	if parsed.Passwd != "" {
+		parsed.Passwd = "****"
+	}
+	return parsed.FormatDSN()
+}
@@ -0,0 +1,144 @@
+package configuration
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RobsonDevCode/GoApi/cmd/api/settings/secrets"
+)
+
+// secretsConfig a config whose polygon key and smtp password are both secret references
+func secretsConfig(provider string) AppConfig {
+	cfg := defaults()
+	cfg.Secrets.Provider = provider
+	cfg.ConnectionStrings.Stocks = "goapi:plain@tcp(db:3306)/stocks"
+	cfg.ApiSettings.Key = "secret://polygon/key"
+	cfg.Smtp.Password = "secret://smtp/password"
+	return cfg
+}
+
+func TestResolveSecrets(t *testing.T) {
+	//file provider: one file per secret, mounted secrets usually end with a newline
+	mounted := t.TempDir()
+	writeConfig(t, filepath.Join(mounted, "polygon"), "key", "file-polygon-key\n")
+	writeConfig(t, filepath.Join(mounted, "smtp"), "password", "file-smtp-password")
+
+	//encrypted provider: a json object sealed with a hex encoded key
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := secrets.Encrypt([]byte(`{"polygon/key": "sealed-polygon-key", "smtp/password": "sealed-smtp-password"}`), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vault := t.TempDir()
+	writeConfig(t, vault, "secrets.enc", string(sealed))
+	writeConfig(t, vault, "secrets.key", hex.EncodeToString(key)+"\n")
+
+	t.Setenv("GOAPI_SECRET_POLYGON_KEY", "env-polygon-key")
+	t.Setenv("GOAPI_SECRET_SMTP_PASSWORD", "env-smtp-password")
+
+	tests := []struct {
+		name         string
+		cfg          func() AppConfig
+		wantKey      string
+		wantPassword string
+		wantErr      string
+	}{
+		{"env provider by default", func() AppConfig { return secretsConfig("") }, "env-polygon-key",
+			"env-smtp-password", ""},
+		{"file provider", func() AppConfig {
+			cfg := secretsConfig("file")
+			cfg.Secrets.Dir = mounted
+			return cfg
+		}, "file-polygon-key", "file-smtp-password", ""},
+		{"encrypted provider", func() AppConfig {
+			cfg := secretsConfig("encrypted")
+			cfg.Secrets.File, cfg.Secrets.KeyFile = filepath.Join(vault, "secrets.enc"), filepath.Join(vault, "secrets.key")
+			return cfg
+		}, "sealed-polygon-key", "sealed-smtp-password", ""},
+		{"missing secret", func() AppConfig {
+			cfg := secretsConfig("")
+			cfg.Smtp.Password = "secret://smtp/missing"
+			return cfg
+		}, "", "", "smtp.password"},
+		{"reference without a name", func() AppConfig {
+			cfg := secretsConfig("")
+			cfg.ApiSettings.Key = "secret://"
+			return cfg
+		}, "", "", "apisettings.key"},
+		{"file reference outside the secrets dir", func() AppConfig {
+			cfg := secretsConfig("file")
+			cfg.Secrets.Dir = mounted
+			cfg.ApiSettings.Key = "secret://../../etc/passwd"
+			return cfg
+		}, "", "", "outside the secrets directory"},
+		{"file provider without a dir", func() AppConfig { return secretsConfig("file") }, "", "",
+			"secrets.dir is required"},
+		{"encrypted provider with the wrong key", func() AppConfig {
+			wrong := t.TempDir()
+			writeConfig(t, wrong, "secrets.key", strings.Repeat("ab", 32))
+			cfg := secretsConfig("encrypted")
+			cfg.Secrets.File, cfg.Secrets.KeyFile = filepath.Join(vault, "secrets.enc"), filepath.Join(wrong, "secrets.key")
+			return cfg
+		}, "", "", "error decrypting"},
+		{"unknown provider", func() AppConfig { return secretsConfig("vault") }, "", "", "unknown secrets provider"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := test.cfg()
+			resolved, err := resolveSecrets(&cfg)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want one mentioning %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.ApiSettings.Key != test.wantKey || cfg.Smtp.Password != test.wantPassword {
+				t.Fatalf("resolved %q and %q, want %q and %q", cfg.ApiSettings.Key, cfg.Smtp.Password,
+					test.wantKey, test.wantPassword)
+			}
+			if !resolved["apisettings.key"] || !resolved["smtp.password"] || resolved["connectionstrings.stocks"] {
+				t.Fatalf("resolved fields %v, want only the references", resolved)
+			}
+		})
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		dsn      string
+		want     string
+		password string
+	}{
+		{"password", "goapi:s3cret@tcp(db:3306)/stocks", "goapi:****@tcp(db:3306)/stocks", "s3cret"},
+		{"password with an @ and a :", "goapi:p@ss:w0rd@tcp(db:3306)/stocks", "goapi:****@tcp(db:3306)/stocks",
+			"p@ss:w0rd"},
+		{"params are kept", "goapi:s3cret@tcp(db:3306)/stocks?parseTime=true",
+			"goapi:****@tcp(db:3306)/stocks?parseTime=true", "s3cret"},
+		{"no password", "goapi@tcp(db:3306)/stocks", "goapi@tcp(db:3306)/stocks", ""},
+		{"unparseable", "goapi:s3cret@tcp(db:3306", "[unparseable dsn]", "s3cret"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := RedactDSN(test.dsn)
+			if got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+			if test.password != "" && strings.Contains(got, test.password) {
+				t.Fatalf("%q still has the password", got)
+			}
+		})
+	}
+}
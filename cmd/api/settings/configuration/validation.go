@@ -0,0 +1,143 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Validate checks required fields are set and values are in range, every problem is reported together
+func Validate(cfg AppConfig) error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	//required
+	check(cfg.ConnectionStrings.Stocks != "", "connectionstrings.stocks is required")
+	check(cfg.ApiSettings.Key != "", "apisettings.key is required")
+
+	//database pool
+	check(cfg.Database.MaxOpenConns >= 1, "database.maxopenconns must be at least 1, got %d", cfg.Database.MaxOpenConns)
+	check(cfg.Database.MaxIdleConns >= 0 && cfg.Database.MaxIdleConns <= cfg.Database.MaxOpenConns,
+		"database.maxidleconns must be between 0 and maxopenconns, got %d", cfg.Database.MaxIdleConns)
+	check(cfg.Database.ConnMaxLifetime > 0, "database.connmaxlifetime must be positive")
+	check(cfg.Database.ConnMaxIdleTime > 0, "database.connmaxidletime must be positive")
+
+	//api settings
+	for _, proxy := range cfg.ApiSettings.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "apisettings.trustedproxies must be ips or cidrs, got %q", proxy)
+	}
+
+	//auth
+	check(cfg.Auth.DefaultDailyQuota >= 0, "auth.defaultdailyquota can't be negative")
+	check(cfg.Auth.Jwt.JwksCacheMinutes >= 0, "auth.jwt.jwkscacheminutes can't be negative")
+
+	//cache
+	check(cfg.Cache.TickerDetailsTtl > 0, "cache.tickerdetailsttl must be positive")
+	check(cfg.Cache.OpenCloseTtl > 0, "cache.openclosettl must be positive")
+	check(cfg.Cache.ClosedTtl > 0, "cache.closedttl must be positive")
+
+	//rate limits
+	for group, rule := range cfg.RateLimit.Rules {
+		check(rule.Requests > 0, "ratelimit.rules.%s.requests must be positive", group)
+		check(rule.WindowSeconds > 0, "ratelimit.rules.%s.windowseconds must be positive", group)
+	}
+
+	//analytics
+	check(cfg.Analytics.BenchmarkTicker != "", "analytics.benchmarkticker is required")
+	check(cfg.Analytics.RiskFreeRate >= 0 && cfg.Analytics.RiskFreeRate < 1,
+		"analytics.riskfreerate must be a fraction between 0 and 1, got %g", cfg.Analytics.RiskFreeRate)
+
+	//alerts
+	check(cfg.Alerts.EvaluationInterval >= Duration(time.Minute), "alerts.evaluationinterval must be at least 1m")
+	check(cfg.Alerts.DefaultCooldown >= 0, "alerts.defaultcooldown can't be negative")
+	check(cfg.Alerts.MaxPerUser >= 1, "alerts.maxperuser must be at least 1, got %d", cfg.Alerts.MaxPerUser)
+
+	//webhooks
+	check(cfg.Webhooks.PollInterval >= Duration(time.Second), "webhooks.pollinterval must be at least 1s")
+	check(cfg.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(cfg.Webhooks.BatchSize >= 1, "webhooks.batchsize must be at least 1, got %d", cfg.Webhooks.BatchSize)
+	check(cfg.Webhooks.MaxAttempts >= 1, "webhooks.maxattempts must be at least 1, got %d", cfg.Webhooks.MaxAttempts)
+	check(cfg.Webhooks.InitialBackoff > 0, "webhooks.initialbackoff must be positive")
+	check(cfg.Webhooks.MaxBackoff >= cfg.Webhooks.InitialBackoff, "webhooks.maxbackoff can't be less than initialbackoff")
+	check(cfg.Webhooks.MaxPerUser >= 1, "webhooks.maxperuser must be at least 1, got %d", cfg.Webhooks.MaxPerUser)
+
+	//stream
+	check(cfg.Stream.PollInterval >= Duration(time.Second), "stream.pollinterval must be at least 1s")
+	check(cfg.Stream.HeartbeatInterval > 0, "stream.heartbeatinterval must be positive")
+	check(cfg.Stream.ReplayBuffer >= 0, "stream.replaybuffer can't be negative")
+	check(cfg.Stream.ClientBuffer >= 1, "stream.clientbuffer must be at least 1, got %d", cfg.Stream.ClientBuffer)
+	check(cfg.Stream.MaxTickers >= 1, "stream.maxtickers must be at least 1, got %d", cfg.Stream.MaxTickers)
+
+	//realtime
+	realtimeUrl, urlErr := url.Parse(cfg.Realtime.Url)
+	check(urlErr == nil && (realtimeUrl.Scheme == "ws" || realtimeUrl.Scheme == "wss"),
+		"realtime.url must be a ws:// or wss:// url, got %q", cfg.Realtime.Url)
+	check(cfg.Realtime.InitialBackoff > 0, "realtime.initialbackoff must be positive")
+	check(cfg.Realtime.MaxBackoff >= cfg.Realtime.InitialBackoff, "realtime.maxbackoff can't be less than initialbackoff")
+	check(cfg.Realtime.ClientBuffer >= 1, "realtime.clientbuffer must be at least 1, got %d", cfg.Realtime.ClientBuffer)
+
+	//websocket
+	check(cfg.Websocket.MaxSubscriptions >= 1, "websocket.maxsubscriptions must be at least 1, got %d", cfg.Websocket.MaxSubscriptions)
+	check(cfg.Websocket.SendBuffer >= 1, "websocket.sendbuffer must be at least 1, got %d", cfg.Websocket.SendBuffer)
+	check(cfg.Websocket.PingInterval > 0, "websocket.pinginterval must be positive")
+	check(cfg.Websocket.PongTimeout > cfg.Websocket.PingInterval, "websocket.pongtimeout must be longer than pinginterval")
+	check(cfg.Websocket.WriteTimeout > 0, "websocket.writetimeout must be positive")
+	check(cfg.Websocket.MaxMessageSize >= 256, "websocket.maxmessagesize must be at least 256, got %d", cfg.Websocket.MaxMessageSize)
+
+	//tickers
+	_, refreshAtErr := time.Parse("15:04", cfg.Tickers.RefreshAt)
+	check(refreshAtErr == nil, "tickers.refreshat must be hh:mm: %v", refreshAtErr)
+	check(cfg.Tickers.SearchCacheTtl > 0, "tickers.searchcachettl must be positive")
+
+	//news
+	check(cfg.News.CacheTtl > 0, "news.cachettl must be positive")
+
+	//calendar
+	check(cfg.Calendar.RefreshInterval >= Duration(time.Minute), "calendar.refreshinterval must be at least 1m")
+
+	//corporate actions
+	check(cfg.CorporateActions.CacheTtl > 0, "corporateactions.cachettl must be positive")
+	check(cfg.CorporateActions.CheckInterval >= Duration(time.Minute), "corporateactions.checkinterval must be at least 1m")
+
+	//smtp and digest
+	if cfg.Smtp.Host != "" {
+		check(cfg.Smtp.Port >= 1 && cfg.Smtp.Port <= 65535, "smtp.port must be between 1 and 65535, got %d", cfg.Smtp.Port)
+		check(cfg.Smtp.From != "", "smtp.from is required when smtp.host is set")
+	}
+	_, sendAtErr := time.Parse("15:04", cfg.Digest.SendAt)
+	check(sendAtErr == nil, "digest.sendat must be hh:mm: %v", sendAtErr)
+	check(cfg.Digest.BaseUrl != "", "digest.baseurl is required")
+
+	//features
+	for name, flag := range cfg.Features {
+		if flag.RolloutPercent != nil {
+			check(*flag.RolloutPercent >= 0 && *flag.RolloutPercent <= 100,
+				"features.%s.rolloutpercent must be between 0 and 100, got %d", name, *flag.RolloutPercent)
+		}
+	}
+
+	//versioning
+	since, sinceErr := parseOptionalDate(cfg.Versioning.DeprecatedSince)
+	check(sinceErr == nil, "versioning.deprecatedsince must be yyyy-mm-dd: %v", sinceErr)
+	sunset, sunsetErr := parseOptionalDate(cfg.Versioning.Sunset)
+	check(sunsetErr == nil, "versioning.sunset must be yyyy-mm-dd: %v", sunsetErr)
+	if !since.IsZero() && !sunset.IsZero() {
+		check(sunset.After(since), "versioning.sunset must be after deprecatedsince")
+	}
+
+	return errors.Join(errs...)
+}
+
+func parseOptionalDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
@@ -0,0 +1,26 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// EnvProvider reads secrets from environment variables, secret://polygon/key is read from {prefix}POLYGON_KEY
+type EnvProvider struct {
+	prefix string
+}
+
+func NewEnvProvider(prefix string) *EnvProvider {
+	return &EnvProvider{prefix: prefix}
+}
+
+func (e *EnvProvider) Lookup(_ context.Context, name string) (string, error) {
+	variable := e.prefix + strings.ToUpper(strings.NewReplacer("/", "_", "-", "_", ".", "_").Replace(name))
+
+	value, ok := os.LookupEnv(variable)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
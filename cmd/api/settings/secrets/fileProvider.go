@@ -0,0 +1,39 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileProvider reads secrets mounted as files, secret://polygon/key is read from {dir}/polygon/key
+type FileProvider struct {
+	dir string
+}
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+func (f *FileProvider) Lookup(_ context.Context, name string) (string, error) {
+	path := filepath.Join(f.dir, filepath.FromSlash(name))
+
+	//don't let a reference like secret://../../etc/passwd escape the secrets dir
+	if rel, err := filepath.Rel(f.dir, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("secret name %q is outside the secrets directory", name)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	//mounted secrets usually end with a newline
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,50 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scheme prefix marking a config value as a reference to a secret, e.g. secret://polygon/key
+const Scheme = "secret://"
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Provider looks up secrets by name, names are slash separated like polygon/key
+type Provider interface {
+	Lookup(ctx context.Context, name string) (string, error)
+}
+
+// IsReference reports whether the config value points at a secret
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// Resolve swaps a secret reference for the secret's value, anything else is returned as is
+func Resolve(ctx context.Context, provider Provider, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	name := strings.TrimPrefix(value, Scheme)
+	if name == "" {
+		return "", errors.New("secret reference has no name")
+	}
+
+	secret, err := provider.Lookup(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", value, err)
+	}
+
+	return secret, nil
+}
+
+// Redact hides all but the first few characters of a secret so logs can still tell keys apart
+func Redact(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:4] + "****"
+}